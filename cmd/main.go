@@ -2,9 +2,15 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
+	"io"
 	"log"
+	"os"
+	"path/filepath"
 
 	"github.com/kevensen/gollama-bubbletea/internal/bot"
+	"github.com/kevensen/gollama-bubbletea/internal/bot/models"
 	"github.com/kevensen/gollama-bubbletea/internal/settings"
 	"github.com/kevensen/gollama-bubbletea/internal/tui"
 
@@ -14,29 +20,116 @@ import (
 func main() {
 	ctx := context.Background()
 
+	// Flags take precedence over environment variables, which take precedence over the settings file
+	configPath := flag.String("config", "", "settings file (env "+settings.EnvConfig+", default shown by the paths command)")
+	url := flag.String("url", "", "LLM server URL (env "+settings.EnvURL+" or "+settings.EnvOllamaHost+")")
+	model := flag.String("model", "", "model to start with (env "+settings.EnvModel+")")
+	chromaURL := flag.String("chroma-url", "", "ChromaDB URL for RAG (env "+settings.EnvChromaURL+")")
+	rag := flag.Bool("rag", false, "enable RAG for this session, --rag=false to disable (env "+settings.EnvRAG+")")
+	theme := flag.String("theme", "", "dark or light (env "+settings.EnvTheme+")")
+	profile := flag.String("profile", "", "apply a settings profile (env "+settings.EnvProfile+")")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [paths]\n\n", os.Args[0])
+		fmt.Fprintln(flag.CommandLine.Output(), "  paths\tprint the config, data and cache directories and exit")
+		fmt.Fprintln(flag.CommandLine.Output())
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *configPath == "" {
+		*configPath = os.Getenv(settings.EnvConfig)
+	}
+	if *profile == "" {
+		*profile = os.Getenv(settings.EnvProfile)
+	}
+
+	paths, err := settings.ResolvePaths()
+	if err != nil {
+		log.Fatalf("Failed to resolve directories: %v", err)
+	}
+
+	switch flag.Arg(0) {
+	case "":
+	case "paths":
+		printPaths(paths, *configPath)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", flag.Arg(0))
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := settings.MoveLegacyData(paths); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	overrides, err := settings.OverridesFromEnv(os.Getenv)
+	if err != nil {
+		log.Fatalf("Invalid environment: %v", err)
+	}
+	flags := settings.Overrides{URL: *url, Model: *model, ChromaURL: *chromaURL, Theme: *theme}
+	flag.Visit(func(f *flag.Flag) {
+		// Only an explicit --rag overrides the saved setting
+		if f.Name == "rag" {
+			flags.RAG = rag
+		}
+	})
+	overrides = overrides.Merge(flags)
+
 	// Load settings to get Ollama URL
-	appSettings, err := settings.Load()
+	appSettings, err := settings.LoadFrom(*configPath)
 	if err != nil {
 		log.Printf("Warning: Could not load settings, using defaults: %v", err)
-		appSettings = settings.DefaultSettings()
+	}
+	// Flags and environment variables take precedence over the profile
+	if *profile != "" {
+		if err := appSettings.ApplyProfile(*profile); err != nil {
+			log.Fatalf("Failed to apply profile: %v", err)
+		}
+	}
+	if err := appSettings.ApplyOverrides(overrides); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
 
-	// Use default model for now - will be overridden by settings if valid
-	defaultModel := "tinyllama:latest"
+	// Use the saved or requested model - the first available model is used if it doesn't exist
+	defaultModel := appSettings.LastModel
+	if defaultModel == "" {
+		defaultModel = "tinyllama:latest"
+	}
 
 	// Always try to create bot, even if no connection
 	// The TUI will handle the no-connection case
-	ollamaURL := appSettings.OllamaURL
-
-	b, err := bot.NewBot(ctx, ollamaURL, defaultModel)
+	b, err := bot.NewBot(ctx, bot.SettingsProvider(appSettings), defaultModel)
 	if err != nil {
 		log.Fatalf("Failed to create bot: %v", err)
 	}
+	b.SetModelInfoCache(models.NewInfoCache(filepath.Join(paths.Cache, "models.json")))
+
+	t := tui.New(b, appSettings)
 
-	t := tui.New(b)
+	// Warnings logged while the interface is showing would draw over it, so they go to a file
+	log.SetOutput(io.Discard)
+	if err := os.MkdirAll(paths.Cache, 0700); err == nil {
+		if f, err := tea.LogToFile(filepath.Join(paths.Cache, "gollama.log"), ""); err == nil {
+			defer f.Close()
+		}
+	}
 
-	p := tea.NewProgram(t)
+	// Mouse reporting gives wheel scrolling and clicks; most terminals still select text with Shift held
+	p := tea.NewProgram(t, tea.WithMouseCellMotion())
 	if _, err := p.Run(); err != nil {
 		panic(err)
 	}
 }
+
+// printPaths prints where gollama keeps its files
+func printPaths(paths settings.Paths, configPath string) {
+	if configPath == "" {
+		configPath = paths.SettingsFile()
+	}
+	fmt.Printf("config    %s\n", paths.Config)
+	fmt.Printf("settings  %s\n", configPath)
+	fmt.Printf("data      %s\n", paths.Data)
+	fmt.Printf("cache     %s\n", paths.Cache)
+}
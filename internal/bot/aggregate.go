@@ -0,0 +1,164 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/kevensen/gollama-bubbletea/internal/bot/models"
+
+	"github.com/parakeet-nest/parakeet/llm"
+)
+
+// ProviderAggregate names the provider merging the models of several endpoints
+const ProviderAggregate = "aggregate"
+
+// hostSeparator joins a model name and the endpoint that serves it
+const hostSeparator = "@"
+
+// Member is a named endpoint taking part in an aggregate provider
+type Member struct {
+	Name     string
+	Provider Provider
+}
+
+// AggregateProvider merges the model lists of several providers and routes each
+// request to the provider that owns the model
+// Model names are qualified with the member name, e.g. llama3.2:1b@laptop
+type AggregateProvider struct {
+	members []Member
+}
+
+// NewAggregateProvider creates a provider over members, listed in display order
+func NewAggregateProvider(members []Member) *AggregateProvider {
+	return &AggregateProvider{members: members}
+}
+
+// QualifyModel returns the aggregate name of a model served by host
+func QualifyModel(model, host string) string {
+	return model + hostSeparator + host
+}
+
+// SplitModel splits an aggregate model name into the model and its host
+// Names without a host are returned unchanged with an empty host
+func SplitModel(name string) (model, host string) {
+	i := strings.LastIndex(name, hostSeparator)
+	if i < 0 {
+		return name, ""
+	}
+	return name[:i], name[i+len(hostSeparator):]
+}
+
+func (p *AggregateProvider) Name() string {
+	return ProviderAggregate
+}
+
+// URL returns the URL of the first member
+func (p *AggregateProvider) URL() string {
+	if len(p.members) == 0 {
+		return ""
+	}
+	return p.members[0].Provider.URL()
+}
+
+// Members returns the providers taking part in the aggregate
+func (p *AggregateProvider) Members() []Member {
+	return p.members
+}
+
+// Ping succeeds if at least one member is reachable
+func (p *AggregateProvider) Ping(ctx context.Context) error {
+	var errs []string
+	for _, member := range p.members {
+		err := member.Provider.Ping(ctx)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, member.Name+": "+err.Error())
+	}
+	return fmt.Errorf("no endpoint reachable: %s", strings.Join(errs, "; "))
+}
+
+// ListModels lists the models of all reachable members, grouped by member
+// Members that can't be reached are left out
+func (p *AggregateProvider) ListModels(ctx context.Context) ([]string, error) {
+	lists := make([][]string, len(p.members))
+	errs := make([]error, len(p.members))
+
+	var wg sync.WaitGroup
+	for i, member := range p.members {
+		wg.Add(1)
+		go func(i int, member Member) {
+			defer wg.Done()
+			lists[i], errs[i] = member.Provider.ListModels(ctx)
+		}(i, member)
+	}
+	wg.Wait()
+
+	var names []string
+	var failed []string
+	for i, member := range p.members {
+		if errs[i] != nil {
+			failed = append(failed, member.Name+": "+errs[i].Error())
+			continue
+		}
+		for _, model := range lists[i] {
+			names = append(names, QualifyModel(model, member.Name))
+		}
+	}
+	if len(failed) == len(p.members) && len(failed) > 0 {
+		return nil, fmt.Errorf("failed to list models: %s", strings.Join(failed, "; "))
+	}
+	return names, nil
+}
+
+func (p *AggregateProvider) ModelInfo(ctx context.Context, model string) (models.Info, error) {
+	provider, name, err := p.route(model)
+	if err != nil {
+		return models.Info{}, err
+	}
+
+	info, err := provider.ModelInfo(ctx, name)
+	info.Name = model
+	return info, err
+}
+
+func (p *AggregateProvider) Chat(ctx context.Context, req ChatRequest) (llm.Answer, error) {
+	provider, name, err := p.route(req.Model)
+	if err != nil {
+		return llm.Answer{}, err
+	}
+
+	req.Model = name
+	return provider.Chat(ctx, req)
+}
+
+func (p *AggregateProvider) ChatStream(ctx context.Context, req ChatRequest, onChunk func(llm.Answer) error) (llm.Answer, error) {
+	provider, name, err := p.route(req.Model)
+	if err != nil {
+		return llm.Answer{}, err
+	}
+
+	req.Model = name
+	return provider.ChatStream(ctx, req, onChunk)
+}
+
+func (p *AggregateProvider) Embed(ctx context.Context, model, text string) ([]float64, error) {
+	provider, name, err := p.route(model)
+	if err != nil {
+		return nil, err
+	}
+	return provider.Embed(ctx, name, text)
+}
+
+// route finds the member serving an aggregate model name
+func (p *AggregateProvider) route(model string) (Provider, string, error) {
+	name, host := SplitModel(model)
+	for _, member := range p.members {
+		if member.Name == host {
+			return member.Provider, name, nil
+		}
+	}
+	return nil, "", fmt.Errorf("no endpoint serves model %s", model)
+}
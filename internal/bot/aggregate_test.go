@@ -0,0 +1,91 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/kevensen/gollama-bubbletea/internal/bot/models"
+
+	"github.com/parakeet-nest/parakeet/llm"
+)
+
+// fakeProvider serves a fixed model list and echoes the model it was asked for
+type fakeProvider struct {
+	url    string
+	models []string
+	down   bool
+	sent   []ChatRequest // Chat requests received, in order
+}
+
+func (f *fakeProvider) Name() string { return "fake" }
+func (f *fakeProvider) URL() string  { return f.url }
+
+func (f *fakeProvider) Ping(ctx context.Context) error {
+	if f.down {
+		return fmt.Errorf("connection refused")
+	}
+	return nil
+}
+
+func (f *fakeProvider) ListModels(ctx context.Context) ([]string, error) {
+	if f.down {
+		return nil, fmt.Errorf("connection refused")
+	}
+	return f.models, nil
+}
+
+func (f *fakeProvider) ModelInfo(ctx context.Context, model string) (models.Info, error) {
+	return models.Info{Name: model, ContextLength: 8192}, nil
+}
+
+func (f *fakeProvider) Chat(ctx context.Context, req ChatRequest) (llm.Answer, error) {
+	f.sent = append(f.sent, req)
+	return llm.Answer{Message: llm.Message{Role: "assistant", Content: f.url + " " + req.Model}}, nil
+}
+
+func (f *fakeProvider) ChatStream(ctx context.Context, req ChatRequest, onChunk func(llm.Answer) error) (llm.Answer, error) {
+	return f.Chat(ctx, req)
+}
+
+func (f *fakeProvider) Embed(ctx context.Context, model, text string) ([]float64, error) {
+	return []float64{1}, nil
+}
+
+func TestAggregateProvider(t *testing.T) {
+	ctx := context.Background()
+	provider := NewAggregateProvider([]Member{
+		{Name: "laptop", Provider: &fakeProvider{url: "http://laptop", models: []string{"llama3.2:1b"}}},
+		{Name: "workstation", Provider: &fakeProvider{url: "http://gpu", models: []string{"llama3.3:70b", "llama3.2:1b"}}},
+		{Name: "offline", Provider: &fakeProvider{url: "http://off", down: true}},
+	})
+
+	names, err := provider.ListModels(ctx)
+	if err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+	want := []string{"llama3.2:1b@laptop", "llama3.3:70b@workstation", "llama3.2:1b@workstation"}
+	if !slices.Equal(names, want) {
+		t.Errorf("ListModels() = %v, want %v", names, want)
+	}
+
+	// Chat requests go to the host owning the model, with the plain model name
+	ans, err := provider.Chat(ctx, ChatRequest{Model: "llama3.2:1b@workstation"})
+	if err != nil || ans.Message.Content != "http://gpu llama3.2:1b" {
+		t.Errorf("Chat() = %q, %v", ans.Message.Content, err)
+	}
+
+	if _, err := provider.Chat(ctx, ChatRequest{Model: "llama3.2:1b@nowhere"}); err == nil {
+		t.Error("Chat() to an unknown host should fail")
+	}
+
+	if err := provider.Ping(ctx); err != nil {
+		t.Errorf("Ping() error = %v, want success while one host is up", err)
+	}
+
+	model, host := SplitModel("hf.co/org/model@v2:Q4@workstation")
+	if model != "hf.co/org/model@v2:Q4" || host != "workstation" {
+		t.Errorf("SplitModel() = %q, %q", model, host)
+	}
+}
@@ -0,0 +1,172 @@
+package attachments
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Size limits keep a prompt within what local models can take in
+const (
+	MaxFileSize  = 256 << 10 // Largest single file that can be attached
+	MaxTotalSize = 1 << 20   // Largest total size of the files attached to one message
+)
+
+// Delimiters placed around each file inlined into a message
+const (
+	beginMarker = "----- BEGIN FILE: "
+	endMarker   = "----- END FILE: "
+	markerEnd   = " -----"
+)
+
+// File is a local text file attached to a message
+type File struct {
+	Path    string // As given by the user, used to name the file in the message
+	Content string
+}
+
+// Size returns the size of the file's content in bytes
+func (f File) Size() int {
+	return len(f.Content)
+}
+
+// Read loads a file to attach, refusing directories, files over MaxFileSize and
+// files that don't look like text
+func Read(path string) (File, error) {
+	info, err := os.Stat(ExpandHome(path))
+	if err != nil {
+		return File{}, fmt.Errorf("failed to attach %s: %v", path, err)
+	}
+	if info.IsDir() {
+		return File{}, fmt.Errorf("failed to attach %s: it is a directory", path)
+	}
+	if info.Size() > MaxFileSize {
+		return File{}, fmt.Errorf("failed to attach %s: %s is over the %s limit", path, FormatSize(int(info.Size())), FormatSize(MaxFileSize))
+	}
+
+	content, err := os.ReadFile(ExpandHome(path))
+	if err != nil {
+		return File{}, fmt.Errorf("failed to attach %s: %v", path, err)
+	}
+	if IsBinary(content) {
+		return File{}, fmt.Errorf("failed to attach %s: it looks like a binary file", path)
+	}
+	return File{Path: path, Content: string(content)}, nil
+}
+
+// IsBinary reports whether content looks like something other than UTF-8 text,
+// judging by a NUL byte or invalid UTF-8 near the start
+func IsBinary(content []byte) bool {
+	sample := content[:min(len(content), 8000)]
+	if bytes.IndexByte(sample, 0) >= 0 {
+		return true
+	}
+	// The sample may end part way through a character
+	for i := 0; i < utf8.UTFMax && len(sample) > 0 && !utf8.Valid(sample); i++ {
+		sample = sample[:len(sample)-1]
+	}
+	return !utf8.Valid(sample)
+}
+
+// TotalSize returns the combined size of files
+func TotalSize(files []File) int {
+	total := 0
+	for _, file := range files {
+		total += file.Size()
+	}
+	return total
+}
+
+// Inline appends files to a message, each between delimiters naming it
+func Inline(message string, files []File) string {
+	if len(files) == 0 {
+		return message
+	}
+
+	var b strings.Builder
+	b.WriteString(message)
+	for _, file := range files {
+		b.WriteString("\n\n" + beginMarker + file.Path + markerEnd + "\n")
+		b.WriteString(strings.TrimRight(file.Content, "\n"))
+		b.WriteString("\n" + endMarker + file.Path + markerEnd)
+	}
+	return b.String()
+}
+
+// Collapse replaces each file inlined into a message with a one-line summary,
+// for showing the message without the files' content
+func Collapse(message string) string {
+	var collapsed []string
+	scan(message, func(line string) {
+		collapsed = append(collapsed, line)
+	}, func(file File) {
+		lines := strings.Count(file.Content, "\n") + 1
+		collapsed = append(collapsed, fmt.Sprintf("📎 %s (%d lines, %s)", file.Path, lines, FormatSize(file.Size())))
+	})
+	return strings.Join(collapsed, "\n")
+}
+
+// Split separates the files inlined into a message from the text they were
+// attached to, undoing Inline
+func Split(message string) (string, []File) {
+	var text []string
+	var files []File
+	scan(message, func(line string) {
+		text = append(text, line)
+	}, func(file File) {
+		files = append(files, file)
+	})
+	return strings.TrimRight(strings.Join(text, "\n"), "\n"), files
+}
+
+// scan walks the lines of a message, passing each inlined file to file and every
+// other line to text
+func scan(message string, text func(line string), file func(File)) {
+	lines := strings.Split(message, "\n")
+	for i := 0; i < len(lines); i++ {
+		path, ok := strings.CutPrefix(lines[i], beginMarker)
+		path, suffixed := strings.CutSuffix(path, markerEnd)
+		if !ok || !suffixed {
+			text(lines[i])
+			continue
+		}
+
+		end := slices.Index(lines[i+1:], endMarker+path+markerEnd)
+		if end < 0 {
+			text(lines[i])
+			continue
+		}
+		end += i + 1
+		file(File{Path: path, Content: strings.Join(lines[i+1:end], "\n")})
+		i = end
+	}
+}
+
+// FormatSize renders a size in bytes, such as 12 KB
+func FormatSize(size int) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%d KB", size>>10)
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
+
+// ExpandHome replaces a leading ~ with the home directory
+func ExpandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~"+string(filepath.Separator))
+	if !ok {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, rest)
+}
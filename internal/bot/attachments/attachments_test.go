@@ -0,0 +1,121 @@
+package attachments
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content []byte) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	text := write("main.go", []byte("package main\n\nfunc main() {}\n"))
+	file, err := Read(text)
+	if err != nil {
+		t.Fatalf("Read(text) error = %v", err)
+	}
+	if file.Path != text || !strings.HasPrefix(file.Content, "package main") {
+		t.Errorf("Read(text) = %+v", file)
+	}
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"binary", write("image.png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"))},
+		{"invalid UTF-8", write("latin1.txt", []byte("caf\xe9 cr\xe8me"))},
+		{"too large", write("big.log", []byte(strings.Repeat("x", MaxFileSize+1)))},
+		{"directory", dir},
+		{"missing", filepath.Join(dir, "missing.txt")},
+	}
+	for _, test := range tests {
+		if _, err := Read(test.path); err == nil {
+			t.Errorf("Read(%s) should fail", test.name)
+		}
+	}
+
+	// A multi-byte character cut off by the sample isn't binary
+	if IsBinary([]byte(strings.Repeat("a", 7999) + "é")) {
+		t.Error("IsBinary() reported text with a character across the sample boundary")
+	}
+}
+
+func TestInlineAndCollapse(t *testing.T) {
+	files := []File{
+		{Path: "main.go", Content: "package main\n\nfunc main() {}\n"},
+		{Path: "notes/todo.md", Content: "- write tests"},
+	}
+	message := Inline("Explain these files", files)
+
+	for _, want := range []string{
+		"Explain these files\n\n----- BEGIN FILE: main.go -----\npackage main\n\nfunc main() {}\n----- END FILE: main.go -----",
+		"----- BEGIN FILE: notes/todo.md -----\n- write tests\n----- END FILE: notes/todo.md -----",
+	} {
+		if !strings.Contains(message, want) {
+			t.Errorf("Inline() = %q, missing %q", message, want)
+		}
+	}
+
+	want := "Explain these files\n\n📎 main.go (3 lines, 28 B)\n\n📎 notes/todo.md (1 lines, 13 B)"
+	if got := Collapse(message); got != want {
+		t.Errorf("Collapse() = %q, want %q", got, want)
+	}
+
+	text, split := Split(message)
+	if text != "Explain these files" || len(split) != 2 || split[0].Path != "main.go" || split[1].Content != "- write tests" {
+		t.Errorf("Split() = %q, %+v", text, split)
+	}
+
+	// A begin marker without its end is left alone
+	unterminated := "----- BEGIN FILE: a.txt -----\ntext"
+	if got := Collapse(unterminated); got != unterminated {
+		t.Errorf("Collapse(unterminated) = %q", got)
+	}
+}
+
+func TestReadImage(t *testing.T) {
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 64, 32))); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "cat.PNG")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if !IsImage(path) || IsImage(filepath.Join(dir, "notes.txt")) {
+		t.Error("IsImage() should go by the file extension")
+	}
+
+	img, err := ReadImage(path)
+	if err != nil {
+		t.Fatalf("ReadImage() error = %v", err)
+	}
+	if img.Format != "png" || img.Width != 64 || img.Height != 32 || img.MIMEType() != "image/png" {
+		t.Errorf("ReadImage() = %s %dx%d", img.Format, img.Width, img.Height)
+	}
+	if got := img.Placeholder(); got != "🖼 cat.PNG (64×32)" {
+		t.Errorf("Placeholder() = %q", got)
+	}
+
+	fake := filepath.Join(dir, "fake.jpg")
+	if err := os.WriteFile(fake, []byte("not an image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadImage(fake); err == nil {
+		t.Error("ReadImage() of a text file should fail")
+	}
+}
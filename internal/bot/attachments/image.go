@@ -0,0 +1,75 @@
+package attachments
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif" // Register decoders for the formats vision models accept
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// MaxImageSize is the largest image that can be attached, as it is sent base64 encoded
+const MaxImageSize = 10 << 20
+
+// imageExtensions are the file extensions attached as images rather than text
+var imageExtensions = []string{".png", ".jpg", ".jpeg", ".gif"}
+
+// Image is a local image attached to a message for a multimodal model
+type Image struct {
+	Path   string // As given by the user
+	Format string // Decoder name, such as png or jpeg
+	Width  int
+	Height int
+	Data   []byte
+}
+
+// IsImage reports whether a path names a file attached as an image, judging by its extension
+func IsImage(path string) bool {
+	return slices.Contains(imageExtensions, strings.ToLower(filepath.Ext(path)))
+}
+
+// ReadImage loads an image to attach, refusing files over MaxImageSize and
+// files that aren't a PNG, JPEG or GIF image
+func ReadImage(path string) (Image, error) {
+	info, err := os.Stat(ExpandHome(path))
+	if err != nil {
+		return Image{}, fmt.Errorf("failed to attach %s: %v", path, err)
+	}
+	if info.IsDir() {
+		return Image{}, fmt.Errorf("failed to attach %s: it is a directory", path)
+	}
+	if info.Size() > MaxImageSize {
+		return Image{}, fmt.Errorf("failed to attach %s: %s is over the %s limit", path, FormatSize(int(info.Size())), FormatSize(MaxImageSize))
+	}
+
+	data, err := os.ReadFile(ExpandHome(path))
+	if err != nil {
+		return Image{}, fmt.Errorf("failed to attach %s: %v", path, err)
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, fmt.Errorf("failed to attach %s: not a PNG, JPEG or GIF image", path)
+	}
+	return Image{Path: path, Format: format, Width: config.Width, Height: config.Height, Data: data}, nil
+}
+
+// Base64 returns the image data base64 encoded, as chat APIs expect it
+func (i Image) Base64() string {
+	return base64.StdEncoding.EncodeToString(i.Data)
+}
+
+// MIMEType returns the media type of the image, such as image/png
+func (i Image) MIMEType() string {
+	return "image/" + i.Format
+}
+
+// Placeholder describes the image in place of showing it, such as 🖼 cat.png (640×480)
+func (i Image) Placeholder() string {
+	return fmt.Sprintf("🖼 %s (%d×%d)", filepath.Base(i.Path), i.Width, i.Height)
+}
@@ -1,38 +1,43 @@
 package bot
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"net/http"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/kevensen/gollama-bubbletea/internal/bot/messages"
 	"github.com/kevensen/gollama-bubbletea/internal/bot/models"
+	"github.com/kevensen/gollama-bubbletea/internal/bot/rag"
+	"github.com/kevensen/gollama-bubbletea/internal/httpclient"
 
-	"github.com/parakeet-nest/parakeet/completion"
 	"github.com/parakeet-nest/parakeet/enums/option"
 	"github.com/parakeet-nest/parakeet/llm"
 )
 
 type Bot struct {
-	ollamaUrl      string
+	provider       Provider // LLM backend used for chat and model queries
 	MessageManager *messages.Manager
 	ModelManager   *models.Manager
+	ragMu          sync.Mutex          // Guards retriever and syncer, used from UI and command goroutines
+	retriever      *rag.Retriever      // Knowledge base retriever for the configured ChromaDB URL
+	syncer         *rag.Syncer         // Keeps the retriever's collection in sync with watch folders
+	chromaHTTP     *httpclient.Factory // HTTP clients for ChromaDB, nil for plain clients
+	ragCollection  string              // ChromaDB collection queried for context, empty for the default
+	persona        string              // System prompt sent ahead of every conversation
+	modelInfoCache *models.InfoCache   // Model information kept between runs, nil for none
 }
 
-func NewBot(ctx context.Context, apiEndpoint string, initialModel string) (*Bot, error) {
+func NewBot(ctx context.Context, provider Provider, initialModel string) (*Bot, error) {
 	b := &Bot{
-		ollamaUrl: apiEndpoint,
+		provider: provider,
 	}
 
 	b.MessageManager = messages.NewManager()
 
 	// Only try to create model manager if we can connect
-	if apiEndpoint != "" && TestConnection(apiEndpoint) == nil {
-		modelManager, err := models.NewManager(apiEndpoint, initialModel)
+	if TestConnection(provider) == nil {
+		modelManager, err := models.NewManager(provider, initialModel)
 		if err != nil {
 			// Don't fail bot creation if no models are available
 			// Just leave ModelManager as nil and let TUI handle it gracefully
@@ -46,29 +51,39 @@ func NewBot(ctx context.Context, apiEndpoint string, initialModel string) (*Bot,
 	return b, nil
 }
 
+// Provider returns the LLM backend the bot is using
+func (b *Bot) Provider() Provider {
+	return b.provider
+}
+
+// chatOptions are the options used for conversation turns
+func chatOptions() map[string]any {
+	return map[string]any{
+		option.Temperature:   0.5,
+		option.RepeatLastN:   2,
+		option.RepeatPenalty: 2.0,
+		option.Verbose:       false,
+	}
+}
+
 func (b *Bot) SendMessage(ctx context.Context, role, message string) (*llm.Answer, error) {
-	var msgsForSending []llm.Message
+	var msgsForSending []messages.Message
 	var err error
-	msg := llm.Message{Role: role, Content: message}
+	msg := messages.Message{Role: role, Content: message}
 
-	msgsForSending, err = b.MessageManager.MessagesForSending()
+	msgsForSending, err = b.MessageManager.ChatMessages()
 	msgsForSending = append(msgsForSending, msg)
 
-	query := llm.Query{
+	req := ChatRequest{
 		Model:    b.ModelManager.CurrentModel(),
-		Messages: msgsForSending,
-		Options: llm.SetOptions(map[string]any{
-			option.Temperature:   0.5,
-			option.RepeatLastN:   2,
-			option.RepeatPenalty: 2.0,
-			option.Verbose:       false,
-		}),
+		Messages: b.withPersona(msgsForSending),
+		Options:  chatOptions(),
 	}
 
-	b.MessageManager.AddMessage(msg)
+	b.MessageManager.AddMessage(msg.ToLLMMessage())
 
 	var ans llm.Answer
-	ans, err = completion.Chat(b.ollamaUrl, query)
+	ans, err = b.provider.Chat(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -76,100 +91,132 @@ func (b *Bot) SendMessage(ctx context.Context, role, message string) (*llm.Answe
 	return &ans, nil
 }
 
-// ChromaDBQuery represents a query to ChromaDB
-type ChromaDBQuery struct {
-	QueryTexts []string `json:"query_texts"`
-	NResults   int      `json:"n_results"`
+// SetPersona sets the system prompt sent ahead of every conversation, empty for none
+func (b *Bot) SetPersona(persona string) {
+	b.persona = persona
 }
 
-// ChromaDBResult represents search results from ChromaDB
-type ChromaDBResult struct {
-	Documents [][]string                 `json:"documents"`
-	Metadatas [][]map[string]interface{} `json:"metadatas"`
-	Distances [][]float64                `json:"distances"`
+// withPersona prepends the persona to the messages sent to the model
+func (b *Bot) withPersona(msgs []messages.Message) []messages.Message {
+	if b.persona == "" {
+		return msgs
+	}
+	return append([]messages.Message{{Role: "system", Content: b.persona}}, msgs...)
 }
 
 // SendRAGMessage sends a message with RAG context from ChromaDB
-func (b *Bot) SendRAGMessage(ctx context.Context, role, message, chromaDBURL string) (*llm.Answer, error) {
-	// First, search ChromaDB for relevant context
-	ragContext, err := b.searchChromaDB(chromaDBURL, message)
-	if err != nil {
-		// If RAG search fails, fall back to regular message but add a note
-		contextualMessage := fmt.Sprintf("(RAG search failed: %v)\n\n%s", err, message)
-		return b.SendMessage(ctx, role, contextualMessage)
-	}
+// The plain message and the retrieval context are both recorded in history
+func (b *Bot) SendRAGMessage(ctx context.Context, role, message, chromaDBURL string, opts rag.Options) (*llm.Answer, error) {
+	b.MessageManager.AddMessage(llm.Message{Role: role, Content: message})
+	ans, prompt, err := b.SendRAGMessageWithoutAdding(ctx, role, message, chromaDBURL, opts, nil)
+	b.MessageManager.AddContextMessage(prompt)
+	return ans, err
+}
 
-	// Enhance the message with RAG context
-	var enhancedMessage string
-	if ragContext != "" {
-		enhancedMessage = fmt.Sprintf("Context from knowledge base:\n%s\n\nUser question: %s", ragContext, message)
-	} else {
-		enhancedMessage = fmt.Sprintf("(No relevant context found in knowledge base)\n\nUser question: %s", message)
+// Retrieve returns the ranked knowledge base chunks for a query
+func (b *Bot) Retrieve(ctx context.Context, chromaDBURL, query string, opts rag.Options) ([]rag.Result, error) {
+	if chromaDBURL == "" {
+		return nil, fmt.Errorf("ChromaDB URL not configured")
 	}
 
-	// Send the enhanced message
-	return b.SendMessage(ctx, role, enhancedMessage)
+	return b.retrieverFor(chromaDBURL).Retrieve(ctx, query, opts, b.scoreRelevance)
 }
 
-// searchChromaDB searches the ChromaDB instance for relevant context
-func (b *Bot) searchChromaDB(chromaDBURL, query string) (string, error) {
-	if chromaDBURL == "" {
-		return "", fmt.Errorf("ChromaDB URL not configured")
+// buildRAGPrompt retrieves context for a message and builds the prompt sent in its place
+// If retrieval fails the prompt falls back to the message with a note and the error is returned
+func (b *Bot) buildRAGPrompt(ctx context.Context, chromaDBURL, message string, opts rag.Options) ([]rag.Result, string, error) {
+	results, err := b.Retrieve(ctx, chromaDBURL, message, opts)
+	if err != nil {
+		return nil, fmt.Sprintf("(RAG search failed: %v)\n\n%s", err, message), err
 	}
 
-	// Create ChromaDB query
-	chromaQuery := ChromaDBQuery{
-		QueryTexts: []string{query},
-		NResults:   3, // Get top 3 most relevant results
-	}
+	return results, rag.BuildPrompt(opts.EffectivePromptTemplate(), rag.FormatContext(results), message), nil
+}
 
-	queryData, err := json.Marshal(chromaQuery)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal ChromaDB query: %v", err)
+// InspectRAG retrieves chunks for a query and builds the prompt that would be sent,
+// without sending anything to the chat model or touching the history
+func (b *Bot) InspectRAG(ctx context.Context, chromaDBURL, query string, opts rag.Options) ([]rag.Result, string, error) {
+	return b.buildRAGPrompt(ctx, chromaDBURL, query, opts)
+}
+
+// retrieverFor returns the retriever for a ChromaDB URL, replacing it when the URL changes
+func (b *Bot) retrieverFor(chromaDBURL string) *rag.Retriever {
+	b.ragMu.Lock()
+	defer b.ragMu.Unlock()
+	return b.retrieverForLocked(chromaDBURL)
+}
+
+// retrieverForLocked is retrieverFor for callers already holding ragMu
+func (b *Bot) retrieverForLocked(chromaDBURL string) *rag.Retriever {
+	if b.retriever == nil || b.retriever.Chroma().URL() != chromaDBURL {
+		b.retriever = rag.NewRetriever(rag.NewChromaClient(chromaDBURL, b.ragCollection, b.chromaHTTP))
 	}
+	return b.retriever
+}
 
-	// Make request to ChromaDB (assuming a collection named "documents")
-	// This is a basic implementation - you may need to adjust the endpoint based on your ChromaDB setup
-	searchURL := fmt.Sprintf("%s/api/v1/collections/documents/query", chromaDBURL)
+// SetChromaHTTP sets the HTTP client factory used for ChromaDB requests
+// The knowledge base retriever is rebuilt on next use
+func (b *Bot) SetChromaHTTP(factory *httpclient.Factory) {
+	b.ragMu.Lock()
+	defer b.ragMu.Unlock()
+	b.chromaHTTP = factory
+	b.retriever = nil
+	b.syncer = nil
+}
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+// SetRAGCollection sets the ChromaDB collection queried for context, empty for the default
+// The knowledge base retriever is rebuilt on next use
+func (b *Bot) SetRAGCollection(collection string) {
+	b.ragMu.Lock()
+	defer b.ragMu.Unlock()
+	if collection != b.ragCollection {
+		b.ragCollection = collection
+		b.retriever = nil
+		b.syncer = nil
 	}
+}
 
-	req, err := http.NewRequest("POST", searchURL, bytes.NewBuffer(queryData))
-	if err != nil {
-		return "", fmt.Errorf("failed to create ChromaDB request: %v", err)
+// KnowledgeBaseSyncer returns the syncer for a ChromaDB URL, keeping its manifest in manifestDir
+func (b *Bot) KnowledgeBaseSyncer(chromaDBURL, manifestDir string) (*rag.Syncer, error) {
+	if chromaDBURL == "" {
+		return nil, fmt.Errorf("ChromaDB URL not configured")
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	b.ragMu.Lock()
+	defer b.ragMu.Unlock()
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to query ChromaDB: %v", err)
+	retriever := b.retrieverForLocked(chromaDBURL)
+	manifestPath := rag.ManifestPath(manifestDir, chromaDBURL, retriever.Chroma().Collection())
+	if b.syncer == nil || b.syncer.Retriever() != retriever || b.syncer.ManifestPath() != manifestPath {
+		syncer, err := rag.NewSyncer(retriever, manifestPath)
+		if err != nil {
+			return nil, err
+		}
+		b.syncer = syncer
 	}
-	defer resp.Body.Close()
+	return b.syncer, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("ChromaDB returned status %d", resp.StatusCode)
+// scoreRelevance asks the current model how relevant a passage is to a query
+func (b *Bot) scoreRelevance(ctx context.Context, query, text string) (float64, error) {
+	if b.ModelManager == nil {
+		return 0, fmt.Errorf("no model manager available")
 	}
 
-	// Parse response
-	var result ChromaDBResult
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("failed to decode ChromaDB response: %v", err)
+	scoreReq := ChatRequest{
+		Model:    b.ModelManager.CurrentModel(),
+		Messages: []messages.Message{{Role: "user", Content: rag.RelevancePrompt(query, text)}},
+		Options: map[string]any{
+			option.Temperature: 0.0,
+			option.Verbose:     false,
+		},
 	}
 
-	// Extract and format the relevant documents
-	var contextBuilder strings.Builder
-	if len(result.Documents) > 0 && len(result.Documents[0]) > 0 {
-		for i, doc := range result.Documents[0] {
-			if i < 3 { // Limit to top 3 results
-				contextBuilder.WriteString(fmt.Sprintf("Document %d: %s\n", i+1, doc))
-			}
-		}
+	ans, err := b.provider.Chat(ctx, scoreReq)
+	if err != nil {
+		return 0, err
 	}
-
-	return contextBuilder.String(), nil
+	return rag.ParseRelevanceScore(ans.Message.Content)
 }
 
 func (b *Bot) MessageLen() int {
@@ -195,108 +242,114 @@ func (b *Bot) ClearMessages() {
 	b.MessageManager.Clear()
 }
 
-// TestConnection tests if the Ollama server is reachable
-func TestConnection(url string) error {
-	if url == "" {
-		return fmt.Errorf("no Ollama URL configured")
+// TestConnection tests if the provider's server is reachable
+func TestConnection(provider Provider) error {
+	if provider == nil || provider.URL() == "" {
+		return fmt.Errorf("no server URL configured")
 	}
 
-	client := &http.Client{
-		Timeout: 5 * time.Second,
-	}
-
-	// Test the /api/tags endpoint which should be available on Ollama
-	resp, err := client.Get(url + "/api/tags")
-	if err != nil {
-		return fmt.Errorf("failed to connect to Ollama at %s: %v", url, err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("Ollama server at %s returned status %d", url, resp.StatusCode)
-	}
-
-	return nil
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return provider.Ping(ctx)
 }
 
-// InitializeModelManager creates the model manager with the given URL and model
-func (b *Bot) InitializeModelManager(apiEndpoint string, initialModel string) error {
-	if apiEndpoint == "" {
+// InitializeModelManager creates the model manager for the given provider and model
+func (b *Bot) InitializeModelManager(provider Provider, initialModel string) error {
+	if provider == nil || provider.URL() == "" {
 		return fmt.Errorf("API endpoint cannot be empty")
 	}
 
 	// Test connection first
-	if err := TestConnection(apiEndpoint); err != nil {
+	if err := TestConnection(provider); err != nil {
 		return err
 	}
 
-	b.ollamaUrl = apiEndpoint
+	b.provider = provider
 
-	modelManager, err := models.NewManager(apiEndpoint, initialModel)
+	modelManager, err := models.NewManager(provider, initialModel)
 	if err != nil {
 		return fmt.Errorf("failed to create model manager: %v", err)
 	}
+	modelManager.SetInfoCache(b.modelInfoCache, provider.URL())
 
 	b.ModelManager = modelManager
 	return nil
 }
 
+// SetModelInfoCache keeps model information in a cache between runs, for the
+// current model manager and those created when switching servers
+func (b *Bot) SetModelInfoCache(cache *models.InfoCache) {
+	b.modelInfoCache = cache
+	if b.ModelManager != nil {
+		b.ModelManager.SetInfoCache(cache, b.provider.URL())
+	}
+}
+
 // HasValidConnection returns true if the bot has a working model manager
 func (b *Bot) HasValidConnection() bool {
 	return b.ModelManager != nil
 }
 
-// SendMessageWithoutAdding sends a message to the LLM without adding the user message to the history
-// This is useful when the caller has already added the user message to the MessageManager
-func (b *Bot) SendMessageWithoutAdding(ctx context.Context, role, message string) (*llm.Answer, error) {
-	var msgsForSending []llm.Message
-	var err error
-	msg := llm.Message{Role: role, Content: message}
-
-	// Get existing messages and add the new message only for this request
-	msgsForSending, err = b.MessageManager.MessagesForSending()
-	msgsForSending = append(msgsForSending, msg)
+// SendMessageWithoutAdding sends the conversation to the LLM without adding the user message to the history
+// The caller must already have added the user message, with its images, to the MessageManager
+// A non-nil onChunk streams the answer, receiving each part as it arrives
+func (b *Bot) SendMessageWithoutAdding(ctx context.Context, onChunk func(llm.Answer) error) (*llm.Answer, error) {
+	msgsForSending, err := b.MessageManager.ChatMessages()
+	if err != nil {
+		return nil, err
+	}
 
-	query := llm.Query{
+	req := ChatRequest{
 		Model:    b.ModelManager.CurrentModel(),
-		Messages: msgsForSending,
-		Options: llm.SetOptions(map[string]any{
-			option.Temperature:   0.5,
-			option.RepeatLastN:   2,
-			option.RepeatPenalty: 2.0,
-			option.Verbose:       false,
-		}),
+		Messages: b.withPersona(msgsForSending),
+		Options:  chatOptions(),
 	}
 
 	// Note: We don't add the message to MessageManager here since caller already did
 
-	var ans llm.Answer
-	ans, err = completion.Chat(b.ollamaUrl, query)
-	if err != nil {
-		return nil, err
-	}
-
-	return &ans, nil
+	return b.chat(ctx, req, onChunk)
 }
 
 // SendRAGMessageWithoutAdding sends a RAG-enhanced message without adding the user message to history
-func (b *Bot) SendRAGMessageWithoutAdding(ctx context.Context, role, message, chromaDBURL string) (*llm.Answer, error) {
-	// First, search ChromaDB for relevant context
-	ragContext, err := b.searchChromaDB(chromaDBURL, message)
+// The caller must already have added the plain user message, and records the returned
+// prompt, sent in its place, after it with AddContextMessage; the prompt is returned
+// even when the chat fails
+// The history is only read, so this can run off the goroutine that changes it
+func (b *Bot) SendRAGMessageWithoutAdding(ctx context.Context, role, message, chromaDBURL string, opts rag.Options, onChunk func(llm.Answer) error) (*llm.Answer, string, error) {
+	// Search for relevant context; if RAG search fails the prompt falls back to the plain message with a note
+	_, enhancedMessage, _ := b.buildRAGPrompt(ctx, chromaDBURL, message, opts)
+
+	msgsForSending, err := b.MessageManager.ChatMessages()
 	if err != nil {
-		// If RAG search fails, fall back to regular message but add a note
-		contextualMessage := fmt.Sprintf("(RAG search failed: %v)\n\n%s", err, message)
-		return b.SendMessageWithoutAdding(ctx, role, contextualMessage)
+		return nil, enhancedMessage, err
+	}
+	// Send the prompt in place of the message, as ChatMessages does once it is recorded
+	if last := len(msgsForSending) - 1; last >= 0 && msgsForSending[last].Role == role {
+		msgsForSending[last].Content = enhancedMessage
+	}
+
+	req := ChatRequest{
+		Model:    b.ModelManager.CurrentModel(),
+		Messages: b.withPersona(msgsForSending),
+		Options:  chatOptions(),
 	}
 
-	// Enhance the message with RAG context
-	var enhancedMessage string
-	if ragContext != "" {
-		enhancedMessage = fmt.Sprintf("Context from knowledge base:\n%s\n\nUser question: %s", ragContext, message)
+	ans, err := b.chat(ctx, req, onChunk)
+	return ans, enhancedMessage, err
+}
+
+// chat sends a request, streaming the answer to onChunk unless it is nil
+func (b *Bot) chat(ctx context.Context, req ChatRequest, onChunk func(llm.Answer) error) (*llm.Answer, error) {
+	var ans llm.Answer
+	var err error
+	if onChunk != nil {
+		ans, err = b.provider.ChatStream(ctx, req, onChunk)
 	} else {
-		enhancedMessage = fmt.Sprintf("(No relevant context found in knowledge base)\n\nUser question: %s", message)
+		ans, err = b.provider.Chat(ctx, req)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	// Send the enhanced message
-	return b.SendMessageWithoutAdding(ctx, role, enhancedMessage)
+	return &ans, nil
 }
@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kevensen/gollama-bubbletea/internal/bot/attachments"
+	"github.com/kevensen/gollama-bubbletea/internal/bot/rag"
+	"github.com/parakeet-nest/parakeet/llm"
+)
+
+func TestSendMessageWithoutAddingSendsEachMessageOnce(t *testing.T) {
+	provider := &fakeProvider{url: "http://laptop", models: []string{"llama3.2:1b"}}
+	b, err := NewBot(context.Background(), provider, "llama3.2:1b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.MessageManager.AddMessage(llm.Message{Role: "user", Content: "Hello"})
+	b.MessageManager.AddMessage(llm.Message{Role: "assistant", Content: "Hi"})
+	question := attachments.Inline("What does this do?", []attachments.File{{Path: "main.go", Content: "package main"}})
+	diagram := attachments.Image{Path: "diagram.png", Format: "png", Width: 1, Height: 1, Data: []byte{1}}
+	b.MessageManager.AddImageMessage(llm.Message{Role: "user", Content: question}, []attachments.Image{diagram})
+
+	if _, err := b.SendMessageWithoutAdding(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(provider.sent) != 1 {
+		t.Fatalf("sent %d requests, want 1", len(provider.sent))
+	}
+	sent := provider.sent[0].Messages
+	if len(sent) != 3 {
+		t.Fatalf("sent %d messages, want 3: %+v", len(sent), sent)
+	}
+	last := sent[2]
+	if last.Role != "user" || last.Content != question || len(last.Images) != 1 {
+		t.Errorf("last message sent = %+v, want the question with its file and image", last)
+	}
+}
+
+func TestSendRAGMessageWithoutAddingLeavesHistory(t *testing.T) {
+	provider := &fakeProvider{url: "http://laptop", models: []string{"llama3.2:1b"}}
+	b, err := NewBot(context.Background(), provider, "llama3.2:1b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.MessageManager.AddMessage(llm.Message{Role: "user", Content: "What is KAFKA_E123?"})
+
+	// An unreachable ChromaDB still sends the question, with a note
+	_, prompt, err := b.SendRAGMessageWithoutAdding(context.Background(), "user", "What is KAFKA_E123?", "http://127.0.0.1:1", rag.Options{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.MessageManager.Len() != 1 {
+		t.Errorf("history has %d messages, want the question only", b.MessageManager.Len())
+	}
+	sent := provider.sent[0].Messages
+	if len(sent) != 1 || sent[0].Content != prompt || !strings.Contains(prompt, "RAG search failed") {
+		t.Errorf("sent %+v, want the returned prompt %q in place of the question", sent, prompt)
+	}
+
+	// Once recorded, later turns see the same conversation
+	b.MessageManager.AddContextMessage(prompt)
+	if msgs, _ := b.MessageManager.ChatMessages(); len(msgs) != 1 || msgs[0].Content != prompt {
+		t.Errorf("ChatMessages() = %+v after recording the prompt", msgs)
+	}
+}
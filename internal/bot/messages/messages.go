@@ -1,24 +1,44 @@
 package messages
 
 import (
+	"fmt"
 	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/kevensen/gollama-bubbletea/internal/bot/attachments"
 	"github.com/parakeet-nest/parakeet/history"
 	"github.com/parakeet-nest/parakeet/llm"
 	"golang.org/x/exp/maps"
 )
 
+// RoleContext marks a message holding the retrieval-augmented prompt that was
+// actually sent to the model in place of the preceding user message
+const RoleContext = "context"
+
+// ContextPolicy decides whether past retrieval context is re-sent on later turns
+type ContextPolicy int
+
+const (
+	// ContextPolicyDrop sends retrieval context only with the turn it was retrieved for
+	ContextPolicyDrop ContextPolicy = iota
+	// ContextPolicyKeep re-sends all past retrieval context on every turn
+	ContextPolicyKeep
+)
+
 type Manager struct {
 	history          history.MemoryMessages
 	currentMessageID int
+	contextPolicy    ContextPolicy
+
+	images map[string][]attachments.Image // Images attached to messages, by message ID
 }
 
 type Message struct {
 	Role    string
 	Content string
+	Images  []attachments.Image // Sent to multimodal models alongside the content
 }
 
 func (msg *Message) ToLLMMessage() llm.Message {
@@ -31,6 +51,7 @@ func NewManager() *Manager {
 		history: history.MemoryMessages{
 			Messages: make(map[string]llm.MessageRecord),
 		},
+		images: make(map[string][]attachments.Image),
 	}
 }
 
@@ -40,19 +61,84 @@ func (m *Manager) AddMessage(msg llm.Message) *llm.Message {
 	return &msg
 }
 
+// AddImageMessage adds a message with images attached, kept with the message so
+// they are sent again on later turns
+func (m *Manager) AddImageMessage(msg llm.Message, images []attachments.Image) *llm.Message {
+	added := m.AddMessage(msg)
+	if len(images) > 0 {
+		m.images[strconv.Itoa(m.currentMessageID)] = images
+	}
+	return added
+}
+
+// AddContextMessage records the retrieval-augmented prompt sent for the most recent user message
+func (m *Manager) AddContextMessage(content string) *llm.Message {
+	return m.AddMessage(llm.Message{Role: RoleContext, Content: content})
+}
+
+// SetContextPolicy sets whether past retrieval context is re-sent on later turns
+func (m *Manager) SetContextPolicy(policy ContextPolicy) {
+	m.contextPolicy = policy
+}
+
+// ContextPolicy returns the current retrieval context policy
+func (m *Manager) ContextPolicy() ContextPolicy {
+	return m.contextPolicy
+}
+
+// MessagesForSending returns the conversation as it should be sent to the model.
+// A user message followed by a context message is replaced by the context when
+// the policy keeps it or when it belongs to the current turn; otherwise the plain
+// user message is sent and the retrieval context is dropped.
 func (m *Manager) MessagesForSending() ([]llm.Message, error) {
+	msgs, err := m.ChatMessages()
+	if err != nil {
+		return nil, err
+	}
+	var llmMsgs []llm.Message
+	for _, msg := range msgs {
+		llmMsgs = append(llmMsgs, msg.ToLLMMessage())
+	}
+	return llmMsgs, nil
+}
+
+// ChatMessages returns the same conversation as MessagesForSending with the
+// images attached to each message
+func (m *Manager) ChatMessages() ([]Message, error) {
 	llms, err := m.history.GetAllMessages()
 	if err != nil {
 		return nil, err
 	}
 
-	var llmMsgs []llm.Message
-	for _, msg := range llms {
-		if msg.Role != "error" {
-			llmMsgs = append(llmMsgs, msg)
+	// The most recent context message belongs to the current turn only if it is
+	// the last message that would be sent
+	currentContext := -1
+	for i := len(llms) - 1; i >= 0; i-- {
+		if llms[i].Role == "error" {
+			continue
+		}
+		if llms[i].Role == RoleContext {
+			currentContext = i
 		}
+		break
 	}
-	return llmMsgs, nil
+
+	var msgs []Message
+	for i, msg := range llms {
+		switch msg.Role {
+		case "error", RoleContext:
+			continue
+		case "user":
+			if i+1 < len(llms) && llms[i+1].Role == RoleContext {
+				if m.contextPolicy == ContextPolicyKeep || i+1 == currentContext {
+					msg.Content = llms[i+1].Content
+				}
+			}
+		}
+		// History keeps messages in the order of its keys
+		msgs = append(msgs, Message{Role: msg.Role, Content: msg.Content, Images: m.images[m.history.Keys[i]]})
+	}
+	return msgs, nil
 }
 
 func (m *Manager) Len() int {
@@ -64,8 +150,8 @@ func (m *Manager) Len() int {
 func (m *Manager) EstimateTokens() int {
 	totalChars := 0
 
-	// Get all messages in the history
-	llms, err := m.history.GetAllMessages()
+	// Get the messages that would be sent, so dropped retrieval context isn't counted
+	llms, err := m.MessagesForSending()
 	if err != nil {
 		return 0
 	}
@@ -82,13 +168,39 @@ func (m *Manager) EstimateTokens() int {
 
 func (m *Manager) Clear() {
 	m.currentMessageID = 0
-	m.history.Messages = make(map[string]llm.MessageRecord)
+	m.history.RemoveAllMessages()
+	clear(m.images)
+}
+
+// StyledMessage is a line of the chat rendered by Styled
+type StyledMessage struct {
+	Index int // Position of the message in the conversation, as taken by MessageAt, or -1 for spacing
+	Text  string
 }
 
 func (m *Manager) StyledMessages() []string {
 	var messages []string
-	var roleStyled string
+	for _, styled := range m.Styled() {
+		messages = append(messages, styled.Text)
+	}
+	return messages
+}
+
+// MessageAt returns the message at a position in the conversation, with its images
+func (m *Manager) MessageAt(index int) (Message, bool) {
+	keys := m.sortedKeys()
+	if index < 0 || index >= len(keys) {
+		return Message{}, false
+	}
+	msg, err := m.history.Get(keys[index])
+	if err != nil {
+		return Message{}, false
+	}
+	return Message{Role: msg.Role, Content: msg.Content, Images: m.images[keys[index]]}, true
+}
 
+// sortedKeys returns the message IDs in the order the messages were added
+func (m *Manager) sortedKeys() []string {
 	keys := maps.Keys(m.history.Messages)
 
 	// Convert string keys to integers for proper numerical sorting
@@ -102,9 +214,27 @@ func (m *Manager) StyledMessages() []string {
 	}
 	slices.Sort(intKeys)
 
-	// Convert back to strings and process messages in correct order
+	sorted := make([]string, len(intKeys))
 	for i, intKey := range intKeys {
-		key := strconv.Itoa(intKey)
+		sorted[i] = strconv.Itoa(intKey)
+	}
+	return sorted
+}
+
+// Styled renders the conversation like StyledMessages, noting which message each entry shows
+func (m *Manager) Styled() []StyledMessage {
+	return m.StyledHighlighted(nil)
+}
+
+// StyledHighlighted renders the conversation like Styled, passing the content shown
+// for each message through highlight, such as to mark search matches
+func (m *Manager) StyledHighlighted(highlight func(index int, content string) string) []StyledMessage {
+	var messages []StyledMessage
+	var roleStyled string
+
+	// Process messages in correct order
+	keys := m.sortedKeys()
+	for i, key := range keys {
 		msg, err := m.history.Get(key)
 		if err != nil {
 			// Don't panic, heh
@@ -124,17 +254,33 @@ func (m *Manager) StyledMessages() []string {
 			c = lipgloss.Color("2") // Green for user
 		}
 
+		if msg.Role == RoleContext {
+			// Retrieval context is recorded for the model, only summarise it in the chat
+			c = lipgloss.Color("8") // Gray for retrieval context
+			summary := fmt.Sprintf("knowledge base context sent with the question (~%d tokens)", len(msg.Content)/4)
+			messages = append(messages, StyledMessage{i, lipgloss.NewStyle().Foreground(c).Render(role + ": " + summary)})
+			continue
+		}
+
+		content := Displayed(msg.Role, msg.Content)
+		if highlight != nil {
+			content = highlight(i, content)
+		}
+
+		for _, image := range m.images[key] {
+			content += "\n" + image.Placeholder()
+		}
+
 		roleStyled = lipgloss.NewStyle().Foreground(c).Render(role)
-		msgStyled := roleStyled + ": " + msg.Content
-		messages = append(messages, msgStyled)
+		msgStyled := roleStyled + ": " + content
+		messages = append(messages, StyledMessage{i, msgStyled})
 
 		// Add extra spacing after assistant messages when followed by a user message
-		if msg.Role == "assistant" && i < len(intKeys)-1 {
+		if msg.Role == "assistant" && i < len(keys)-1 {
 			// Check if the next message is from user
-			nextKey := strconv.Itoa(intKeys[i+1])
-			nextMsg, err := m.history.Get(nextKey)
+			nextMsg, err := m.history.Get(keys[i+1])
 			if err == nil && nextMsg.Role == "user" {
-				messages = append(messages, "") // Add blank line
+				messages = append(messages, StyledMessage{-1, ""}) // Add blank line
 			}
 		}
 	}
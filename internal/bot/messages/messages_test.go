@@ -1,9 +1,12 @@
 package messages
 
 import (
+	"slices"
 	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/kevensen/gollama-bubbletea/internal/bot/attachments"
 	"github.com/parakeet-nest/parakeet/llm"
 )
 
@@ -132,3 +135,148 @@ func TestStyledMessages(t *testing.T) {
 		// }
 	}
 }
+
+func TestMessagesForSendingWithContext(t *testing.T) {
+	history := []llm.Message{
+		{Role: "user", Content: "First"},
+		{Role: RoleContext, Content: "Context A\n\nFirst"},
+		{Role: "assistant", Content: "Answer"},
+		{Role: "user", Content: "Second"},
+		{Role: RoleContext, Content: "Context B\n\nSecond"},
+	}
+
+	tests := []struct {
+		policy ContextPolicy
+		want   []llm.Message
+	}{
+		{
+			policy: ContextPolicyDrop,
+			want: []llm.Message{
+				{Role: "user", Content: "First"},
+				{Role: "assistant", Content: "Answer"},
+				{Role: "user", Content: "Context B\n\nSecond"},
+			},
+		},
+		{
+			policy: ContextPolicyKeep,
+			want: []llm.Message{
+				{Role: "user", Content: "Context A\n\nFirst"},
+				{Role: "assistant", Content: "Answer"},
+				{Role: "user", Content: "Context B\n\nSecond"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		manager := NewManager()
+		manager.SetContextPolicy(test.policy)
+		for _, msg := range history {
+			manager.AddMessage(msg)
+		}
+
+		got, err := manager.MessagesForSending()
+		if err != nil {
+			t.Errorf("MessagesForSending() error = %v", err)
+		}
+		if len(got) != len(test.want) {
+			t.Fatalf("MessagesForSending() = %v, want %v", got, test.want)
+		}
+		for i := range got {
+			if got[i].Role != test.want[i].Role || got[i].Content != test.want[i].Content {
+				t.Errorf("MessagesForSending()[%d] = %v, want %v", i, got[i], test.want[i])
+			}
+		}
+	}
+}
+
+func TestClearResetsOrder(t *testing.T) {
+	manager := NewManager()
+	manager.AddMessage(llm.Message{Role: "user", Content: "Old"})
+	manager.AddMessage(llm.Message{Role: "assistant", Content: "Old answer"})
+	manager.Clear()
+	manager.AddMessage(llm.Message{Role: "user", Content: "New"})
+
+	got, err := manager.MessagesForSending()
+	if err != nil {
+		t.Fatalf("MessagesForSending() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Content != "New" {
+		t.Errorf("MessagesForSending() after Clear() = %v, want only the new message", got)
+	}
+}
+
+func TestImageMessages(t *testing.T) {
+	image := attachments.Image{Path: "photos/cat.png", Format: "png", Width: 640, Height: 480, Data: []byte("png")}
+
+	manager := NewManager()
+	manager.AddImageMessage(llm.Message{Role: "user", Content: "What is this?"}, []attachments.Image{image})
+	manager.AddMessage(llm.Message{Role: "assistant", Content: "A cat"})
+	manager.AddMessage(llm.Message{Role: "user", Content: "What colour?"})
+
+	got, err := manager.ChatMessages()
+	if err != nil {
+		t.Fatalf("ChatMessages() error = %v", err)
+	}
+	if len(got) != 3 || len(got[0].Images) != 1 || len(got[1].Images) != 0 || len(got[2].Images) != 0 {
+		t.Fatalf("ChatMessages() = %+v, want the image kept with the first message only", got)
+	}
+
+	if styled := manager.RenderMessages(); !strings.Contains(styled, "🖼 cat.png (640×480)") {
+		t.Errorf("RenderMessages() = %q, want an image placeholder", styled)
+	}
+
+	manager.Clear()
+	manager.AddMessage(llm.Message{Role: "user", Content: "New"})
+	if got, _ := manager.ChatMessages(); len(got[0].Images) != 0 {
+		t.Errorf("ChatMessages() after Clear() = %+v, want no images", got)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	manager := NewManager()
+	manager.AddMessage(llm.Message{Role: "user", Content: "Why does the Kafka consumer lag?"})
+	manager.AddMessage(llm.Message{Role: "assistant", Content: "The consumer commits offsets too often. Consumer lag grows."})
+	manager.AddContextMessage("consumer context is summarised, not searched")
+	manager.AddMessage(llm.Message{Role: "user", Content: "Thanks\n\n----- BEGIN FILE: consumer.go -----\nconsumer code\n----- END FILE: consumer.go -----"})
+
+	tests := []struct {
+		input string
+		want  []Match
+	}{
+		{"consumer", []Match{{0, 19, 27}, {1, 4, 12}, {1, 40, 48}, {3, 13, 21}}},
+		{"Consumer", []Match{{1, 40, 48}}},
+		{"consumer role:user", []Match{{0, 19, 27}, {3, 13, 21}}},
+		{"/lag[s?]?/ role:assistant", []Match{{1, 49, 52}}},
+		{"role:user", nil},
+	}
+	for _, test := range tests {
+		query, opts, err := ParseSearch(test.input)
+		if err != nil {
+			t.Fatalf("ParseSearch(%q) error = %v", test.input, err)
+		}
+		got, err := manager.Search(query, opts)
+		if err != nil {
+			t.Fatalf("Search(%q) error = %v", test.input, err)
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("Search(%q) = %v, want %v", test.input, got, test.want)
+		}
+	}
+
+	if _, _, err := ParseSearch("lag role:robot"); err == nil {
+		t.Error("ParseSearch() with an unknown role should fail")
+	}
+	if _, err := manager.Search("(", SearchOptions{Regexp: true}); err == nil {
+		t.Error("Search() with an invalid regular expression should fail")
+	}
+
+	// Highlighting sees the same content the match offsets refer to
+	var shown []string
+	manager.StyledHighlighted(func(index int, content string) string {
+		shown = append(shown, content)
+		return content
+	})
+	if len(shown) != 3 || shown[2] != "Thanks\n\n📎 consumer.go (1 lines, 13 B)" {
+		t.Errorf("StyledHighlighted() content = %q", shown)
+	}
+}
@@ -0,0 +1,100 @@
+package messages
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/kevensen/gollama-bubbletea/internal/bot/attachments"
+)
+
+// searchRoles are the roles a search can be narrowed to
+var searchRoles = []string{"user", "assistant", "system", "error"}
+
+// SearchOptions narrows a search of the conversation
+type SearchOptions struct {
+	Regexp bool     // The query is a regular expression rather than plain text
+	Roles  []string // Roles of the messages searched, empty for every role
+}
+
+// Match is an occurrence of a search query in the conversation
+type Match struct {
+	Index int // Position of the message, as taken by MessageAt
+	Start int // Byte offsets of the match in the message's displayed content
+	End   int
+}
+
+// ParseSearch splits a typed search into its query and options: role:user (or
+// role:user,assistant) narrows it to roles, and a query between slashes, such as
+// /err(or)?s/, is a regular expression
+func ParseSearch(input string) (string, SearchOptions, error) {
+	var opts SearchOptions
+	var words []string
+	for _, word := range strings.Fields(input) {
+		roles, ok := strings.CutPrefix(word, "role:")
+		if !ok {
+			words = append(words, word)
+			continue
+		}
+		for _, role := range strings.Split(roles, ",") {
+			if !slices.Contains(searchRoles, role) {
+				return "", opts, fmt.Errorf("unknown role %q, use one of %s", role, strings.Join(searchRoles, ", "))
+			}
+			opts.Roles = append(opts.Roles, role)
+		}
+	}
+
+	query := strings.Join(words, " ")
+	if len(query) >= 2 && strings.HasPrefix(query, "/") && strings.HasSuffix(query, "/") {
+		query, opts.Regexp = query[1:len(query)-1], true
+	}
+	return query, opts, nil
+}
+
+// Search finds a query in the messages shown in the chat, in conversation order
+// Plain text matches ignore case unless the query has an upper-case letter
+func (m *Manager) Search(query string, opts SearchOptions) ([]Match, error) {
+	if query == "" {
+		return nil, nil
+	}
+	pattern := regexp.QuoteMeta(query)
+	if opts.Regexp {
+		pattern = query
+	}
+	if !opts.Regexp && !strings.ContainsFunc(query, unicode.IsUpper) {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %v", err)
+	}
+
+	var matches []Match
+	for i, key := range m.sortedKeys() {
+		msg, err := m.history.Get(key)
+		if err != nil || msg.Role == RoleContext {
+			continue
+		}
+		if len(opts.Roles) > 0 && !slices.Contains(opts.Roles, msg.Role) {
+			continue
+		}
+		for _, loc := range re.FindAllStringIndex(Displayed(msg.Role, msg.Content), -1) {
+			// Empty matches, such as from /a*/, can't be shown
+			if loc[1] > loc[0] {
+				matches = append(matches, Match{Index: i, Start: loc[0], End: loc[1]})
+			}
+		}
+	}
+	return matches, nil
+}
+
+// Displayed returns a message's content as shown in the chat, which Match offsets refer to
+func Displayed(role, content string) string {
+	if role == "user" {
+		// Attached files are sent in full but only named in the chat
+		return attachments.Collapse(content)
+	}
+	return content
+}
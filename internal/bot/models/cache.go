@@ -0,0 +1,69 @@
+package models
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// infoCacheTTL is how long cached model information is used before it is fetched
+// again, in case the model was pulled again with different details
+const infoCacheTTL = 24 * time.Hour
+
+// InfoCache keeps model information on disk between runs, as looking it up
+// takes a request to the server each time the context window is shown
+type InfoCache struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]cachedInfo // By server URL and model name
+}
+
+// cachedInfo is a cache entry with the time it was fetched
+type cachedInfo struct {
+	Info    Info      `json:"info"`
+	Fetched time.Time `json:"fetched"`
+}
+
+// NewInfoCache creates a cache kept in a file, reading the entries already in it
+// A missing or damaged file starts an empty cache, which is written on the first fetch
+func NewInfoCache(path string) *InfoCache {
+	c := &InfoCache{path: path, entries: make(map[string]cachedInfo)}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &c.entries)
+	}
+	return c
+}
+
+// get returns the cached information of a model when it is recent enough
+func (c *InfoCache) get(server, model string) (Info, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[cacheKey(server, model)]
+	if !ok || time.Since(entry.Fetched) > infoCacheTTL {
+		return Info{}, false
+	}
+	return entry.Info, true
+}
+
+// put caches the information of a model and saves the cache
+func (c *InfoCache) put(server, model string, info Info) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[cacheKey(server, model)] = cachedInfo{Info: info, Fetched: time.Now()}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0644)
+}
+
+func cacheKey(server, model string) string {
+	return server + " " + model
+}
@@ -1,32 +1,58 @@
 package models
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"net/http"
 	"slices"
-	"strings"
-	"time"
-
-	"github.com/parakeet-nest/parakeet/llm"
 )
 
+// DefaultContextWindow is assumed when a backend doesn't report a model's context length
+// Most modern models have at least 4k context
+const DefaultContextWindow = 4096
+
+// Info is the backend-neutral description of a model
+type Info struct {
+	Name          string   `json:"name"`
+	ContextLength int      `json:"contextLength"` // 0 when the backend doesn't report it
+	Family        string   `json:"family"`
+	Families      []string `json:"families"`
+	ParameterSize string   `json:"parameterSize"`
+}
+
+// visionFamilies are the model families of the image encoders Ollama reports for
+// multimodal models, such as clip for llava and mllama for llama3.2-vision
+var visionFamilies = []string{"clip", "mllama"}
+
+// SupportsVision reports whether the model accepts images, and whether that is
+// known at all: backends that don't report model families leave it unknown
+func (i Info) SupportsVision() (vision, known bool) {
+	if len(i.Families) == 0 && i.Family == "" {
+		return false, false
+	}
+	for _, family := range append([]string{i.Family}, i.Families...) {
+		if slices.Contains(visionFamilies, family) {
+			return true, true
+		}
+	}
+	return false, true
+}
+
+// Backend is the part of an LLM provider the model manager needs
+type Backend interface {
+	ListModels(ctx context.Context) ([]string, error)
+	ModelInfo(ctx context.Context, model string) (Info, error)
+}
+
 type Manager struct {
-	list         llm.ModelList
+	names        []string
 	currentModel string
-	ollamaURL    string // Store URL for detailed model queries
-}
+	backend      Backend // Backend used for detailed model queries
 
-// ModelDetails represents detailed model information from Ollama
-type ModelDetails struct {
-	License    string    `json:"license"`
-	ModelFile  string    `json:"modelfile"`
-	Parameters string    `json:"parameters"`
-	Template   string    `json:"template"`
-	Details    Details   `json:"details"`
-	ModelInfo  ModelInfo `json:"model_info"`
+	cache  *InfoCache // Model information kept between runs, nil to always ask the backend
+	server string     // URL of the backend, keeping its models apart from other servers' in the cache
 }
 
+// Details is the details block of Ollama's /api/show response
 type Details struct {
 	ParentModel       string   `json:"parent_model"`
 	Format            string   `json:"format"`
@@ -36,42 +62,21 @@ type Details struct {
 	QuantizationLevel string   `json:"quantization_level"`
 }
 
-type ModelInfo struct {
-	GeneralArchitecture               string  `json:"general.architecture"`
-	GeneralBasename                   string  `json:"general.basename"`
-	GeneralFileType                   int     `json:"general.file_type"`
-	GeneralFinetune                   string  `json:"general.finetune"`
-	GeneralParameterCount             int64   `json:"general.parameter_count"`
-	GeneralQuantizationVersion        int     `json:"general.quantization_version"`
-	GeneralSizeLabel                  string  `json:"general.size_label"`
-	GeneralType                       string  `json:"general.type"`
-	LlamaAttentionHeadCount           int     `json:"llama.attention.head_count"`
-	LlamaAttentionHeadCountKV         int     `json:"llama.attention.head_count_kv"`
-	LlamaAttentionLayerNormRMSEpsilon float64 `json:"llama.attention.layer_norm_rms_epsilon"`
-	LlamaBlockCount                   int     `json:"llama.block_count"`
-	LlamaContextLength                int     `json:"llama.context_length"` // This is what we want!
-	LlamaEmbeddingLength              int     `json:"llama.embedding_length"`
-	LlamaFeedForwardLength            int     `json:"llama.feed_forward_length"`
-	LlamaRopeDimensionCount           int     `json:"llama.rope.dimension_count"`
-	LlamaRopeFreqBase                 float64 `json:"llama.rope.freq_base"`
-	LlamaVocabSize                    int     `json:"llama.vocab_size"`
-}
-
-func NewManager(ollamaURL string, initialModel string) (*Manager, error) {
-	list, _, err := llm.GetModelsList(ollamaURL)
+func NewManager(backend Backend, initialModel string) (*Manager, error) {
+	names, err := backend.ListModels(context.Background())
 	if err != nil {
 		return nil, fmt.Errorf("failed to get models: %v", err)
 	}
 
 	mgr := &Manager{
-		list:      list,
-		ollamaURL: ollamaURL,
+		names:   names,
+		backend: backend,
 	}
 
 	// Get available model names
 	availableModels := mgr.ModelNames()
 	if len(availableModels) == 0 {
-		return nil, fmt.Errorf("no models available on Ollama server")
+		return nil, fmt.Errorf("no models available on server")
 	}
 
 	// Check if the initial model exists, if not use the first available model
@@ -86,19 +91,14 @@ func NewManager(ollamaURL string, initialModel string) (*Manager, error) {
 }
 
 func (m *Manager) ModelNames() []string {
-	var ms []string
-	for _, model := range m.list.Models {
-		ms = append(ms, model.Name)
-	}
-
-	return ms
+	return slices.Clone(m.names)
 }
 
 func (m *Manager) MaxModelNameLength() int {
 	maxLen := 0
-	for _, model := range m.list.Models {
-		if len(model.Name) > maxLen {
-			maxLen = len(model.Name)
+	for _, name := range m.names {
+		if len(name) > maxLen {
+			maxLen = len(name)
 		}
 	}
 
@@ -129,50 +129,43 @@ func (m *Manager) GetContextWindowSize() (int, error) {
 
 // GetContextWindowSizeForModel retrieves the context window size for a specific model
 func (m *Manager) GetContextWindowSizeForModel(modelName string) (int, error) {
-	if !m.ModelExists(modelName) {
-		return 0, fmt.Errorf("model not found: %s", modelName)
-	}
-
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+	info, err := m.ModelInfo(modelName)
+	if err != nil {
+		return 0, err
 	}
 
-	// Create request body with model name
-	body := fmt.Sprintf(`{"name": "%s"}`, modelName)
-	bodyReader := strings.NewReader(body)
-
-	// Make request to Ollama's /api/show endpoint
-	url := fmt.Sprintf("%s/api/show", m.ollamaURL)
-	req, err := http.NewRequest("POST", url, bodyReader)
-	if err != nil {
-		return 0, fmt.Errorf("failed to create request: %v", err)
+	if info.ContextLength > 0 {
+		return info.ContextLength, nil
 	}
 
-	// Set request headers
-	req.Header.Set("Content-Type", "application/json")
+	// If we can't find the context length, return a reasonable default
+	return DefaultContextWindow, nil
+}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return 0, fmt.Errorf("failed to get model details: %v", err)
+// ModelInfo retrieves details about a specific model from the backend
+func (m *Manager) ModelInfo(modelName string) (Info, error) {
+	if !m.ModelExists(modelName) {
+		return Info{}, fmt.Errorf("model not found: %s", modelName)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("failed to get model details: HTTP %d", resp.StatusCode)
+	if m.cache != nil {
+		if info, ok := m.cache.get(m.server, modelName); ok {
+			return info, nil
+		}
 	}
 
-	var details ModelDetails
-	if err := json.NewDecoder(resp.Body).Decode(&details); err != nil {
-		return 0, fmt.Errorf("failed to parse model details: %v", err)
+	info, err := m.backend.ModelInfo(context.Background(), modelName)
+	if err != nil {
+		return Info{}, fmt.Errorf("failed to get model details: %v", err)
 	}
-
-	// Try to get context length from different possible fields
-	if details.ModelInfo.LlamaContextLength > 0 {
-		return details.ModelInfo.LlamaContextLength, nil
+	if m.cache != nil {
+		// A cache that can't be written only means asking the backend again
+		m.cache.put(m.server, modelName, info)
 	}
+	return info, nil
+}
 
-	// If we can't find the context length, return a reasonable default
-	// Most modern models have at least 4k context
-	return 4096, nil
+// SetInfoCache keeps the information of the server's models in a cache
+func (m *Manager) SetInfoCache(cache *InfoCache, server string) {
+	m.cache, m.server = cache, server
 }
@@ -0,0 +1,255 @@
+package bot
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kevensen/gollama-bubbletea/internal/bot/models"
+	"github.com/kevensen/gollama-bubbletea/internal/httpclient"
+
+	"github.com/parakeet-nest/parakeet/llm"
+)
+
+// OllamaProvider talks to an Ollama server over its native API
+type OllamaProvider struct {
+	url  string
+	http *httpclient.Factory
+}
+
+// NewOllamaProvider creates a provider for the Ollama server at url
+// A nil factory uses plain HTTP clients
+func NewOllamaProvider(url string, factory *httpclient.Factory) *OllamaProvider {
+	return &OllamaProvider{url: url, http: factory}
+}
+
+// ollamaChatRequest is the body of /api/chat
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Options  llm.Options     `json:"options"`
+	Stream   bool            `json:"stream"`
+}
+
+// ollamaMessage is a chat message in the Ollama wire format
+type ollamaMessage struct {
+	Role    string   `json:"role"`
+	Content string   `json:"content"`
+	Images  []string `json:"images,omitempty"` // Base64 encoded, for multimodal models
+}
+
+func (p *OllamaProvider) Name() string {
+	return ProviderOllama
+}
+
+func (p *OllamaProvider) URL() string {
+	return p.url
+}
+
+// Ping tests the /api/tags endpoint which should be available on Ollama
+func (p *OllamaProvider) Ping(ctx context.Context) error {
+	client := p.http.Client(5 * time.Second)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", p.url+"/api/tags", nil)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Ollama at %s: %v", p.url, err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Ollama at %s: %v", p.url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Ollama server at %s returned status %d", p.url, resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (p *OllamaProvider) ListModels(ctx context.Context) ([]string, error) {
+	client := p.http.Client(10 * time.Second)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", p.url+"/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	var list llm.ModelList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to parse model list: %v", err)
+	}
+
+	var names []string
+	for _, model := range list.Models {
+		names = append(names, model.Name)
+	}
+	return names, nil
+}
+
+// ModelInfo queries Ollama's /api/show endpoint for a model's details
+func (p *OllamaProvider) ModelInfo(ctx context.Context, model string) (models.Info, error) {
+	resp, err := p.post(ctx, "/api/show", map[string]string{"name": model}, 10*time.Second)
+	if err != nil {
+		return models.Info{}, err
+	}
+	defer resp.Body.Close()
+
+	var details struct {
+		Details   models.Details `json:"details"`
+		ModelInfo map[string]any `json:"model_info"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&details); err != nil {
+		return models.Info{}, fmt.Errorf("failed to parse model details: %v", err)
+	}
+
+	info := models.Info{
+		Name:          model,
+		Family:        details.Details.Family,
+		Families:      details.Details.Families,
+		ParameterSize: details.Details.ParameterSize,
+	}
+
+	// The context length is keyed by architecture, e.g. llama.context_length
+	if arch, ok := details.ModelInfo["general.architecture"].(string); ok {
+		if length, ok := details.ModelInfo[arch+".context_length"].(float64); ok {
+			info.ContextLength = int(length)
+		}
+	}
+
+	return info, nil
+}
+
+func (p *OllamaProvider) Chat(ctx context.Context, req ChatRequest) (llm.Answer, error) {
+	resp, err := p.post(ctx, "/api/chat", p.chatRequest(req, false), 0)
+	if err != nil {
+		return llm.Answer{}, err
+	}
+	defer resp.Body.Close()
+
+	var answer llm.Answer
+	if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+		return llm.Answer{}, fmt.Errorf("failed to decode chat response: %v", err)
+	}
+	return answer, nil
+}
+
+// ChatStream reads the newline-delimited answers of a streamed chat
+func (p *OllamaProvider) ChatStream(ctx context.Context, req ChatRequest, onChunk func(llm.Answer) error) (llm.Answer, error) {
+	resp, err := p.post(ctx, "/api/chat", p.chatRequest(req, true), 0)
+	if err != nil {
+		return llm.Answer{}, err
+	}
+	defer resp.Body.Close()
+
+	var full llm.Answer
+	var content strings.Builder
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var chunk llm.Answer
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return llm.Answer{}, fmt.Errorf("failed to decode chat stream: %v", err)
+		}
+		content.WriteString(chunk.Message.Content)
+		if err := onChunk(chunk); err != nil {
+			return llm.Answer{}, err
+		}
+
+		// The final chunk carries the metrics for the whole answer
+		full = chunk
+	}
+	if err := scanner.Err(); err != nil {
+		return llm.Answer{}, err
+	}
+
+	full.Message.Content = content.String()
+	return full, nil
+}
+
+// Embed queries Ollama's /api/embeddings endpoint
+func (p *OllamaProvider) Embed(ctx context.Context, model, text string) ([]float64, error) {
+	resp, err := p.post(ctx, "/api/embeddings", map[string]string{"model": model, "prompt": text}, 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Embedding []float64 `json:"embedding"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode embedding response: %v", err)
+	}
+	if len(result.Embedding) == 0 {
+		return nil, fmt.Errorf("embedding is empty")
+	}
+	return result.Embedding, nil
+}
+
+// post sends a JSON body and returns the response, which must be closed by the caller
+// A zero timeout leaves the request bounded only by ctx, as chats can be slow
+func (p *OllamaProvider) post(ctx context.Context, path string, body any, timeout time.Duration) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.url+path, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.http.Client(timeout).Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("Ollama returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+// chatRequest converts a chat request to the Ollama wire format
+func (p *OllamaProvider) chatRequest(req ChatRequest, stream bool) ollamaChatRequest {
+	body := ollamaChatRequest{
+		Model:   req.Model,
+		Options: llm.SetOptions(req.Options),
+		Stream:  stream,
+	}
+	for _, msg := range req.Messages {
+		wire := ollamaMessage{Role: msg.Role, Content: msg.Content}
+		for _, image := range msg.Images {
+			wire.Images = append(wire.Images, image.Base64())
+		}
+		body.Messages = append(body.Messages, wire)
+	}
+	return body
+}
@@ -0,0 +1,80 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kevensen/gollama-bubbletea/internal/bot/attachments"
+	"github.com/kevensen/gollama-bubbletea/internal/bot/messages"
+	"github.com/kevensen/gollama-bubbletea/internal/httpclient"
+
+	"github.com/parakeet-nest/parakeet/llm"
+)
+
+func TestOllamaProvider(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/tags", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"models":[{"name":"llama3.2:1b"}]}`)
+	})
+	mux.HandleFunc("/api/chat", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"model":"llama3.2:1b","message":{"role":"assistant","content":"Hi "},"done":false}`)
+		fmt.Fprintln(w, `{"model":"llama3.2:1b","message":{"role":"assistant","content":"there"},"done":true,"eval_count":2}`)
+	})
+
+	// Requests without the configured token are rejected, as by a reverse proxy
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	if err := TestConnection(NewOllamaProvider(server.URL, nil)); err == nil {
+		t.Error("TestConnection() without a token should fail")
+	}
+
+	ctx := context.Background()
+	provider := NewOllamaProvider(server.URL, httpclient.New(httpclient.Config{BearerToken: "secret"}))
+	if err := TestConnection(provider); err != nil {
+		t.Fatalf("TestConnection() error = %v", err)
+	}
+
+	names, err := provider.ListModels(ctx)
+	if err != nil || len(names) != 1 || names[0] != "llama3.2:1b" {
+		t.Errorf("ListModels() = %v, %v", names, err)
+	}
+
+	var chunks int
+	ans, err := provider.ChatStream(ctx, ChatRequest{Model: "llama3.2:1b"}, func(llm.Answer) error {
+		chunks++
+		return nil
+	})
+	if err != nil || ans.Message.Content != "Hi there" || !ans.Done || chunks != 2 {
+		t.Errorf("ChatStream() = %+v, %v, %d chunks", ans, err, chunks)
+	}
+}
+
+func TestOllamaChatRequestImages(t *testing.T) {
+	image := attachments.Image{Path: "cat.png", Format: "png", Data: []byte("png")}
+	body := NewOllamaProvider("http://localhost:11434", nil).chatRequest(ChatRequest{
+		Messages: []messages.Message{
+			{Role: "system", Content: "be brief"},
+			{Role: "user", Content: "what is this?", Images: []attachments.Image{image}},
+		},
+	}, false)
+
+	data, err := json.Marshal(body.Messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"role":"system","content":"be brief"},{"role":"user","content":"what is this?","images":["cG5n"]}]`
+	if string(data) != want {
+		t.Errorf("messages = %s, want %s", data, want)
+	}
+}
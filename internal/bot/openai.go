@@ -0,0 +1,375 @@
+package bot
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kevensen/gollama-bubbletea/internal/bot/models"
+	"github.com/kevensen/gollama-bubbletea/internal/httpclient"
+
+	"github.com/parakeet-nest/parakeet/enums/option"
+	"github.com/parakeet-nest/parakeet/llm"
+)
+
+// OpenAIProvider talks to an OpenAI-compatible server such as llama.cpp server,
+// vLLM or LM Studio through its /v1 endpoints
+type OpenAIProvider struct {
+	url  string
+	http *httpclient.Factory
+}
+
+// NewOpenAIProvider creates a provider for the OpenAI-compatible server at url
+// A trailing /v1 is accepted and stripped; a nil factory uses plain HTTP clients
+func NewOpenAIProvider(url string, factory *httpclient.Factory) *OpenAIProvider {
+	url = strings.TrimSuffix(strings.TrimSuffix(url, "/"), "/v1")
+	return &OpenAIProvider{url: url, http: factory}
+}
+
+// openAIMessage is a chat message in the OpenAI wire format
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// openAIRequestMessage is a chat message sent to the server, whose content is
+// either text or, with images attached, a list of openAIContentPart
+type openAIRequestMessage struct {
+	Role    string `json:"role"`
+	Content any    `json:"content"`
+}
+
+// openAIContentPart is a piece of a message holding text or an image
+type openAIContentPart struct {
+	Type     string          `json:"type"` // text or image_url
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+}
+
+// openAIImageURL gives an image as a URL, here a base64 data URL
+type openAIImageURL struct {
+	URL string `json:"url"`
+}
+
+// openAIChatRequest is the body of /v1/chat/completions
+type openAIChatRequest struct {
+	Model            string                 `json:"model"`
+	Messages         []openAIRequestMessage `json:"messages"`
+	Stream           bool                   `json:"stream"`
+	Temperature      *float64               `json:"temperature,omitempty"`
+	TopP             *float64               `json:"top_p,omitempty"`
+	MaxTokens        *int                   `json:"max_tokens,omitempty"`
+	Seed             *int                   `json:"seed,omitempty"`
+	Stop             []string               `json:"stop,omitempty"`
+	PresencePenalty  *float64               `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64               `json:"frequency_penalty,omitempty"`
+}
+
+// openAIChatResponse covers both complete and streamed chat responses
+type openAIChatResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message      openAIMessage `json:"message"`
+		Delta        openAIMessage `json:"delta"`
+		FinishReason *string       `json:"finish_reason"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// openAIModel is an entry of /v1/models; the context length fields are
+// extensions reported by llama.cpp server and LM Studio
+type openAIModel struct {
+	ID               string `json:"id"`
+	ContextLength    int    `json:"context_length"`
+	MaxContextLength int    `json:"max_context_length"`
+	Meta             struct {
+		NCtxTrain int `json:"n_ctx_train"`
+	} `json:"meta"`
+}
+
+func (p *OpenAIProvider) Name() string {
+	return ProviderOpenAI
+}
+
+func (p *OpenAIProvider) URL() string {
+	return p.url
+}
+
+// Ping tests the /v1/models endpoint which every OpenAI-compatible server provides
+func (p *OpenAIProvider) Ping(ctx context.Context) error {
+	client := p.http.Client(5 * time.Second)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", p.url+"/v1/models", nil)
+	if err != nil {
+		return fmt.Errorf("failed to connect to server at %s: %v", p.url, err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect to server at %s: %v", p.url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server at %s returned status %d", p.url, resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (p *OpenAIProvider) ListModels(ctx context.Context) ([]string, error) {
+	list, err := p.models(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, model := range list {
+		names = append(names, model.ID)
+	}
+	return names, nil
+}
+
+func (p *OpenAIProvider) ModelInfo(ctx context.Context, model string) (models.Info, error) {
+	list, err := p.models(ctx)
+	if err != nil {
+		return models.Info{}, err
+	}
+
+	for _, m := range list {
+		if m.ID != model {
+			continue
+		}
+		info := models.Info{Name: m.ID}
+		switch {
+		case m.ContextLength > 0:
+			info.ContextLength = m.ContextLength
+		case m.MaxContextLength > 0:
+			info.ContextLength = m.MaxContextLength
+		case m.Meta.NCtxTrain > 0:
+			info.ContextLength = m.Meta.NCtxTrain
+		}
+		return info, nil
+	}
+	return models.Info{}, fmt.Errorf("model not found: %s", model)
+}
+
+func (p *OpenAIProvider) Chat(ctx context.Context, req ChatRequest) (llm.Answer, error) {
+	resp, err := p.post(ctx, "/v1/chat/completions", p.chatRequest(req, false), 0)
+	if err != nil {
+		return llm.Answer{}, err
+	}
+	defer resp.Body.Close()
+
+	var chat openAIChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chat); err != nil {
+		return llm.Answer{}, fmt.Errorf("failed to decode chat response: %v", err)
+	}
+	if len(chat.Choices) == 0 {
+		return llm.Answer{}, fmt.Errorf("chat response contained no choices")
+	}
+
+	return llm.Answer{
+		Model:           chat.Model,
+		Message:         llm.Message{Role: "assistant", Content: chat.Choices[0].Message.Content},
+		Done:            true,
+		CreatedAt:       time.Now(),
+		PromptEvalCount: chat.Usage.PromptTokens,
+		EvalCount:       chat.Usage.CompletionTokens,
+	}, nil
+}
+
+// ChatStream reads the server-sent events of a streamed chat completion
+func (p *OpenAIProvider) ChatStream(ctx context.Context, req ChatRequest, onChunk func(llm.Answer) error) (llm.Answer, error) {
+	resp, err := p.post(ctx, "/v1/chat/completions", p.chatRequest(req, true), 0)
+	if err != nil {
+		return llm.Answer{}, err
+	}
+	defer resp.Body.Close()
+
+	answer := llm.Answer{Model: req.Model, Message: llm.Message{Role: "assistant"}}
+	var content strings.Builder
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk openAIChatResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return llm.Answer{}, fmt.Errorf("failed to decode chat stream: %v", err)
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		if chunk.Model != "" {
+			answer.Model = chunk.Model
+		}
+
+		delta := chunk.Choices[0].Delta.Content
+		content.WriteString(delta)
+		partial := llm.Answer{
+			Model:   answer.Model,
+			Message: llm.Message{Role: "assistant", Content: delta},
+		}
+		if err := onChunk(partial); err != nil {
+			return llm.Answer{}, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return llm.Answer{}, err
+	}
+
+	answer.Message.Content = content.String()
+	answer.Done = true
+	answer.CreatedAt = time.Now()
+	return answer, nil
+}
+
+func (p *OpenAIProvider) Embed(ctx context.Context, model, text string) ([]float64, error) {
+	body := map[string]any{"model": model, "input": text}
+	resp, err := p.post(ctx, "/v1/embeddings", body, 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data []struct {
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode embedding response: %v", err)
+	}
+	if len(result.Data) == 0 || len(result.Data[0].Embedding) == 0 {
+		return nil, fmt.Errorf("embedding is empty")
+	}
+	return result.Data[0].Embedding, nil
+}
+
+// models fetches the /v1/models list
+func (p *OpenAIProvider) models(ctx context.Context) ([]openAIModel, error) {
+	client := p.http.Client(10 * time.Second)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", p.url+"/v1/models", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	var list struct {
+		Data []openAIModel `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to parse model list: %v", err)
+	}
+	return list.Data, nil
+}
+
+// post sends a JSON body and returns the response, which must be closed by the caller
+// A zero timeout leaves the request bounded only by ctx, as chat completions can be slow
+func (p *OpenAIProvider) post(ctx context.Context, path string, body any, timeout time.Duration) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.url+path, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.http.Client(timeout).Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+// chatRequest converts a chat request to the OpenAI wire format, mapping the
+// parakeet options that have an OpenAI equivalent
+func (p *OpenAIProvider) chatRequest(req ChatRequest, stream bool) openAIChatRequest {
+	body := openAIChatRequest{
+		Model:  req.Model,
+		Stream: stream,
+	}
+	for _, msg := range req.Messages {
+		if len(msg.Images) == 0 {
+			body.Messages = append(body.Messages, openAIRequestMessage{Role: msg.Role, Content: msg.Content})
+			continue
+		}
+		parts := []openAIContentPart{{Type: "text", Text: msg.Content}}
+		for _, image := range msg.Images {
+			url := "data:" + image.MIMEType() + ";base64," + image.Base64()
+			parts = append(parts, openAIContentPart{Type: "image_url", ImageURL: &openAIImageURL{URL: url}})
+		}
+		body.Messages = append(body.Messages, openAIRequestMessage{Role: msg.Role, Content: parts})
+	}
+
+	for key, value := range req.Options {
+		switch key {
+		case option.Temperature:
+			if v, ok := value.(float64); ok {
+				body.Temperature = &v
+			}
+		case option.TopP:
+			if v, ok := value.(float64); ok {
+				body.TopP = &v
+			}
+		case option.NumPredict:
+			if v, ok := value.(int); ok && v > 0 {
+				body.MaxTokens = &v
+			}
+		case option.Seed:
+			if v, ok := value.(int); ok {
+				body.Seed = &v
+			}
+		case option.Stop:
+			if v, ok := value.([]string); ok {
+				body.Stop = v
+			}
+		case option.PresencePenalty:
+			if v, ok := value.(float64); ok {
+				body.PresencePenalty = &v
+			}
+		case option.FrequencyPenalty:
+			if v, ok := value.(float64); ok {
+				body.FrequencyPenalty = &v
+			}
+		}
+	}
+	return body
+}
@@ -0,0 +1,117 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kevensen/gollama-bubbletea/internal/bot/attachments"
+	"github.com/kevensen/gollama-bubbletea/internal/bot/messages"
+
+	"github.com/parakeet-nest/parakeet/enums/option"
+	"github.com/parakeet-nest/parakeet/llm"
+)
+
+func newOpenAITestServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/models", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data":[{"id":"qwen2.5-7b","meta":{"n_ctx_train":32768}},{"id":"phi-3"}]}`)
+	})
+	mux.HandleFunc("/v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		var req openAIChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode chat request: %v", err)
+		}
+		if req.Temperature == nil || *req.Temperature != 0.5 {
+			t.Errorf("temperature = %v, want 0.5", req.Temperature)
+		}
+		if len(req.Messages) != 1 || req.Messages[0].Content != "hello" {
+			t.Errorf("messages = %+v", req.Messages)
+		}
+
+		if req.Stream {
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Hi \"}}]}\n\n")
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"there\"}}]}\n\n")
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			return
+		}
+		fmt.Fprint(w, `{"model":"qwen2.5-7b","choices":[{"message":{"role":"assistant","content":"Hi there"}}]}`)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestOpenAIProvider(t *testing.T) {
+	server := newOpenAITestServer(t)
+	defer server.Close()
+
+	ctx := context.Background()
+	provider := NewOpenAIProvider(server.URL+"/v1", nil)
+	if provider.URL() != server.URL {
+		t.Errorf("URL() = %q, want the /v1 suffix stripped", provider.URL())
+	}
+
+	if err := TestConnection(provider); err != nil {
+		t.Fatalf("TestConnection() error = %v", err)
+	}
+
+	names, err := provider.ListModels(ctx)
+	if err != nil || len(names) != 2 || names[0] != "qwen2.5-7b" {
+		t.Errorf("ListModels() = %v, %v", names, err)
+	}
+
+	info, err := provider.ModelInfo(ctx, "qwen2.5-7b")
+	if err != nil || info.ContextLength != 32768 {
+		t.Errorf("ModelInfo() = %+v, %v, want context length 32768", info, err)
+	}
+
+	req := ChatRequest{
+		Model:    "qwen2.5-7b",
+		Messages: []messages.Message{{Role: "user", Content: "hello"}},
+		Options:  map[string]any{option.Temperature: 0.5},
+	}
+
+	ans, err := provider.Chat(ctx, req)
+	if err != nil || ans.Message.Content != "Hi there" || !ans.Done {
+		t.Errorf("Chat() = %+v, %v", ans, err)
+	}
+
+	var chunks []string
+	ans, err = provider.ChatStream(ctx, req, func(chunk llm.Answer) error {
+		chunks = append(chunks, chunk.Message.Content)
+		return nil
+	})
+	if err != nil || ans.Message.Content != "Hi there" || len(chunks) != 2 {
+		t.Errorf("ChatStream() = %+v, %v, chunks %v", ans, err, chunks)
+	}
+}
+
+func TestOpenAIChatRequestImages(t *testing.T) {
+	image := attachments.Image{Path: "cat.png", Format: "png", Data: []byte("png")}
+	body := NewOpenAIProvider("http://localhost:8080", nil).chatRequest(ChatRequest{
+		Messages: []messages.Message{{Role: "user", Content: "what is this?", Images: []attachments.Image{image}}},
+	}, false)
+
+	data, err := json.Marshal(body.Messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"role":"user","content":[{"type":"text","text":"what is this?"},{"type":"image_url","image_url":{"url":"data:image/png;base64,cG5n"}}]}]`
+	if string(data) != want {
+		t.Errorf("messages = %s, want %s", data, want)
+	}
+}
+
+func TestNextProviderName(t *testing.T) {
+	if got := NextProviderName(ProviderOllama); got != ProviderOpenAI {
+		t.Errorf("NextProviderName(ollama) = %q", got)
+	}
+	if got := NextProviderName(ProviderOpenAI); got != ProviderOllama {
+		t.Errorf("NextProviderName(openai) = %q", got)
+	}
+	if _, err := NewProvider("bogus", "http://localhost", nil); err == nil {
+		t.Error("NewProvider(bogus) should fail")
+	}
+}
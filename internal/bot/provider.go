@@ -0,0 +1,80 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kevensen/gollama-bubbletea/internal/bot/messages"
+	"github.com/kevensen/gollama-bubbletea/internal/bot/models"
+	"github.com/kevensen/gollama-bubbletea/internal/httpclient"
+
+	"github.com/parakeet-nest/parakeet/llm"
+)
+
+// Provider names as stored in settings
+const (
+	ProviderOllama = "ollama"
+	ProviderOpenAI = "openai"
+)
+
+// ProviderNames lists the supported providers in the order they are cycled in the UI
+var ProviderNames = []string{ProviderOllama, ProviderOpenAI}
+
+// Provider is an LLM backend gollama can chat with
+type Provider interface {
+	models.Backend
+
+	// Name returns the provider name, one of ProviderNames
+	Name() string
+	// URL returns the server base URL
+	URL() string
+	// Ping checks that the server is reachable
+	Ping(ctx context.Context) error
+	// Chat sends a conversation and returns the complete answer
+	Chat(ctx context.Context, req ChatRequest) (llm.Answer, error)
+	// ChatStream sends a conversation, calling onChunk for each partial answer
+	ChatStream(ctx context.Context, req ChatRequest, onChunk func(llm.Answer) error) (llm.Answer, error)
+	// Embed returns the embedding of text
+	Embed(ctx context.Context, model, text string) ([]float64, error)
+}
+
+// ChatRequest is a provider-neutral chat completion request
+// Options use the parakeet option names, e.g. option.Temperature
+type ChatRequest struct {
+	Model    string
+	Messages []messages.Message // Images on a message are sent to models that accept them
+	Options  map[string]any
+}
+
+// NewProvider creates the named provider for a server URL, making requests with
+// clients from factory
+// An empty name selects Ollama
+func NewProvider(name, url string, factory *httpclient.Factory) (Provider, error) {
+	switch name {
+	case ProviderOllama, "":
+		return NewOllamaProvider(url, factory), nil
+	case ProviderOpenAI:
+		return NewOpenAIProvider(url, factory), nil
+	}
+	return nil, fmt.Errorf("unknown provider: %s", name)
+}
+
+// ProviderLabel returns a human readable name for a provider
+func ProviderLabel(name string) string {
+	switch name {
+	case ProviderOpenAI:
+		return "OpenAI-compatible"
+	default:
+		return "Ollama"
+	}
+}
+
+// NextProviderName returns the provider following name in ProviderNames
+func NextProviderName(name string) string {
+	for i, n := range ProviderNames {
+		if n == name {
+			return ProviderNames[(i+1)%len(ProviderNames)]
+		}
+	}
+	return ProviderOllama
+}
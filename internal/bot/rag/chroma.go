@@ -0,0 +1,206 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/kevensen/gollama-bubbletea/internal/httpclient"
+)
+
+// DefaultCollection is the ChromaDB collection queried for context
+const DefaultCollection = "documents"
+
+// ChromaDBQuery represents a query to ChromaDB
+type ChromaDBQuery struct {
+	QueryTexts []string       `json:"query_texts"`
+	NResults   int            `json:"n_results"`
+	Where      map[string]any `json:"where,omitempty"`
+}
+
+// ChromaDBResult represents search results from ChromaDB
+type ChromaDBResult struct {
+	IDs       [][]string                 `json:"ids"`
+	Documents [][]string                 `json:"documents"`
+	Metadatas [][]map[string]interface{} `json:"metadatas"`
+	Distances [][]float64                `json:"distances"`
+}
+
+// ChromaDBGet represents a request for stored documents from ChromaDB
+type ChromaDBGet struct {
+	Where   map[string]any `json:"where,omitempty"`
+	Include []string       `json:"include"`
+}
+
+// ChromaDBGetResult represents stored documents returned by ChromaDB
+type ChromaDBGetResult struct {
+	IDs       []string                 `json:"ids"`
+	Documents []string                 `json:"documents"`
+	Metadatas []map[string]interface{} `json:"metadatas"`
+}
+
+// ChromaDBUpsert represents documents to add or replace in ChromaDB
+type ChromaDBUpsert struct {
+	IDs       []string         `json:"ids"`
+	Documents []string         `json:"documents"`
+	Metadatas []map[string]any `json:"metadatas"`
+}
+
+// ChromaDBDelete represents documents to delete from ChromaDB
+type ChromaDBDelete struct {
+	IDs []string `json:"ids"`
+}
+
+// ChromaClient talks to a ChromaDB collection over its HTTP API
+type ChromaClient struct {
+	url        string
+	collection string
+	client     *http.Client
+}
+
+// NewChromaClient creates a client for the given ChromaDB URL and collection
+// A nil factory uses plain HTTP clients
+func NewChromaClient(url, collection string, factory *httpclient.Factory) *ChromaClient {
+	if collection == "" {
+		collection = DefaultCollection
+	}
+	return &ChromaClient{
+		url:        url,
+		collection: collection,
+		client:     factory.Client(10 * time.Second),
+	}
+}
+
+// URL returns the ChromaDB base URL
+func (c *ChromaClient) URL() string {
+	return c.url
+}
+
+// Collection returns the name of the collection
+func (c *ChromaClient) Collection() string {
+	return c.collection
+}
+
+// Query runs a similarity search and returns up to n results ordered by distance
+func (c *ChromaClient) Query(ctx context.Context, text string, n int, where map[string]any) ([]Result, error) {
+	chromaQuery := ChromaDBQuery{
+		QueryTexts: []string{text},
+		NResults:   n,
+		Where:      where,
+	}
+
+	var result ChromaDBResult
+	if err := c.post(ctx, "query", chromaQuery, &result); err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	if len(result.Documents) == 0 {
+		return results, nil
+	}
+	for i, doc := range result.Documents[0] {
+		chunk := Chunk{Text: doc}
+		if len(result.IDs) > 0 && i < len(result.IDs[0]) {
+			chunk.ID = result.IDs[0][i]
+		}
+		if len(result.Metadatas) > 0 && i < len(result.Metadatas[0]) {
+			chunk.Metadata = result.Metadatas[0][i]
+		}
+		r := Result{Chunk: chunk, Distance: -1, VectorRank: i + 1}
+		if len(result.Distances) > 0 && i < len(result.Distances[0]) {
+			r.Distance = result.Distances[0][i]
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// GetAll returns every document stored in the collection
+func (c *ChromaClient) GetAll(ctx context.Context) ([]Chunk, error) {
+	get := ChromaDBGet{Include: []string{"documents", "metadatas"}}
+
+	var result ChromaDBGetResult
+	if err := c.post(ctx, "get", get, &result); err != nil {
+		return nil, err
+	}
+
+	chunks := make([]Chunk, 0, len(result.Documents))
+	for i, doc := range result.Documents {
+		chunk := Chunk{Text: doc}
+		if i < len(result.IDs) {
+			chunk.ID = result.IDs[i]
+		}
+		if i < len(result.Metadatas) {
+			chunk.Metadata = result.Metadatas[i]
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
+
+// post sends a JSON request to a collection endpoint and decodes the response
+func (c *ChromaClient) post(ctx context.Context, endpoint string, body any, out any) error {
+	if c.url == "" {
+		return fmt.Errorf("ChromaDB URL not configured")
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal ChromaDB %s request: %v", endpoint, err)
+	}
+
+	// This is a basic implementation - you may need to adjust the endpoint based on your ChromaDB setup
+	url := fmt.Sprintf("%s/api/v1/collections/%s/%s", c.url, c.collection, endpoint)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(data))
+	if err != nil {
+		return fmt.Errorf("failed to create ChromaDB request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to query ChromaDB: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ChromaDB returned status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode ChromaDB response: %v", err)
+	}
+	return nil
+}
+
+// Upsert adds chunks to the collection, replacing existing chunks with the same ID
+// ChromaDB embeds the documents with the collection's embedding function
+func (c *ChromaClient) Upsert(ctx context.Context, chunks []Chunk) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	upsert := ChromaDBUpsert{}
+	for _, chunk := range chunks {
+		upsert.IDs = append(upsert.IDs, chunk.ID)
+		upsert.Documents = append(upsert.Documents, chunk.Text)
+		upsert.Metadatas = append(upsert.Metadatas, chunk.Metadata)
+	}
+
+	var result any
+	return c.post(ctx, "upsert", upsert, &result)
+}
+
+// Delete removes chunks from the collection by ID
+func (c *ChromaClient) Delete(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var result any
+	return c.post(ctx, "delete", ChromaDBDelete{IDs: ids}, &result)
+}
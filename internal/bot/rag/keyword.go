@@ -0,0 +1,250 @@
+package rag
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// BM25 tuning parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// KeywordIndex is an in-memory BM25 index over knowledge base chunks
+type KeywordIndex struct {
+	mu       sync.RWMutex
+	chunks   map[string]Chunk
+	termFreq map[string]map[string]int // chunk ID -> term -> count
+	docFreq  map[string]int            // term -> number of chunks containing it
+	lengths  map[string]int            // chunk ID -> number of terms
+	total    int                       // total number of terms across all chunks
+}
+
+// NewKeywordIndex creates an empty keyword index
+func NewKeywordIndex() *KeywordIndex {
+	return &KeywordIndex{
+		chunks:   make(map[string]Chunk),
+		termFreq: make(map[string]map[string]int),
+		docFreq:  make(map[string]int),
+		lengths:  make(map[string]int),
+	}
+}
+
+// Tokenize splits text into lowercase terms, keeping identifiers such as
+// ERR_CONN_42 or searchChromaDB intact
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+}
+
+// Add indexes chunks, replacing any existing chunk with the same ID
+func (idx *KeywordIndex) Add(chunks ...Chunk) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, chunk := range chunks {
+		idx.remove(chunk.ID)
+
+		terms := Tokenize(chunk.Text)
+		freq := make(map[string]int)
+		for _, term := range terms {
+			freq[term]++
+		}
+		for term := range freq {
+			idx.docFreq[term]++
+		}
+
+		idx.chunks[chunk.ID] = chunk
+		idx.termFreq[chunk.ID] = freq
+		idx.lengths[chunk.ID] = len(terms)
+		idx.total += len(terms)
+	}
+}
+
+// Remove deletes chunks from the index by ID
+func (idx *KeywordIndex) Remove(ids ...string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, id := range ids {
+		idx.remove(id)
+	}
+}
+
+// remove deletes a single chunk, the caller must hold the lock
+func (idx *KeywordIndex) remove(id string) {
+	freq, ok := idx.termFreq[id]
+	if !ok {
+		return
+	}
+	for term := range freq {
+		idx.docFreq[term]--
+		if idx.docFreq[term] <= 0 {
+			delete(idx.docFreq, term)
+		}
+	}
+	idx.total -= idx.lengths[id]
+	delete(idx.chunks, id)
+	delete(idx.termFreq, id)
+	delete(idx.lengths, id)
+}
+
+// Len returns the number of indexed chunks
+func (idx *KeywordIndex) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.chunks)
+}
+
+// Search returns up to n chunks matching the query ordered by BM25 score
+// Chunks that don't satisfy the metadata filter are skipped
+func (idx *KeywordIndex) Search(query string, n int, where map[string]any) []Result {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if len(idx.chunks) == 0 {
+		return nil
+	}
+
+	terms := Tokenize(query)
+	avgLength := float64(idx.total) / float64(len(idx.chunks))
+	docCount := float64(len(idx.chunks))
+
+	var results []Result
+	for id, freq := range idx.termFreq {
+		chunk := idx.chunks[id]
+		if !MatchesWhere(chunk.Metadata, where) {
+			continue
+		}
+
+		score := 0.0
+		length := float64(idx.lengths[id])
+		for _, term := range terms {
+			tf := float64(freq[term])
+			if tf == 0 {
+				continue
+			}
+			df := float64(idx.docFreq[term])
+			idf := math.Log(1 + (docCount-df+0.5)/(df+0.5))
+			score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*length/avgLength))
+		}
+		if score > 0 {
+			results = append(results, Result{Chunk: chunk, Distance: -1, KeywordScore: score})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].KeywordScore == results[j].KeywordScore {
+			return results[i].Chunk.ID < results[j].Chunk.ID
+		}
+		return results[i].KeywordScore > results[j].KeywordScore
+	})
+	if n > 0 && len(results) > n {
+		results = results[:n]
+	}
+	for i := range results {
+		results[i].KeywordRank = i + 1
+	}
+	return results
+}
+
+// MatchesWhere reports whether metadata satisfies a ChromaDB style filter.
+// Plain equality, $eq, $ne, $in, $nin, $and and $or are supported; other
+// operators are treated as matching so the vector store remains authoritative
+func MatchesWhere(metadata map[string]any, where map[string]any) bool {
+	for key, cond := range where {
+		switch key {
+		case "$and", "$or":
+			clauses, ok := cond.([]any)
+			if !ok {
+				continue
+			}
+			matched := key == "$and"
+			for _, clause := range clauses {
+				sub, ok := clause.(map[string]any)
+				if !ok {
+					continue
+				}
+				if key == "$and" && !MatchesWhere(metadata, sub) {
+					matched = false
+					break
+				}
+				if key == "$or" && MatchesWhere(metadata, sub) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return false
+			}
+		default:
+			if !matchesCondition(metadata[key], cond) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchesCondition checks a single metadata value against a filter condition
+func matchesCondition(value any, cond any) bool {
+	ops, ok := cond.(map[string]any)
+	if !ok {
+		return valuesEqual(value, cond)
+	}
+	for op, operand := range ops {
+		switch op {
+		case "$eq":
+			if !valuesEqual(value, operand) {
+				return false
+			}
+		case "$ne":
+			if valuesEqual(value, operand) {
+				return false
+			}
+		case "$in", "$nin":
+			list, _ := operand.([]any)
+			found := false
+			for _, item := range list {
+				if valuesEqual(value, item) {
+					found = true
+					break
+				}
+			}
+			if found != (op == "$in") {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// valuesEqual compares metadata values, treating all numbers as float64
+// Lists and objects from a JSON filter are compared by content, as == panics on them
+func valuesEqual(a, b any) bool {
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		return ok && fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
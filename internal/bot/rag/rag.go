@@ -1 +1,112 @@
 package rag
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Placeholders recognised in a prompt template
+const (
+	ContextPlaceholder  = "{{context}}"
+	QuestionPlaceholder = "{{question}}"
+)
+
+// DefaultTopK is the number of documents retrieved when no value is configured
+const DefaultTopK = 3
+
+// DefaultPromptTemplate wraps the retrieved context and the user question
+const DefaultPromptTemplate = "Context from knowledge base:\n" + ContextPlaceholder + "\n\nUser question: " + QuestionPlaceholder
+
+// NoContextText is substituted for the context when retrieval finds nothing
+const NoContextText = "(No relevant context found in knowledge base)"
+
+// Options controls how documents are retrieved and combined with the question
+type Options struct {
+	TopK           int            // Number of documents to retrieve
+	MaxDistance    float64        // Documents further than this are dropped (0 disables the threshold)
+	Where          map[string]any // ChromaDB metadata filter
+	PromptTemplate string         // Template with context and question placeholders
+	Mode           Mode           // Which retrievers to use
+	Rerank         bool           // Re-rank candidates by asking the model to score relevance
+}
+
+// EffectiveTopK returns the configured top-k or the default when unset
+func (o Options) EffectiveTopK() int {
+	if o.TopK <= 0 {
+		return DefaultTopK
+	}
+	return o.TopK
+}
+
+// EffectivePromptTemplate returns the configured template or the default when unset
+func (o Options) EffectivePromptTemplate() string {
+	if strings.TrimSpace(o.PromptTemplate) == "" {
+		return DefaultPromptTemplate
+	}
+	return o.PromptTemplate
+}
+
+// BuildPrompt substitutes the context and question into the template
+func BuildPrompt(template, context, question string) string {
+	if strings.TrimSpace(template) == "" {
+		template = DefaultPromptTemplate
+	}
+	if strings.TrimSpace(context) == "" {
+		context = NoContextText
+	}
+
+	replacer := strings.NewReplacer(
+		ContextPlaceholder, context,
+		QuestionPlaceholder, question,
+	)
+	return replacer.Replace(template)
+}
+
+// ValidatePromptTemplate checks that a template contains both placeholders
+func ValidatePromptTemplate(template string) error {
+	if !strings.Contains(template, ContextPlaceholder) {
+		return fmt.Errorf("prompt template must contain %s", ContextPlaceholder)
+	}
+	if !strings.Contains(template, QuestionPlaceholder) {
+		return fmt.Errorf("prompt template must contain %s", QuestionPlaceholder)
+	}
+	return nil
+}
+
+// ParseWhere parses a JSON metadata filter such as {"source": "docs"}
+// An empty string yields a nil filter
+func ParseWhere(text string) (map[string]any, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+
+	var where map[string]any
+	if err := json.Unmarshal([]byte(text), &where); err != nil {
+		return nil, fmt.Errorf("where filter must be a JSON object: %v", err)
+	}
+	return where, nil
+}
+
+// FormatWhere renders a metadata filter as compact JSON for display and editing
+func FormatWhere(where map[string]any) string {
+	if len(where) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(where)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// EscapeTemplate converts newlines to a literal \n for single-line editing
+func EscapeTemplate(template string) string {
+	return strings.ReplaceAll(template, "\n", `\n`)
+}
+
+// UnescapeTemplate reverses EscapeTemplate
+func UnescapeTemplate(template string) string {
+	return strings.ReplaceAll(template, `\n`, "\n")
+}
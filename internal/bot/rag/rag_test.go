@@ -0,0 +1,100 @@
+package rag
+
+import (
+	"testing"
+)
+
+func TestBuildPrompt(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		context  string
+		question string
+		want     string
+	}{
+		{
+			name:     "default template",
+			template: "",
+			context:  "Document 1: gophers\n",
+			question: "What is Go?",
+			want:     "Context from knowledge base:\nDocument 1: gophers\n\n\nUser question: What is Go?",
+		},
+		{
+			name:     "custom template",
+			template: "Q: {{question}}\nC: {{context}}",
+			context:  "doc",
+			question: "why?",
+			want:     "Q: why?\nC: doc",
+		},
+		{
+			name:     "no context",
+			template: "{{context}} | {{question}}",
+			context:  "",
+			question: "hello",
+			want:     NoContextText + " | hello",
+		},
+	}
+
+	for _, test := range tests {
+		got := BuildPrompt(test.template, test.context, test.question)
+		if got != test.want {
+			t.Errorf("%s: BuildPrompt() = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestValidatePromptTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		wantErr  bool
+	}{
+		{DefaultPromptTemplate, false},
+		{"{{context}} {{question}}", false},
+		{"only {{question}}", true},
+		{"only {{context}}", true},
+	}
+
+	for _, test := range tests {
+		err := ValidatePromptTemplate(test.template)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ValidatePromptTemplate(%q) error = %v, wantErr %t", test.template, err, test.wantErr)
+		}
+	}
+}
+
+func TestParseWhere(t *testing.T) {
+	where, err := ParseWhere(`{"source": "docs"}`)
+	if err != nil {
+		t.Fatalf("ParseWhere() error = %v", err)
+	}
+	if where["source"] != "docs" {
+		t.Errorf("ParseWhere() source = %v, want docs", where["source"])
+	}
+	if got := FormatWhere(where); got != `{"source":"docs"}` {
+		t.Errorf("FormatWhere() = %s", got)
+	}
+
+	where, err = ParseWhere("  ")
+	if err != nil || where != nil {
+		t.Errorf("ParseWhere(empty) = %v, %v, want nil, nil", where, err)
+	}
+
+	if _, err := ParseWhere("[1, 2]"); err == nil {
+		t.Errorf("ParseWhere(array) expected error")
+	}
+}
+
+func TestOptionsDefaults(t *testing.T) {
+	var opts Options
+	if opts.EffectiveTopK() != DefaultTopK {
+		t.Errorf("EffectiveTopK() = %d, want %d", opts.EffectiveTopK(), DefaultTopK)
+	}
+	if opts.EffectivePromptTemplate() != DefaultPromptTemplate {
+		t.Errorf("EffectivePromptTemplate() = %q", opts.EffectivePromptTemplate())
+	}
+
+	template := "a\nb"
+	if UnescapeTemplate(EscapeTemplate(template)) != template {
+		t.Errorf("EscapeTemplate round trip failed")
+	}
+}
@@ -0,0 +1,323 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// rrfK dampens the influence of top ranks in reciprocal rank fusion
+const rrfK = 60
+
+// RerankCandidates is the number of fused candidates scored when re-ranking
+const RerankCandidates = 10
+
+// rerankConcurrency is the number of candidates scored at once when re-ranking
+const rerankConcurrency = 4
+
+// Mode selects which retrievers are used for a query
+type Mode string
+
+const (
+	ModeVector  Mode = "vector"  // ChromaDB similarity search only
+	ModeKeyword Mode = "keyword" // Local BM25 keyword index only
+	ModeHybrid  Mode = "hybrid"  // Both, fused by reciprocal rank
+)
+
+// Modes lists the retrieval modes in the order they are cycled in the UI
+var Modes = []Mode{ModeVector, ModeKeyword, ModeHybrid}
+
+// ParseMode converts a stored mode name, defaulting to vector search
+func ParseMode(name string) Mode {
+	for _, mode := range Modes {
+		if string(mode) == name {
+			return mode
+		}
+	}
+	return ModeVector
+}
+
+// NextMode returns the mode following the given one
+func NextMode(mode Mode) Mode {
+	for i, m := range Modes {
+		if m == mode {
+			return Modes[(i+1)%len(Modes)]
+		}
+	}
+	return ModeVector
+}
+
+// Chunk is a piece of a document stored in the knowledge base
+type Chunk struct {
+	ID       string
+	Text     string
+	Metadata map[string]any
+}
+
+// Result is a retrieved chunk with the ranking information that produced it
+type Result struct {
+	Chunk        Chunk
+	Score        float64 // Fused reciprocal rank score
+	Distance     float64 // Vector distance, -1 when not retrieved by vector search
+	VectorRank   int     // 1-based rank from vector search, 0 when absent
+	KeywordRank  int     // 1-based rank from keyword search, 0 when absent
+	KeywordScore float64 // BM25 score
+	Reranked     bool    // Whether RerankScore was assigned by the model
+	RerankScore  float64 // Relevance score from the model
+}
+
+// ScoreFunc asks a model how relevant a text is to a query
+type ScoreFunc func(ctx context.Context, query, text string) (float64, error)
+
+// Retriever combines vector search in ChromaDB with a local keyword index
+type Retriever struct {
+	chroma   *ChromaClient
+	keywords *KeywordIndex
+
+	mu             sync.Mutex
+	keywordsLoaded bool
+}
+
+// NewRetriever creates a retriever backed by the given ChromaDB client
+func NewRetriever(chroma *ChromaClient) *Retriever {
+	return &Retriever{
+		chroma:   chroma,
+		keywords: NewKeywordIndex(),
+	}
+}
+
+// Chroma returns the ChromaDB client used for vector search
+func (r *Retriever) Chroma() *ChromaClient {
+	return r.chroma
+}
+
+// Keywords returns the local keyword index
+func (r *Retriever) Keywords() *KeywordIndex {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.keywords
+}
+
+// RefreshKeywordIndex rebuilds the keyword index from the documents stored in ChromaDB
+func (r *Retriever) RefreshKeywordIndex(ctx context.Context) error {
+	chunks, err := r.chroma.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	index := NewKeywordIndex()
+	index.Add(chunks...)
+
+	r.mu.Lock()
+	r.keywords = index
+	r.keywordsLoaded = true
+	r.mu.Unlock()
+	return nil
+}
+
+// InvalidateKeywordIndex makes the next search rebuild the keyword index from ChromaDB,
+// such as after a sync that another instance may also have written to
+func (r *Retriever) InvalidateKeywordIndex() {
+	r.mu.Lock()
+	r.keywordsLoaded = false
+	r.mu.Unlock()
+}
+
+// keywordIndex returns the keyword index, loading it from ChromaDB on first use
+// or after it was invalidated
+func (r *Retriever) keywordIndex(ctx context.Context) (*KeywordIndex, error) {
+	r.mu.Lock()
+	loaded := r.keywordsLoaded
+	r.mu.Unlock()
+
+	if !loaded {
+		if err := r.RefreshKeywordIndex(ctx); err != nil {
+			return nil, fmt.Errorf("failed to build keyword index: %v", err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.keywords, nil
+}
+
+// Retrieve returns the top chunks for a query using the mode in opts,
+// re-ranking the fused candidates with score when opts.Rerank is set
+func (r *Retriever) Retrieve(ctx context.Context, query string, opts Options, score ScoreFunc) ([]Result, error) {
+	topK := opts.EffectiveTopK()
+	pool := topK
+	if opts.Rerank && pool < RerankCandidates {
+		pool = RerankCandidates
+	}
+
+	var vectorResults, keywordResults []Result
+	mode := ParseMode(string(opts.Mode))
+
+	if mode == ModeVector || mode == ModeHybrid {
+		results, err := r.chroma.Query(ctx, query, pool, opts.Where)
+		if err != nil {
+			return nil, err
+		}
+		vectorResults = FilterByDistance(results, opts.MaxDistance)
+	}
+
+	if mode == ModeKeyword || mode == ModeHybrid {
+		index, err := r.keywordIndex(ctx)
+		if err != nil {
+			return nil, err
+		}
+		keywordResults = index.Search(query, pool, opts.Where)
+	}
+
+	results := Fuse(vectorResults, keywordResults)
+	if len(results) > pool {
+		results = results[:pool]
+	}
+
+	if opts.Rerank && score != nil {
+		// A failing model only loses the re-ranking, the fused order still answers the query
+		reranked, err := Rerank(ctx, query, results, score)
+		if err != nil {
+			log.Printf("Warning: %v, keeping the fused order", err)
+		} else {
+			results = reranked
+		}
+	}
+
+	if len(results) > topK {
+		results = results[:topK]
+	}
+	return results, nil
+}
+
+// FilterByDistance drops vector results further than maxDistance and renumbers
+// the remaining ranks; a maxDistance of 0 disables the threshold
+func FilterByDistance(results []Result, maxDistance float64) []Result {
+	if maxDistance <= 0 {
+		return results
+	}
+
+	var kept []Result
+	for _, result := range results {
+		if result.Distance >= 0 && result.Distance > maxDistance {
+			continue
+		}
+		result.VectorRank = len(kept) + 1
+		kept = append(kept, result)
+	}
+	return kept
+}
+
+// Fuse merges ranked lists with reciprocal rank fusion, identifying chunks by
+// ID or, when no ID is available, by their text
+func Fuse(vectorResults, keywordResults []Result) []Result {
+	var fused []Result
+	positions := make(map[string]int)
+
+	key := func(chunk Chunk) string {
+		if chunk.ID != "" {
+			return "id:" + chunk.ID
+		}
+		return "text:" + chunk.Text
+	}
+
+	for i, result := range vectorResults {
+		result.VectorRank = i + 1
+		result.Score = 1.0 / float64(rrfK+i+1)
+		positions[key(result.Chunk)] = len(fused)
+		fused = append(fused, result)
+	}
+
+	for i, result := range keywordResults {
+		contribution := 1.0 / float64(rrfK+i+1)
+		if pos, ok := positions[key(result.Chunk)]; ok {
+			fused[pos].KeywordRank = i + 1
+			fused[pos].KeywordScore = result.KeywordScore
+			fused[pos].Score += contribution
+			continue
+		}
+		result.KeywordRank = i + 1
+		result.Score = contribution
+		positions[key(result.Chunk)] = len(fused)
+		fused = append(fused, result)
+	}
+
+	sort.SliceStable(fused, func(i, j int) bool {
+		return fused[i].Score > fused[j].Score
+	})
+	return fused
+}
+
+// Rerank scores every result with the model and orders them by that score,
+// keeping the fused order for ties
+func Rerank(ctx context.Context, query string, results []Result, score ScoreFunc) ([]Result, error) {
+	reranked := make([]Result, len(results))
+	copy(reranked, results)
+
+	// Score a few candidates at a time, so a query doesn't wait for each model call in turn,
+	// and stop scoring once one fails
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	slots := make(chan struct{}, rerankConcurrency)
+	var wg sync.WaitGroup
+	var failed sync.Once
+	var firstErr error // The failure that stopped scoring, rather than the calls it cancelled
+	for i := range reranked {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			s, err := score(ctx, query, reranked[i].Chunk.Text)
+			if err != nil {
+				failed.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			reranked[i].Reranked = true
+			reranked[i].RerankScore = s
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, fmt.Errorf("re-ranking failed: %v", firstErr)
+	}
+
+	sort.SliceStable(reranked, func(i, j int) bool {
+		return reranked[i].RerankScore > reranked[j].RerankScore
+	})
+	return reranked, nil
+}
+
+// FormatContext renders results as numbered documents for the prompt
+func FormatContext(results []Result) string {
+	var contextBuilder strings.Builder
+	for i, result := range results {
+		contextBuilder.WriteString(fmt.Sprintf("Document %d: %s\n", i+1, result.Chunk.Text))
+	}
+	return contextBuilder.String()
+}
+
+// RelevancePrompt asks a model to rate how relevant a passage is to a question
+func RelevancePrompt(query, text string) string {
+	return fmt.Sprintf("Rate how relevant the passage is to the question on a scale from 0 (irrelevant) to 10 (directly answers it). "+
+		"Reply with the number only.\n\nQuestion: %s\n\nPassage: %s", query, text)
+}
+
+var scorePattern = regexp.MustCompile(`\d+(\.\d+)?`)
+
+// ParseRelevanceScore extracts the first number from a model's relevance reply
+func ParseRelevanceScore(reply string) (float64, error) {
+	match := scorePattern.FindString(reply)
+	if match == "" {
+		return 0, fmt.Errorf("no relevance score in reply: %q", reply)
+	}
+	return strconv.ParseFloat(match, 64)
+}
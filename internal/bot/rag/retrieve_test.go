@@ -0,0 +1,263 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestKeywordIndexSearch(t *testing.T) {
+	index := NewKeywordIndex()
+	index.Add(
+		Chunk{ID: "a", Text: "The consumer failed with ERR_CONN_42 after a rebalance"},
+		Chunk{ID: "b", Text: "Call searchChromaDB to query the vector store"},
+		Chunk{ID: "c", Text: "General notes about the consumer group and rebalance", Metadata: map[string]any{"source": "notes"}},
+	)
+
+	results := index.Search("what does err_conn_42 mean", 3, nil)
+	if len(results) != 1 || results[0].Chunk.ID != "a" {
+		t.Fatalf("Search(ERR_CONN_42) = %v, want chunk a", results)
+	}
+	if results[0].KeywordRank != 1 {
+		t.Errorf("KeywordRank = %d, want 1", results[0].KeywordRank)
+	}
+
+	results = index.Search("searchChromaDB", 3, nil)
+	if len(results) != 1 || results[0].Chunk.ID != "b" {
+		t.Errorf("Search(searchChromaDB) = %v, want chunk b", results)
+	}
+
+	results = index.Search("rebalance", 3, map[string]any{"source": "notes"})
+	if len(results) != 1 || results[0].Chunk.ID != "c" {
+		t.Errorf("Search(rebalance, where) = %v, want chunk c", results)
+	}
+
+	index.Remove("a")
+	if index.Len() != 2 {
+		t.Errorf("Len() after Remove = %d, want 2", index.Len())
+	}
+	if results := index.Search("ERR_CONN_42", 3, nil); len(results) != 0 {
+		t.Errorf("Search after Remove = %v, want none", results)
+	}
+}
+
+func TestMatchesWhere(t *testing.T) {
+	metadata := map[string]any{"source": "docs", "page": 3.0, "tags": []any{"kafka", "ops"}, "owner": map[string]any{"team": "ml"}}
+
+	tests := []struct {
+		where map[string]any
+		want  bool
+	}{
+		{nil, true},
+		{map[string]any{"source": "docs"}, true},
+		{map[string]any{"source": "notes"}, false},
+		{map[string]any{"page": map[string]any{"$ne": 3.0}}, false},
+		{map[string]any{"source": map[string]any{"$in": []any{"notes", "docs"}}}, true},
+		{map[string]any{"$or": []any{map[string]any{"source": "notes"}, map[string]any{"page": 3.0}}}, true},
+		{map[string]any{"$and": []any{map[string]any{"source": "docs"}, map[string]any{"page": 4.0}}}, false},
+		// Lists and objects compare by content instead of panicking
+		{map[string]any{"tags": []any{"kafka", "ops"}}, true},
+		{map[string]any{"tags": []any{"kafka"}}, false},
+		{map[string]any{"owner": map[string]any{"$eq": map[string]any{"team": "ml"}}}, true},
+		{map[string]any{"source": []any{"docs"}}, false},
+	}
+
+	for _, test := range tests {
+		if got := MatchesWhere(metadata, test.where); got != test.want {
+			t.Errorf("MatchesWhere(%v) = %t, want %t", test.where, got, test.want)
+		}
+	}
+}
+
+func TestFuse(t *testing.T) {
+	vector := []Result{
+		{Chunk: Chunk{ID: "a"}, Distance: 0.1},
+		{Chunk: Chunk{ID: "b"}, Distance: 0.2},
+	}
+	keyword := []Result{
+		{Chunk: Chunk{ID: "b"}, KeywordScore: 4},
+		{Chunk: Chunk{ID: "c"}, KeywordScore: 2},
+	}
+
+	fused := Fuse(vector, keyword)
+	if len(fused) != 3 {
+		t.Fatalf("Fuse() returned %d results, want 3", len(fused))
+	}
+	// b appears in both lists so it should win
+	if fused[0].Chunk.ID != "b" || fused[0].VectorRank != 2 || fused[0].KeywordRank != 1 {
+		t.Errorf("Fuse()[0] = %+v, want chunk b ranked by both", fused[0])
+	}
+	if fused[1].Chunk.ID != "a" || fused[2].Chunk.ID != "c" {
+		t.Errorf("Fuse() order = %s, %s, want a, c", fused[1].Chunk.ID, fused[2].Chunk.ID)
+	}
+}
+
+func TestFilterByDistance(t *testing.T) {
+	results := []Result{
+		{Chunk: Chunk{ID: "a"}, Distance: 0.2},
+		{Chunk: Chunk{ID: "b"}, Distance: 0.9},
+		{Chunk: Chunk{ID: "c"}, Distance: 0.4},
+	}
+
+	kept := FilterByDistance(results, 0.5)
+	if len(kept) != 2 || kept[1].Chunk.ID != "c" || kept[1].VectorRank != 2 {
+		t.Errorf("FilterByDistance() = %+v", kept)
+	}
+	if len(FilterByDistance(results, 0)) != 3 {
+		t.Errorf("FilterByDistance(0) should keep all results")
+	}
+}
+
+func TestRerank(t *testing.T) {
+	results := []Result{
+		{Chunk: Chunk{ID: "a", Text: "unrelated"}},
+		{Chunk: Chunk{ID: "b", Text: "very relevant"}},
+	}
+	score := func(ctx context.Context, query, text string) (float64, error) {
+		if strings.Contains(text, "relevant") {
+			return 9, nil
+		}
+		return 1, nil
+	}
+
+	reranked, err := Rerank(context.Background(), "q", results, score)
+	if err != nil {
+		t.Fatalf("Rerank() error = %v", err)
+	}
+	if reranked[0].Chunk.ID != "b" || !reranked[0].Reranked || reranked[0].RerankScore != 9 {
+		t.Errorf("Rerank()[0] = %+v, want chunk b scored 9", reranked[0])
+	}
+	if results[0].Chunk.ID != "a" {
+		t.Errorf("Rerank() modified its input")
+	}
+
+	// Candidates are scored concurrently, a few at a time
+	var running, most atomic.Int32
+	slow := func(ctx context.Context, query, text string) (float64, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := most.Load()
+			if n <= m || most.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return float64(len(text)), nil
+	}
+	many := make([]Result, RerankCandidates)
+	for i := range many {
+		many[i] = Result{Chunk: Chunk{ID: strconv.Itoa(i), Text: strings.Repeat("x", i)}}
+	}
+	reranked, err = Rerank(context.Background(), "q", many, slow)
+	if err != nil || reranked[0].Chunk.ID != strconv.Itoa(RerankCandidates-1) {
+		t.Errorf("Rerank() = %+v, %v, want the longest text first", reranked, err)
+	}
+	if got := most.Load(); got < 2 || got > rerankConcurrency {
+		t.Errorf("scored %d candidates at once, want 2 to %d", got, rerankConcurrency)
+	}
+
+	// A failing call fails the re-ranking with its own error
+	failing := func(ctx context.Context, query, text string) (float64, error) {
+		if text == "" {
+			return 0, fmt.Errorf("model unavailable")
+		}
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}
+	if _, err := Rerank(context.Background(), "q", many, failing); err == nil || !strings.Contains(err.Error(), "model unavailable") {
+		t.Errorf("Rerank() with a failing call = %v, want the model's error", err)
+	}
+}
+
+func TestParseRelevanceScore(t *testing.T) {
+	tests := []struct {
+		reply   string
+		want    float64
+		wantErr bool
+	}{
+		{"7", 7, false},
+		{"Score: 8.5/10", 8.5, false},
+		{"not relevant", 0, true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseRelevanceScore(test.reply)
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("ParseRelevanceScore(%q) = %v, %v, want %v", test.reply, got, err, test.want)
+		}
+	}
+}
+
+func TestRetrieveHybrid(t *testing.T) {
+	loads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/documents/get") {
+			loads++
+		}
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/documents/query"):
+			json.NewEncoder(w).Encode(ChromaDBResult{
+				IDs:       [][]string{{"1", "2"}},
+				Documents: [][]string{{"kafka consumer lag explained", "rebalance protocol overview"}},
+				Distances: [][]float64{{0.3, 0.8}},
+			})
+		case strings.HasSuffix(r.URL.Path, "/documents/get"):
+			json.NewEncoder(w).Encode(ChromaDBGetResult{
+				IDs:       []string{"1", "2", "3"},
+				Documents: []string{"kafka consumer lag explained", "rebalance protocol overview", "error KAFKA_E123 means offset out of range"},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	retriever := NewRetriever(NewChromaClient(server.URL, "", nil))
+	opts := Options{TopK: 2, Mode: ModeHybrid, MaxDistance: 0.5}
+
+	results, err := retriever.Retrieve(context.Background(), "what is KAFKA_E123", opts, nil)
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Retrieve() returned %d results, want 2", len(results))
+	}
+
+	ids := map[string]bool{}
+	for _, result := range results {
+		ids[result.Chunk.ID] = true
+	}
+	if !ids["1"] || !ids["3"] {
+		t.Errorf("Retrieve() = %+v, want chunks 1 (vector) and 3 (keyword)", results)
+	}
+	if retriever.Keywords().Len() != 3 {
+		t.Errorf("keyword index has %d chunks, want 3", retriever.Keywords().Len())
+	}
+
+	// A failing model keeps the fused order rather than failing the search
+	opts.Rerank = true
+	fail := func(ctx context.Context, query, text string) (float64, error) {
+		return 0, fmt.Errorf("model unavailable")
+	}
+	reranked, err := retriever.Retrieve(context.Background(), "what is KAFKA_E123", opts, fail)
+	if err != nil || len(reranked) != len(results) || reranked[0].Chunk.ID != results[0].Chunk.ID || reranked[0].Reranked {
+		t.Errorf("Retrieve() with failing re-ranking = %+v, %v, want %+v", reranked, err, results)
+	}
+
+	// The keyword index is loaded once, until it is invalidated
+	retriever.InvalidateKeywordIndex()
+	if _, err := retriever.Retrieve(context.Background(), "what is KAFKA_E123", opts, nil); err != nil {
+		t.Fatal(err)
+	}
+	if loads != 2 {
+		t.Errorf("keyword index loaded %d times, want 2", loads)
+	}
+}
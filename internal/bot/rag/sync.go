@@ -0,0 +1,416 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kevensen/gollama-bubbletea/internal/atomicfile"
+)
+
+// Ingestion limits
+const (
+	MaxFileSize  = 1 << 20 // Files larger than 1 MiB are skipped
+	ChunkSize    = 1000    // Target chunk size in characters
+	ChunkOverlap = 200     // Characters shared between consecutive chunks
+)
+
+// FileRecord tracks an ingested file so unchanged files aren't re-embedded
+type FileRecord struct {
+	Hash      string    `json:"hash"`
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"modTime"`
+	ChunkIDs  []string  `json:"chunkIds"`
+	IndexedAt time.Time `json:"indexedAt"`
+}
+
+// Manifest records every file ingested into a knowledge base
+type Manifest struct {
+	Files       map[string]FileRecord `json:"files"`
+	LastIndexed time.Time             `json:"lastIndexed"`
+}
+
+// SyncStats summarises a single sync run
+type SyncStats struct {
+	Added     int
+	Updated   int
+	Removed   int
+	Unchanged int
+	Skipped   int // Binary or oversized files
+}
+
+// String renders the stats for the status line
+func (s SyncStats) String() string {
+	return fmt.Sprintf("%d added, %d updated, %d removed, %d unchanged, %d skipped",
+		s.Added, s.Updated, s.Removed, s.Unchanged, s.Skipped)
+}
+
+// SyncStatus describes the state of the knowledge base sync
+type SyncStatus struct {
+	Syncing     bool
+	LastIndexed time.Time
+	LastStats   SyncStats
+	LastError   error
+	Files       int
+	Chunks      int
+}
+
+// Syncer keeps a ChromaDB collection and the keyword index in sync with local folders
+type Syncer struct {
+	retriever    *Retriever
+	manifestPath string
+
+	mu       sync.Mutex // Held for the duration of a sync
+	manifest Manifest
+
+	statusMu sync.Mutex
+	status   SyncStatus
+}
+
+// NewSyncer creates a syncer, loading the manifest from manifestPath if it exists
+func NewSyncer(retriever *Retriever, manifestPath string) (*Syncer, error) {
+	s := &Syncer{
+		retriever:    retriever,
+		manifestPath: manifestPath,
+		manifest:     Manifest{Files: make(map[string]FileRecord)},
+	}
+
+	data, err := os.ReadFile(manifestPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &s.manifest); err != nil {
+			return nil, fmt.Errorf("failed to parse sync manifest: %v", err)
+		}
+		if s.manifest.Files == nil {
+			s.manifest.Files = make(map[string]FileRecord)
+		}
+	}
+
+	s.updateStatus(func(status *SyncStatus) {
+		status.LastIndexed = s.manifest.LastIndexed
+		status.Files, status.Chunks = s.manifest.counts()
+	})
+	return s, nil
+}
+
+// ManifestPath returns the manifest file for a ChromaDB collection inside dir
+func ManifestPath(dir, chromaDBURL, collection string) string {
+	// The default collection keeps the name manifests had before collections could be chosen
+	key := chromaDBURL
+	if collection != "" && collection != DefaultCollection {
+		key += "#" + collection
+	}
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(dir, "rag-sync-"+hex.EncodeToString(sum[:4])+".json")
+}
+
+// Retriever returns the retriever whose collection and keyword index are synced
+func (s *Syncer) Retriever() *Retriever {
+	return s.retriever
+}
+
+// ManifestPath returns the path of the manifest file
+func (s *Syncer) ManifestPath() string {
+	return s.manifestPath
+}
+
+// Status returns a snapshot of the sync state
+func (s *Syncer) Status() SyncStatus {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	return s.status
+}
+
+func (s *Syncer) updateStatus(update func(*SyncStatus)) {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	update(&s.status)
+}
+
+// Sync ingests new and changed files from folders, re-embedding only files whose
+// content hash changed, and deletes chunks of files that no longer exist
+func (s *Syncer) Sync(ctx context.Context, folders []string) (SyncStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.updateStatus(func(status *SyncStatus) { status.Syncing = true })
+
+	stats, err := s.sync(ctx, folders)
+	// A failed sync, or another instance syncing the same collection, can leave the
+	// keyword index out of step with ChromaDB, so the next search rebuilds it
+	s.retriever.InvalidateKeywordIndex()
+	if saveErr := s.saveManifest(); err == nil {
+		err = saveErr
+	}
+
+	s.updateStatus(func(status *SyncStatus) {
+		status.Syncing = false
+		status.LastStats = stats
+		status.LastError = err
+		status.LastIndexed = s.manifest.LastIndexed
+		status.Files, status.Chunks = s.manifest.counts()
+	})
+	return stats, err
+}
+
+func (s *Syncer) sync(ctx context.Context, folders []string) (SyncStats, error) {
+	var stats SyncStats
+	seen := make(map[string]bool)
+	chroma := s.retriever.Chroma()
+
+	for _, folder := range folders {
+		root, err := ExpandPath(folder)
+		if err != nil {
+			return stats, err
+		}
+
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			// Skip hidden directories such as .git
+			if d.IsDir() {
+				if path != root && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if info.Size() > MaxFileSize {
+				stats.Skipped++
+				return nil
+			}
+
+			record, known := s.manifest.Files[path]
+			if known && record.Size == info.Size() && record.ModTime.Equal(info.ModTime()) {
+				seen[path] = true
+				stats.Unchanged++
+				return nil
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if !IsText(data) {
+				stats.Skipped++
+				return nil
+			}
+			seen[path] = true
+
+			hash := hashContent(data)
+			if known && record.Hash == hash {
+				// Only the timestamp changed, nothing to re-embed
+				record.ModTime = info.ModTime()
+				record.Size = info.Size()
+				s.manifest.Files[path] = record
+				stats.Unchanged++
+				return nil
+			}
+
+			chunks := fileChunks(path, hash, string(data))
+			if err := chroma.Upsert(ctx, chunks); err != nil {
+				return err
+			}
+
+			// Delete chunks left over from a longer previous version
+			var ids []string
+			for _, chunk := range chunks {
+				ids = append(ids, chunk.ID)
+			}
+			stale := staleIDs(record.ChunkIDs, ids)
+			if err := chroma.Delete(ctx, stale); err != nil {
+				return err
+			}
+
+			keywords := s.retriever.Keywords()
+			keywords.Remove(stale...)
+			keywords.Add(chunks...)
+
+			s.manifest.Files[path] = FileRecord{
+				Hash:      hash,
+				Size:      info.Size(),
+				ModTime:   info.ModTime(),
+				ChunkIDs:  ids,
+				IndexedAt: time.Now(),
+			}
+			if known {
+				stats.Updated++
+			} else {
+				stats.Added++
+			}
+			return nil
+		})
+		if err != nil {
+			return stats, fmt.Errorf("failed to sync %s: %v", folder, err)
+		}
+	}
+
+	// Remove files that were deleted or whose folder is no longer configured
+	var removed []string
+	for path := range s.manifest.Files {
+		if !seen[path] {
+			removed = append(removed, path)
+		}
+	}
+	sort.Strings(removed)
+	for _, path := range removed {
+		ids := s.manifest.Files[path].ChunkIDs
+		if err := chroma.Delete(ctx, ids); err != nil {
+			return stats, err
+		}
+		s.retriever.Keywords().Remove(ids...)
+		delete(s.manifest.Files, path)
+		stats.Removed++
+	}
+
+	s.manifest.LastIndexed = time.Now()
+	return stats, nil
+}
+
+// saveManifest writes the manifest to disk, readable only by the owner as it lists
+// every file indexed, and atomically so an interrupted sync can't leave it truncated
+func (s *Syncer) saveManifest() error {
+	if err := os.MkdirAll(filepath.Dir(s.manifestPath), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(s.manifestPath, data)
+}
+
+// counts returns the number of files and chunks in the manifest
+func (m Manifest) counts() (files, chunks int) {
+	for _, record := range m.Files {
+		chunks += len(record.ChunkIDs)
+	}
+	return len(m.Files), chunks
+}
+
+// ExpandPath resolves a leading ~ and returns an absolute path
+func ExpandPath(path string) (string, error) {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(homeDir, strings.TrimPrefix(path, "~"))
+	}
+	return filepath.Abs(path)
+}
+
+// IsText reports whether data looks like UTF-8 text rather than a binary file
+func IsText(data []byte) bool {
+	sample := data
+	if len(sample) > 8000 {
+		sample = sample[:8000]
+	}
+	return bytes.IndexByte(sample, 0) < 0 && utf8.Valid(data)
+}
+
+// ChunkText splits text into overlapping chunks, preferring to break at
+// paragraph, line or word boundaries
+func ChunkText(text string, size, overlap int) []string {
+	runes := []rune(text)
+	var chunks []string
+
+	for start := 0; start < len(runes); {
+		end := start + size
+		if end >= len(runes) {
+			end = len(runes)
+		} else {
+			end = breakPoint(runes, start, end)
+		}
+
+		if chunk := strings.TrimSpace(string(runes[start:end])); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		if end == len(runes) {
+			break
+		}
+
+		next := end - overlap
+		if next <= start {
+			next = end
+		}
+		start = next
+	}
+	return chunks
+}
+
+// breakPoint finds a natural boundary in the second half of runes[start:end]
+func breakPoint(runes []rune, start, end int) int {
+	window := string(runes[start:end])
+	half := len(window) / 2
+	for _, sep := range []string{"\n\n", "\n", " "} {
+		if i := strings.LastIndex(window, sep); i > half {
+			return start + utf8.RuneCountInString(window[:i+len(sep)])
+		}
+	}
+	return end
+}
+
+// fileChunks splits a file into chunks with IDs that are stable per path
+func fileChunks(path, hash, text string) []Chunk {
+	sum := sha256.Sum256([]byte(path))
+	prefix := hex.EncodeToString(sum[:6])
+
+	var chunks []Chunk
+	for i, part := range ChunkText(text, ChunkSize, ChunkOverlap) {
+		chunks = append(chunks, Chunk{
+			ID:   fmt.Sprintf("%s-%d", prefix, i),
+			Text: part,
+			Metadata: map[string]any{
+				"source": path,
+				"chunk":  i,
+				"hash":   hash,
+			},
+		})
+	}
+	return chunks
+}
+
+// staleIDs returns the IDs in previous that are not in current
+func staleIDs(previous, current []string) []string {
+	keep := make(map[string]bool, len(current))
+	for _, id := range current {
+		keep[id] = true
+	}
+	var stale []string
+	for _, id := range previous {
+		if !keep[id] {
+			stale = append(stale, id)
+		}
+	}
+	return stale
+}
+
+func hashContent(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
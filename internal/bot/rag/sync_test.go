@@ -0,0 +1,144 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeChroma stores upserted documents in memory
+type fakeChroma struct {
+	mu   sync.Mutex
+	docs map[string]string
+}
+
+func (f *fakeChroma) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/upsert"):
+		var upsert ChromaDBUpsert
+		json.NewDecoder(r.Body).Decode(&upsert)
+		for i, id := range upsert.IDs {
+			f.docs[id] = upsert.Documents[i]
+		}
+	case strings.HasSuffix(r.URL.Path, "/delete"):
+		var del ChromaDBDelete
+		json.NewDecoder(r.Body).Decode(&del)
+		for _, id := range del.IDs {
+			delete(f.docs, id)
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	w.Write([]byte("true"))
+}
+
+func TestSyncerSync(t *testing.T) {
+	chroma := &fakeChroma{docs: make(map[string]string)}
+	server := httptest.NewServer(chroma)
+	defer server.Close()
+
+	folder := t.TempDir()
+	manifestPath := filepath.Join(t.TempDir(), "manifest.json")
+
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(folder, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.md", "Alpha document about ERR_CONN_42")
+	write("b.txt", "Bravo document")
+	write("image.bin", "\x00\x01\x02")
+	os.MkdirAll(filepath.Join(folder, ".git"), 0755)
+	os.WriteFile(filepath.Join(folder, ".git", "HEAD"), []byte("ref"), 0644)
+
+	retriever := NewRetriever(NewChromaClient(server.URL, "", nil))
+	syncer, err := NewSyncer(retriever, manifestPath)
+	if err != nil {
+		t.Fatalf("NewSyncer() error = %v", err)
+	}
+
+	stats, err := syncer.Sync(context.Background(), []string{folder})
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if stats.Added != 2 || stats.Skipped != 1 {
+		t.Errorf("first Sync() = %+v, want 2 added and 1 skipped", stats)
+	}
+	if len(chroma.docs) != 2 || retriever.Keywords().Len() != 2 {
+		t.Errorf("expected 2 chunks in ChromaDB and keyword index, got %d and %d", len(chroma.docs), retriever.Keywords().Len())
+	}
+
+	// Nothing changed, nothing is re-embedded
+	stats, err = syncer.Sync(context.Background(), []string{folder})
+	if err != nil || stats.Unchanged != 2 || stats.Added+stats.Updated+stats.Removed != 0 {
+		t.Errorf("second Sync() = %+v, %v, want 2 unchanged", stats, err)
+	}
+
+	// Change one file and delete the other
+	write("a.md", "Alpha document rewritten")
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(folder, "a.md"), later, later)
+	os.Remove(filepath.Join(folder, "b.txt"))
+
+	stats, err = syncer.Sync(context.Background(), []string{folder})
+	if err != nil {
+		t.Fatalf("third Sync() error = %v", err)
+	}
+	if stats.Updated != 1 || stats.Removed != 1 {
+		t.Errorf("third Sync() = %+v, want 1 updated and 1 removed", stats)
+	}
+	if len(chroma.docs) != 1 {
+		t.Errorf("expected 1 chunk in ChromaDB, got %d", len(chroma.docs))
+	}
+	for _, doc := range chroma.docs {
+		if doc != "Alpha document rewritten" {
+			t.Errorf("unexpected chunk %q", doc)
+		}
+	}
+
+	// The manifest lists private file paths, so only the owner can read it
+	if info, err := os.Stat(manifestPath); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("manifest mode = %v, %v, want 0600", info, err)
+	}
+
+	// The manifest survives a restart
+	reloaded, err := NewSyncer(retriever, manifestPath)
+	if err != nil {
+		t.Fatalf("NewSyncer() reload error = %v", err)
+	}
+	status := reloaded.Status()
+	if status.Files != 1 || status.LastIndexed.IsZero() {
+		t.Errorf("reloaded Status() = %+v, want 1 file with a last indexed time", status)
+	}
+}
+
+func TestChunkText(t *testing.T) {
+	text := strings.Repeat("word ", 500)
+	chunks := ChunkText(text, 1000, 200)
+	if len(chunks) < 3 {
+		t.Fatalf("ChunkText() returned %d chunks, want at least 3", len(chunks))
+	}
+	for _, chunk := range chunks {
+		if len([]rune(chunk)) > 1000 {
+			t.Errorf("chunk longer than size: %d", len([]rune(chunk)))
+		}
+		if strings.HasPrefix(chunk, "ord") {
+			t.Errorf("chunk should break on word boundaries: %q", chunk[:10])
+		}
+	}
+
+	if got := ChunkText("short", 1000, 200); len(got) != 1 || got[0] != "short" {
+		t.Errorf("ChunkText(short) = %v", got)
+	}
+}
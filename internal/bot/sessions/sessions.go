@@ -0,0 +1,328 @@
+package sessions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kevensen/gollama-bubbletea/internal/bot/attachments"
+	"github.com/kevensen/gollama-bubbletea/internal/bot/messages"
+	"github.com/kevensen/gollama-bubbletea/internal/bot/rag"
+	"github.com/parakeet-nest/parakeet/llm"
+)
+
+// snippetLength is the number of characters of a message shown with a search result
+const snippetLength = 80
+
+// Session is a saved conversation
+type Session struct {
+	ID       string    `json:"id"`
+	Created  time.Time `json:"created"`
+	Updated  time.Time `json:"updated"`
+	Model    string    `json:"model,omitempty"`
+	Messages []Message `json:"messages"`
+}
+
+// Message is a message of a saved conversation
+type Message struct {
+	Role    string  `json:"role"`
+	Content string  `json:"content"`
+	Images  []Image `json:"images,omitempty"`
+}
+
+// Image is an image attached to a saved message, kept so it is sent again on later turns
+type Image struct {
+	Path   string `json:"path"`
+	Format string `json:"format"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Data   []byte `json:"data"` // Base64 encoded in the file
+}
+
+// Capture copies the conversation held by a message manager into the session
+func (s *Session) Capture(manager *messages.Manager) {
+	s.Messages = nil
+	for i := 0; ; i++ {
+		msg, ok := manager.MessageAt(i)
+		if !ok {
+			break
+		}
+		saved := Message{Role: msg.Role, Content: msg.Content}
+		for _, image := range msg.Images {
+			saved.Images = append(saved.Images, Image(image))
+		}
+		s.Messages = append(s.Messages, saved)
+	}
+}
+
+// Restore replaces the conversation held by a message manager with the session's,
+// keeping each message at the same position
+func (s *Session) Restore(manager *messages.Manager) {
+	manager.Clear()
+	for _, msg := range s.Messages {
+		var images []attachments.Image
+		for _, image := range msg.Images {
+			images = append(images, attachments.Image(image))
+		}
+		manager.AddImageMessage(llm.Message{Role: msg.Role, Content: msg.Content}, images)
+	}
+}
+
+// Title returns the first line of the first user message, without attached files
+func (s *Session) Title() string {
+	for _, msg := range s.Messages {
+		if msg.Role != "user" {
+			continue
+		}
+		text, _ := attachments.Split(msg.Content)
+		if line, _, _ := strings.Cut(strings.TrimSpace(text), "\n"); line != "" {
+			return line
+		}
+	}
+	return "(untitled)"
+}
+
+// Summary describes the session's length and the model it was held with
+func (s *Session) Summary() string {
+	summary := fmt.Sprintf("%d messages", len(s.Messages))
+	if len(s.Messages) == 1 {
+		summary = "1 message"
+	}
+	if s.Model != "" {
+		summary += " with " + s.Model
+	}
+	return summary
+}
+
+// Result is a saved session matching a search
+type Result struct {
+	Session *Session
+	Message int    // Position of the best matching message, -1 when listing every session
+	Snippet string // Text of the message around the match, or a summary when listing
+}
+
+// Store keeps sessions as JSON files in a directory, with a keyword index over
+// their messages for searching them
+type Store struct {
+	dir      string
+	sessions map[string]*Session  // Sessions read from the directory, by ID
+	modTimes map[string]time.Time // Modification time of each session's file when it was read
+	index    *rag.KeywordIndex    // Messages of every session, by session ID and position
+}
+
+// NewStore creates a store for the sessions in a directory, which is created
+// when the first session is saved
+func NewStore(dir string) *Store {
+	return &Store{
+		dir:      dir,
+		sessions: make(map[string]*Session),
+		modTimes: make(map[string]time.Time),
+		index:    rag.NewKeywordIndex(),
+	}
+}
+
+// New starts a session with an ID not used by any saved session
+func (s *Store) New(now time.Time) *Session {
+	base := now.Format("2006-01-02-150405")
+	id := base
+	for n := 2; s.exists(id); n++ {
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+	return &Session{ID: id, Created: now, Updated: now}
+}
+
+// exists reports whether a session ID is taken
+func (s *Store) exists(id string) bool {
+	if _, ok := s.sessions[id]; ok {
+		return true
+	}
+	_, err := os.Stat(s.path(id))
+	return err == nil
+}
+
+// path returns the file a session is saved in
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// Save writes a session to its file, readable only by the owner as conversations
+// can hold private text and images, and indexes its messages
+func (s *Store) Save(session *Session) error {
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create sessions directory: %v", err)
+	}
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := s.path(session.ID)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to save session: %v", err)
+	}
+	// WriteFile keeps the mode of a file saved by an earlier version
+	if err := os.Chmod(path, 0600); err != nil {
+		return fmt.Errorf("failed to save session: %v", err)
+	}
+
+	saved := *session
+	saved.Messages = append([]Message(nil), session.Messages...)
+	s.add(&saved)
+	if info, err := os.Stat(path); err == nil {
+		s.modTimes[session.ID] = info.ModTime()
+	}
+	return nil
+}
+
+// Load reads a saved session
+func (s *Store) Load(id string) (*Session, error) {
+	data, err := os.ReadFile(s.path(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session: %v", err)
+	}
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to parse session %s: %v", id, err)
+	}
+	session.ID = id
+	return &session, nil
+}
+
+// Refresh reads the sessions saved since the last refresh, such as by another
+// instance, and forgets deleted ones
+func (s *Store) Refresh() error {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	for _, file := range files {
+		id := strings.TrimSuffix(filepath.Base(file), ".json")
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		seen[id] = true
+		if modTime, ok := s.modTimes[id]; ok && modTime.Equal(info.ModTime()) {
+			continue
+		}
+		session, err := s.Load(id)
+		if err != nil {
+			// A damaged file shouldn't hide the other sessions
+			continue
+		}
+		s.add(session)
+		s.modTimes[id] = info.ModTime()
+	}
+
+	for id := range s.sessions {
+		if !seen[id] {
+			s.remove(id)
+		}
+	}
+	return nil
+}
+
+// add indexes a session's messages, replacing any earlier version of it
+func (s *Store) add(session *Session) {
+	s.remove(session.ID)
+	s.sessions[session.ID] = session
+
+	var chunks []rag.Chunk
+	for i, msg := range session.Messages {
+		// Retrieval context repeats the knowledge base rather than the conversation
+		if msg.Role == messages.RoleContext {
+			continue
+		}
+		chunks = append(chunks, rag.Chunk{
+			ID:       chunkID(session.ID, i),
+			Text:     messages.Displayed(msg.Role, msg.Content),
+			Metadata: map[string]any{"session": session.ID, "message": i},
+		})
+	}
+	s.index.Add(chunks...)
+}
+
+// remove drops a session and its messages from the index
+func (s *Store) remove(id string) {
+	session, ok := s.sessions[id]
+	if !ok {
+		return
+	}
+	var ids []string
+	for i := range session.Messages {
+		ids = append(ids, chunkID(id, i))
+	}
+	s.index.Remove(ids...)
+	delete(s.sessions, id)
+	delete(s.modTimes, id)
+}
+
+// chunkID names a message of a session in the index
+func chunkID(session string, message int) string {
+	return session + "/" + strconv.Itoa(message)
+}
+
+// Search returns the sessions with messages matching a query, best first, each
+// with its best matching message; an empty query lists every session, newest first
+func (s *Store) Search(query string) []Result {
+	var results []Result
+	if len(rag.Tokenize(query)) == 0 {
+		for _, session := range s.sessions {
+			results = append(results, Result{Session: session, Message: -1, Snippet: session.Summary()})
+		}
+		sort.Slice(results, func(i, j int) bool {
+			return results[i].Session.Updated.After(results[j].Session.Updated)
+		})
+		return results
+	}
+
+	seen := make(map[string]bool)
+	for _, match := range s.index.Search(query, 0, nil) {
+		id, _ := match.Chunk.Metadata["session"].(string)
+		message, _ := match.Chunk.Metadata["message"].(int)
+		session, ok := s.sessions[id]
+		if !ok || seen[id] {
+			continue
+		}
+		seen[id] = true
+		results = append(results, Result{Session: session, Message: message, Snippet: Snippet(match.Chunk.Text, query)})
+	}
+	return results
+}
+
+// Snippet returns a line of text around the first word of the query found in it
+func Snippet(text, query string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	lower := strings.ToLower(text)
+	if len(lower) != len(text) {
+		// Offsets in the lowered text wouldn't fit the text, so only exact case is found
+		lower = text
+	}
+	start := -1
+	for _, term := range rag.Tokenize(query) {
+		if i := strings.Index(lower, term); i >= 0 && (start < 0 || i < start) {
+			start = i
+		}
+	}
+
+	// Lead in with some of the text before the match
+	prefix := ""
+	if start > 0 {
+		runes := []rune(text[:start])
+		if lead := snippetLength / 4; len(runes) > lead {
+			text = string(runes[len(runes)-lead:]) + text[start:]
+			prefix = "…"
+		}
+	}
+	if utf8.RuneCountInString(text) > snippetLength {
+		text = string([]rune(text)[:snippetLength-1]) + "…"
+	}
+	return prefix + text
+}
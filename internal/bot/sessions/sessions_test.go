@@ -0,0 +1,153 @@
+package sessions
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kevensen/gollama-bubbletea/internal/bot/attachments"
+	"github.com/kevensen/gollama-bubbletea/internal/bot/messages"
+	"github.com/parakeet-nest/parakeet/llm"
+)
+
+func TestCaptureAndRestore(t *testing.T) {
+	manager := messages.NewManager()
+	manager.AddMessage(llm.Message{Role: "user", Content: attachments.Inline("Why does this fail?", []attachments.File{{Path: "main.go", Content: "package main"}})})
+	manager.AddContextMessage("Context: ...")
+	manager.AddMessage(llm.Message{Role: "assistant", Content: "It doesn't compile."})
+	screenshot := attachments.Image{Path: "error.png", Format: "png", Width: 2, Height: 1, Data: []byte{0x89, 'P', 'N', 'G'}}
+	manager.AddImageMessage(llm.Message{Role: "user", Content: "And this?"}, []attachments.Image{screenshot})
+
+	var session Session
+	session.Capture(manager)
+	if len(session.Messages) != 4 || session.Messages[1].Role != messages.RoleContext {
+		t.Fatalf("captured %+v", session.Messages)
+	}
+	if title := session.Title(); title != "Why does this fail?" {
+		t.Errorf("Title() = %q", title)
+	}
+
+	// Images survive the session's file
+	store := NewStore(t.TempDir())
+	session.ID = "images"
+	if err := store.Save(&session); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := store.Load(session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := messages.NewManager()
+	restored.AddMessage(llm.Message{Role: "user", Content: "replaced"})
+	loaded.Restore(restored)
+	for i, want := range session.Messages {
+		got, ok := restored.MessageAt(i)
+		if !ok || got.Role != want.Role || got.Content != want.Content {
+			t.Errorf("MessageAt(%d) = %+v, %v, want %+v", i, got, ok, want)
+		}
+	}
+	if _, ok := restored.MessageAt(4); ok {
+		t.Error("restored conversation has extra messages")
+	}
+	if got, _ := restored.MessageAt(3); len(got.Images) != 1 || !reflect.DeepEqual(got.Images[0], screenshot) {
+		t.Errorf("restored images = %+v, want %+v", got.Images, screenshot)
+	}
+	sent, err := restored.ChatMessages()
+	if err != nil || len(sent[len(sent)-1].Images) != 1 {
+		t.Errorf("ChatMessages() = %+v, %v, want the image sent again", sent, err)
+	}
+}
+
+func TestStoreSearch(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "sessions")
+	store := NewStore(dir)
+	now := time.Date(2026, 3, 14, 9, 30, 0, 0, time.UTC)
+
+	kafka := store.New(now)
+	kafka.Messages = []Message{
+		{Role: "user", Content: "Hello"},
+		{Role: "assistant", Content: "Hi, how can I help?"},
+		{Role: "user", Content: "Why does my Kafka consumer keep rebalancing?"},
+		{Role: messages.RoleContext, Content: "kafka kafka kafka"},
+		{Role: "assistant", Content: "Check max.poll.interval.ms on the consumer."},
+	}
+	if err := store.Save(kafka); err != nil {
+		t.Fatal(err)
+	}
+
+	// Conversations are private, so only the owner can read them
+	for path, want := range map[string]os.FileMode{dir: 0700, store.path(kafka.ID): 0600} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mode := info.Mode().Perm(); mode != want {
+			t.Errorf("%s mode = %v, want %v", path, mode, want)
+		}
+	}
+
+	recipes := store.New(now)
+	if recipes.ID == kafka.ID {
+		t.Fatalf("New reused ID %s", kafka.ID)
+	}
+	recipes.Updated = now.Add(time.Hour)
+	recipes.Messages = []Message{{Role: "user", Content: "A bread recipe please"}}
+	if err := store.Save(recipes); err != nil {
+		t.Fatal(err)
+	}
+
+	results := store.Search("kafka consumer")
+	if len(results) != 1 || results[0].Session.ID != kafka.ID || results[0].Message != 2 {
+		t.Fatalf("Search(kafka consumer) = %+v", results)
+	}
+	if !strings.Contains(results[0].Snippet, "Kafka consumer") {
+		t.Errorf("snippet %q doesn't show the match", results[0].Snippet)
+	}
+
+	// An empty query lists every session, newest first
+	results = store.Search("")
+	if len(results) != 2 || results[0].Session.ID != recipes.ID || results[0].Snippet != "1 message" {
+		t.Errorf("Search() = %+v", results)
+	}
+
+	// Another store reads the saved files, and forgets them once deleted
+	other := NewStore(dir)
+	if err := other.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if results := other.Search("bread"); len(results) != 1 || results[0].Session.ID != recipes.ID {
+		t.Errorf("Search(bread) after Refresh = %+v", results)
+	}
+	if err := os.Remove(store.path(recipes.ID)); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if results := other.Search("bread"); len(results) != 0 {
+		t.Errorf("Search(bread) after removal = %+v", results)
+	}
+
+	loaded, err := other.Load(kafka.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Messages) != len(kafka.Messages) || !loaded.Created.Equal(now) {
+		t.Errorf("Load() = %+v", loaded)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	text := strings.Repeat("lorem ipsum ", 10) + "the Kafka\nconsumer lags " + strings.Repeat("dolor ", 20)
+	snippet := Snippet(text, "consumer")
+	if !strings.HasPrefix(snippet, "…") || !strings.HasSuffix(snippet, "…") || !strings.Contains(snippet, "Kafka consumer lags") {
+		t.Errorf("Snippet() = %q", snippet)
+	}
+	if got := Snippet("short text", "missing"); got != "short text" {
+		t.Errorf("Snippet() without a match = %q", got)
+	}
+}
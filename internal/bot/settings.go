@@ -0,0 +1,46 @@
+package bot
+
+import (
+	"github.com/kevensen/gollama-bubbletea/internal/httpclient"
+	"github.com/kevensen/gollama-bubbletea/internal/settings"
+)
+
+// endpointFactories makes the HTTP clients of providers built from settings, so
+// providers built again, such as for each endpoint check, reuse connections
+var endpointFactories httpclient.Cache
+
+// SettingsProvider returns the backend described by settings: every endpoint merged
+// when model aggregation is enabled, otherwise the active endpoint
+func SettingsProvider(s *settings.Settings) Provider {
+	if !Aggregating(s) {
+		// OllamaURL mirrors the active endpoint unless overridden for this session
+		endpoint, _ := s.Endpoint(s.ActiveEndpoint)
+		endpoint.URL, endpoint.Provider = s.OllamaURL, s.Provider
+		return EndpointProvider(s, endpoint)
+	}
+
+	var members []Member
+	for _, endpoint := range s.Endpoints {
+		members = append(members, Member{
+			Name:     endpoint.Name,
+			Provider: EndpointProvider(s, endpoint),
+		})
+	}
+	return NewAggregateProvider(members)
+}
+
+// Aggregating reports whether the model list merges several endpoints
+func Aggregating(s *settings.Settings) bool {
+	return s.AggregateModels && len(s.Endpoints) > 1
+}
+
+// EndpointProvider creates the backend for an endpoint with its HTTP options,
+// falling back to Ollama when the provider name in settings is unknown
+func EndpointProvider(s *settings.Settings, endpoint settings.Endpoint) Provider {
+	factory := endpointFactories.Factory(s.EndpointHTTP(endpoint))
+	provider, err := NewProvider(endpoint.Provider, endpoint.URL, factory)
+	if err != nil {
+		return NewOllamaProvider(endpoint.URL, factory)
+	}
+	return provider
+}
@@ -0,0 +1,246 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config describes how requests to a server are authenticated and secured
+type Config struct {
+	Headers            map[string]string `json:"headers,omitempty"`            // Sent with every request
+	BearerToken        string            `json:"bearerToken,omitempty"`        // Sent as Authorization: Bearer <token>
+	BearerTokenEnv     string            `json:"bearerTokenEnv,omitempty"`     // Environment variable holding the token, to keep it out of the settings file
+	BearerTokenFile    string            `json:"bearerTokenFile,omitempty"`    // File holding the token, such as a mounted secret
+	CAFile             string            `json:"caFile,omitempty"`             // PEM bundle trusted in addition to the system roots
+	CertFile           string            `json:"certFile,omitempty"`           // Client certificate for mutual TLS
+	KeyFile            string            `json:"keyFile,omitempty"`            // Key of the client certificate
+	Proxy              string            `json:"proxy,omitempty"`              // Proxy URL; empty uses HTTP_PROXY and friends
+	InsecureSkipVerify bool              `json:"insecureSkipVerify,omitempty"` // Skip server certificate checks
+}
+
+// Merge returns c with the fields set in override replacing its own
+// Headers are combined, with override winning on conflicts
+func (c Config) Merge(override *Config) Config {
+	if override == nil {
+		return c
+	}
+
+	merged := c
+	if len(override.Headers) > 0 {
+		merged.Headers = make(map[string]string, len(c.Headers)+len(override.Headers))
+		maps.Copy(merged.Headers, c.Headers)
+		maps.Copy(merged.Headers, override.Headers)
+	}
+	if override.BearerToken != "" || override.BearerTokenEnv != "" || override.BearerTokenFile != "" {
+		merged.BearerToken = override.BearerToken
+		merged.BearerTokenEnv = override.BearerTokenEnv
+		merged.BearerTokenFile = override.BearerTokenFile
+	}
+	if override.CAFile != "" {
+		merged.CAFile = override.CAFile
+	}
+	if override.CertFile != "" {
+		merged.CertFile = override.CertFile
+		merged.KeyFile = override.KeyFile
+	}
+	if override.Proxy != "" {
+		merged.Proxy = override.Proxy
+	}
+	if override.InsecureSkipVerify {
+		merged.InsecureSkipVerify = true
+	}
+	return merged
+}
+
+// Factory creates HTTP clients sharing one configured transport
+type Factory struct {
+	transport http.RoundTripper
+	err       error
+}
+
+// New creates a factory for cfg
+// Configuration errors such as an unreadable CA bundle are reported by Err and
+// returned by every request made with the factory's clients
+func New(cfg Config) *Factory {
+	transport, err := newTransport(cfg)
+	if err != nil {
+		return &Factory{transport: errorTransport{err}, err: err}
+	}
+	return &Factory{transport: transport}
+}
+
+// defaultFactory is shared by every caller of Default, so their clients reuse connections
+var defaultFactory = sync.OnceValue(func() *Factory {
+	return New(Config{})
+})
+
+// Default returns the shared factory without authentication or custom TLS
+func Default() *Factory {
+	return defaultFactory()
+}
+
+// Cache keeps one factory per configuration, so clients made again for the same
+// settings share a transport and its idle connections rather than opening new ones
+// Token and certificate files are read when a configuration is first used; one that
+// failed is tried again on the next use. The zero value is ready to use
+type Cache struct {
+	mu        sync.Mutex
+	factories map[string]*Factory // By configuration, encoded as JSON
+}
+
+// Factory returns the factory for cfg, creating it on first use
+func (c *Cache) Factory(cfg Config) *Factory {
+	key, err := json.Marshal(cfg)
+	if err != nil {
+		return New(cfg)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if factory, ok := c.factories[string(key)]; ok {
+		return factory
+	}
+	factory := New(cfg)
+	if factory.Err() == nil {
+		if c.factories == nil {
+			c.factories = make(map[string]*Factory)
+		}
+		c.factories[string(key)] = factory
+	}
+	return factory
+}
+
+// Err returns the configuration error, if any
+func (f *Factory) Err() error {
+	if f == nil {
+		return nil
+	}
+	return f.err
+}
+
+// Client returns a client with the given timeout; zero means no timeout
+// A nil factory returns a client with the default configuration
+func (f *Factory) Client(timeout time.Duration) *http.Client {
+	if f == nil {
+		f = Default()
+	}
+	return &http.Client{
+		Transport: f.transport,
+		Timeout:   timeout,
+	}
+}
+
+func newTransport(cfg Config) (http.RoundTripper, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %v", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if cfg.CAFile != "" || cfg.CertFile != "" || cfg.InsecureSkipVerify {
+		tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+
+		if cfg.CAFile != "" {
+			pem, err := os.ReadFile(cfg.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA bundle: %v", err)
+			}
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in CA bundle %s", cfg.CAFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+
+		if cfg.CertFile != "" {
+			cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load client certificate: %v", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	headers := make(http.Header)
+	for name, value := range cfg.Headers {
+		headers.Set(name, value)
+	}
+	token, err := cfg.bearerToken()
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		headers.Set("Authorization", "Bearer "+token)
+	}
+	if len(headers) == 0 {
+		return transport, nil
+	}
+	return &headerTransport{headers: headers, next: transport}, nil
+}
+
+// bearerToken returns the token given directly, or else read from the
+// environment variable or file named in the config
+func (c Config) bearerToken() (string, error) {
+	switch {
+	case c.BearerToken != "":
+		return c.BearerToken, nil
+	case c.BearerTokenEnv != "":
+		token := strings.TrimSpace(os.Getenv(c.BearerTokenEnv))
+		if token == "" {
+			return "", fmt.Errorf("bearer token variable %s is not set", c.BearerTokenEnv)
+		}
+		return token, nil
+	case c.BearerTokenFile != "":
+		data, err := os.ReadFile(c.BearerTokenFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read bearer token: %v", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	return "", nil
+}
+
+// headerTransport adds configured headers to every request
+type headerTransport struct {
+	headers http.Header
+	next    http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(req.Context())
+	for name, values := range t.headers {
+		req.Header[name] = values
+	}
+	return t.next.RoundTrip(req)
+}
+
+// errorTransport fails every request with a configuration error
+type errorTransport struct {
+	err error
+}
+
+func (t errorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return nil, t.err
+}
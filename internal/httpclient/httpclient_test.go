@@ -0,0 +1,135 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFactoryHeaders(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	global := Config{Headers: map[string]string{"X-Team": "ml", "X-Env": "dev"}}
+	cfg := global.Merge(&Config{BearerToken: "secret", Headers: map[string]string{"X-Env": "prod"}})
+
+	factory := New(cfg)
+	if factory.Err() != nil {
+		t.Fatalf("New() error = %v", factory.Err())
+	}
+	resp, err := factory.Client(0).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if got.Get("Authorization") != "Bearer secret" {
+		t.Errorf("Authorization = %q", got.Get("Authorization"))
+	}
+	if got.Get("X-Team") != "ml" || got.Get("X-Env") != "prod" {
+		t.Errorf("headers = %v, want X-Team ml and X-Env prod", got)
+	}
+	if global.Headers["X-Env"] != "dev" {
+		t.Error("Merge() modified the base config")
+	}
+}
+
+func TestFactoryTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// The test server's certificate isn't trusted without configuration
+	if _, err := Default().Client(0).Get(server.URL); err == nil {
+		t.Error("Get() with default config should fail certificate verification")
+	}
+
+	resp, err := New(Config{InsecureSkipVerify: true}).Client(0).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() with InsecureSkipVerify error = %v", err)
+	}
+	resp.Body.Close()
+
+	// A missing CA bundle is reported by the factory and by every request
+	factory := New(Config{CAFile: filepath.Join(t.TempDir(), "missing.pem")})
+	if factory.Err() == nil {
+		t.Error("New() with a missing CA bundle should report an error")
+	}
+	if _, err := factory.Client(0).Get(server.URL); err == nil {
+		t.Error("Get() with a broken config should fail")
+	}
+}
+
+func TestFactoryBearerTokenSources(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOLLAMA_TEST_TOKEN", "from-env")
+
+	// An endpoint's token source replaces the global token
+	global := Config{BearerToken: "global"}
+	for _, tc := range []struct {
+		cfg  Config
+		want string
+	}{
+		{global.Merge(&Config{BearerTokenEnv: "GOLLAMA_TEST_TOKEN"}), "Bearer from-env"},
+		{global.Merge(&Config{BearerTokenFile: tokenFile}), "Bearer from-file"},
+	} {
+		resp, err := New(tc.cfg).Client(0).Get(server.URL)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		resp.Body.Close()
+		if got != tc.want {
+			t.Errorf("Authorization = %q, want %q", got, tc.want)
+		}
+	}
+
+	if New(Config{BearerTokenEnv: "GOLLAMA_TEST_UNSET"}).Err() == nil {
+		t.Error("New() with an unset token variable should report an error")
+	}
+	if New(Config{BearerTokenFile: filepath.Join(t.TempDir(), "missing")}).Err() == nil {
+		t.Error("New() with a missing token file should report an error")
+	}
+}
+
+func TestCacheReusesFactories(t *testing.T) {
+	if Default() != Default() {
+		t.Error("Default() made a new factory")
+	}
+	var nilFactory *Factory
+	if nilFactory.Client(0).Transport != Default().Client(0).Transport {
+		t.Error("a nil factory's client doesn't share the default transport")
+	}
+
+	var cache Cache
+	cfg := Config{Headers: map[string]string{"X-Team": "ml", "X-Env": "dev"}}
+	same := Config{Headers: map[string]string{"X-Env": "dev", "X-Team": "ml"}}
+	if cache.Factory(cfg) != cache.Factory(same) {
+		t.Error("Factory() made a new factory for the same configuration")
+	}
+	if cache.Factory(cfg) == cache.Factory(Config{BearerToken: "t"}) {
+		t.Error("Factory() shared a factory between configurations")
+	}
+
+	// A failed configuration is tried again, such as once the token is set
+	failing := Config{BearerTokenEnv: "GOLLAMA_TEST_LATER"}
+	if cache.Factory(failing).Err() == nil {
+		t.Fatal("Factory() with an unset token variable should report an error")
+	}
+	t.Setenv("GOLLAMA_TEST_LATER", "now-set")
+	if err := cache.Factory(failing).Err(); err != nil {
+		t.Errorf("Factory() after setting the token = %v", err)
+	}
+}
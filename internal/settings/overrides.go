@@ -0,0 +1,249 @@
+package settings
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Environment variables read by OverridesFromEnv
+const (
+	EnvConfig     = "GOLLAMA_CONFIG"     // Settings file
+	EnvProfile    = "GOLLAMA_PROFILE"    // Profile to apply at startup
+	EnvURL        = "GOLLAMA_URL"        // LLM server URL
+	EnvOllamaHost = "OLLAMA_HOST"        // Ollama's own variable, used when GOLLAMA_URL is unset
+	EnvModel      = "GOLLAMA_MODEL"      // Model to start with
+	EnvChromaURL  = "GOLLAMA_CHROMA_URL" // ChromaDB URL
+	EnvRAG        = "GOLLAMA_RAG"        // true or false
+	EnvTheme      = "GOLLAMA_THEME"      // dark or light
+)
+
+// DefaultOllamaPort is assumed when OLLAMA_HOST doesn't name a port
+const DefaultOllamaPort = "11434"
+
+// Overrides are settings given on the command line or in the environment
+// They apply to the running session only and are not written back by Save
+type Overrides struct {
+	URL       string
+	Model     string
+	ChromaURL string
+	RAG       *bool  // nil leaves the saved setting alone
+	Theme     string // dark or light
+}
+
+type overrideField int
+
+const (
+	overrideURL overrideField = iota
+	overrideModel
+	overrideChromaURL
+	overrideRAG
+	overrideTheme
+)
+
+// OverridesFromEnv reads overrides from environment variables using getenv
+// GOLLAMA_URL takes precedence over OLLAMA_HOST
+func OverridesFromEnv(getenv func(string) string) (Overrides, error) {
+	o := Overrides{
+		URL:       getenv(EnvURL),
+		Model:     getenv(EnvModel),
+		ChromaURL: getenv(EnvChromaURL),
+		Theme:     getenv(EnvTheme),
+	}
+	if o.URL == "" {
+		o.URL = NormalizeOllamaHost(getenv(EnvOllamaHost))
+	}
+
+	if value := getenv(EnvRAG); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return Overrides{}, fmt.Errorf("invalid %s: %q is not true or false", EnvRAG, value)
+		}
+		o.RAG = &enabled
+	}
+
+	if o.Theme != "" {
+		if _, err := ParseTheme(o.Theme); err != nil {
+			return Overrides{}, fmt.Errorf("invalid %s: %v", EnvTheme, err)
+		}
+	}
+	return o, nil
+}
+
+// Merge returns o with the values set in higher replacing its own
+func (o Overrides) Merge(higher Overrides) Overrides {
+	if higher.URL != "" {
+		o.URL = higher.URL
+	}
+	if higher.Model != "" {
+		o.Model = higher.Model
+	}
+	if higher.ChromaURL != "" {
+		o.ChromaURL = higher.ChromaURL
+	}
+	if higher.RAG != nil {
+		o.RAG = higher.RAG
+	}
+	if higher.Theme != "" {
+		o.Theme = higher.Theme
+	}
+	return o
+}
+
+// ParseTheme reports whether a theme name selects dark mode
+func ParseTheme(theme string) (dark bool, err error) {
+	switch strings.ToLower(theme) {
+	case "dark":
+		return true, nil
+	case "light":
+		return false, nil
+	}
+	return false, fmt.Errorf("unknown theme %q, use dark or light", theme)
+}
+
+// NormalizeOllamaHost turns an OLLAMA_HOST value such as "gpu-box" or ":8080"
+// into a URL, applying Ollama's defaults for the scheme, host and port
+func NormalizeOllamaHost(host string) string {
+	host = strings.TrimSpace(host)
+	if host == "" {
+		return ""
+	}
+
+	scheme := "http"
+	if i := strings.Index(host, "://"); i >= 0 {
+		scheme, host = host[:i], host[i+3:]
+	}
+	host = strings.TrimSuffix(host, "/")
+
+	hostname, port, err := net.SplitHostPort(host)
+	if err != nil {
+		// No port given
+		hostname, port = host, DefaultOllamaPort
+		if scheme == "https" {
+			port = "443"
+		}
+	}
+	if hostname == "" {
+		hostname = "127.0.0.1"
+	}
+
+	u := url.URL{Scheme: scheme, Host: net.JoinHostPort(hostname, port)}
+	return u.String()
+}
+
+// ApplyOverrides replaces settings with the given overrides for this session
+// The file values are kept so Save doesn't persist the overrides; changing an
+// overridden setting through its setter makes the new value persistent again
+func (s *Settings) ApplyOverrides(o Overrides) error {
+	dark := s.DarkMode
+	if o.Theme != "" {
+		var err error
+		if dark, err = ParseTheme(o.Theme); err != nil {
+			return err
+		}
+	}
+
+	if s.file == nil {
+		file := *s
+		s.file = &file
+		s.overridden = make(map[overrideField]bool)
+	}
+	s.overrides = s.overrides.Merge(o)
+
+	if o.URL != "" {
+		s.OllamaURL = o.URL
+		s.overridden[overrideURL] = true
+	}
+	if o.Model != "" {
+		s.LastModel = o.Model
+		s.AggregateLastModel = o.Model
+		s.overridden[overrideModel] = true
+	}
+	if o.ChromaURL != "" {
+		s.ChromaDBURL = o.ChromaURL
+		s.overridden[overrideChromaURL] = true
+	}
+	if o.RAG != nil {
+		s.RAGEnabled = *o.RAG
+		s.overridden[overrideRAG] = true
+	}
+	if o.Theme != "" {
+		s.DarkMode = dark
+		s.overridden[overrideTheme] = true
+	}
+	return nil
+}
+
+// releaseChanged makes overridden settings that differ from before persistent again
+func (s *Settings) releaseChanged(before *Settings) {
+	if s.OllamaURL != before.OllamaURL {
+		s.release(overrideURL)
+	}
+	if s.LastModel != before.LastModel || s.AggregateLastModel != before.AggregateLastModel {
+		s.release(overrideModel)
+	}
+	if s.ChromaDBURL != before.ChromaDBURL {
+		s.release(overrideChromaURL)
+	}
+	if s.RAGEnabled != before.RAGEnabled {
+		s.release(overrideRAG)
+	}
+	if s.DarkMode != before.DarkMode {
+		s.release(overrideTheme)
+	}
+}
+
+// activeOverrides returns the overrides still in effect, leaving out settings
+// the user has since changed
+func (s *Settings) activeOverrides() Overrides {
+	var o Overrides
+	if s.overridden[overrideURL] {
+		o.URL = s.overrides.URL
+	}
+	if s.overridden[overrideModel] {
+		o.Model = s.overrides.Model
+	}
+	if s.overridden[overrideChromaURL] {
+		o.ChromaURL = s.overrides.ChromaURL
+	}
+	if s.overridden[overrideRAG] {
+		o.RAG = s.overrides.RAG
+	}
+	if s.overridden[overrideTheme] {
+		o.Theme = s.overrides.Theme
+	}
+	return o
+}
+
+// release makes an overridden setting persistent again once the user changes it
+func (s *Settings) release(field overrideField) {
+	delete(s.overridden, field)
+}
+
+// persisted returns the settings as they should be written to the file
+func (s *Settings) persisted() *Settings {
+	if len(s.overridden) == 0 {
+		return s
+	}
+
+	out := *s
+	if s.overridden[overrideURL] {
+		out.OllamaURL = s.file.OllamaURL
+	}
+	if s.overridden[overrideModel] {
+		out.LastModel = s.file.LastModel
+		out.AggregateLastModel = s.file.AggregateLastModel
+	}
+	if s.overridden[overrideChromaURL] {
+		out.ChromaDBURL = s.file.ChromaDBURL
+	}
+	if s.overridden[overrideRAG] {
+		out.RAGEnabled = s.file.RAGEnabled
+	}
+	if s.overridden[overrideTheme] {
+		out.DarkMode = s.file.DarkMode
+	}
+	return &out
+}
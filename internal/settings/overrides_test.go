@@ -0,0 +1,102 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOverridesFromEnv(t *testing.T) {
+	env := map[string]string{
+		EnvOllamaHost: "gpu-box",
+		EnvModel:      "llama3.2:1b",
+		EnvRAG:        "true",
+	}
+	getenv := func(key string) string { return env[key] }
+
+	o, err := OverridesFromEnv(getenv)
+	if err != nil {
+		t.Fatalf("OverridesFromEnv() error = %v", err)
+	}
+	if o.URL != "http://gpu-box:11434" || o.Model != "llama3.2:1b" || o.RAG == nil || !*o.RAG {
+		t.Errorf("OverridesFromEnv() = %+v", o)
+	}
+
+	// GOLLAMA_URL wins over OLLAMA_HOST, and flags win over both
+	env[EnvURL] = "http://laptop:11434"
+	o, _ = OverridesFromEnv(getenv)
+	if o.URL != "http://laptop:11434" {
+		t.Errorf("URL = %q, want GOLLAMA_URL", o.URL)
+	}
+	merged := o.Merge(Overrides{URL: "http://flag:11434"})
+	if merged.URL != "http://flag:11434" || merged.Model != "llama3.2:1b" {
+		t.Errorf("Merge() = %+v", merged)
+	}
+
+	env[EnvRAG] = "maybe"
+	if _, err := OverridesFromEnv(getenv); err == nil {
+		t.Error("OverridesFromEnv() should reject an invalid GOLLAMA_RAG")
+	}
+}
+
+func TestNormalizeOllamaHost(t *testing.T) {
+	tests := map[string]string{
+		"":                       "",
+		"gpu-box":                "http://gpu-box:11434",
+		"0.0.0.0:8080":           "http://0.0.0.0:8080",
+		":8080":                  "http://127.0.0.1:8080",
+		"https://ollama.example": "https://ollama.example:443",
+		"http://[::1]:11434/":    "http://[::1]:11434",
+	}
+	for host, want := range tests {
+		if got := NormalizeOllamaHost(host); got != want {
+			t.Errorf("NormalizeOllamaHost(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestOverridesNotSaved(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "custom", "settings.json")
+
+	settings, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom() error = %v", err)
+	}
+	settings.OllamaURL = "http://file:11434"
+	settings.LastModel = "file-model"
+	if err := settings.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	rag := true
+	if err := settings.ApplyOverrides(Overrides{URL: "http://flag:11434", Model: "flag-model", RAG: &rag, Theme: "dark"}); err != nil {
+		t.Fatalf("ApplyOverrides() error = %v", err)
+	}
+	if settings.OllamaURL != "http://flag:11434" || !settings.DarkMode || !settings.RAGEnabled {
+		t.Errorf("overrides not applied: %+v", settings)
+	}
+
+	// Saving an unrelated change keeps the file values of overridden settings
+	settings.SetRAGTopK(7)
+	// Changing an overridden setting makes it persistent
+	settings.SetDarkMode(false)
+	settings.SetLastModel("chosen-model")
+
+	saved, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom() error = %v", err)
+	}
+	if saved.OllamaURL != "http://file:11434" || saved.RAGEnabled {
+		t.Errorf("overrides were saved: url %s rag %t", saved.OllamaURL, saved.RAGEnabled)
+	}
+	if saved.RAGTopK != 7 || saved.LastModel != "chosen-model" {
+		t.Errorf("changes were not saved: topK %d model %s", saved.RAGTopK, saved.LastModel)
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("settings were not written to the custom path: %v", err)
+	}
+	if err := settings.ApplyOverrides(Overrides{Theme: "purple"}); err == nil {
+		t.Error("ApplyOverrides() should reject an unknown theme")
+	}
+}
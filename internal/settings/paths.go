@@ -0,0 +1,91 @@
+package settings
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// XDG base directory variables read by ResolvePaths
+const (
+	EnvXDGConfigHome = "XDG_CONFIG_HOME"
+	EnvXDGDataHome   = "XDG_DATA_HOME"
+	EnvXDGCacheHome  = "XDG_CACHE_HOME"
+)
+
+// appName is the directory name used inside each base directory
+const appName = "gollama"
+
+// Paths are the directories gollama keeps its files in
+// None of them are created until something is written there
+type Paths struct {
+	Config string // Settings
+	Data   string // Sessions and knowledge base sync manifests
+	Cache  string // Model information that can be fetched again, and the log
+}
+
+// ResolvePaths returns the directories named by the XDG environment variables,
+// falling back to ~/.config, ~/.local/share and ~/.cache
+func ResolvePaths() (Paths, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return Paths{}, fmt.Errorf("failed to find home directory: %v", err)
+	}
+	return resolvePaths(os.Getenv, homeDir), nil
+}
+
+func resolvePaths(getenv func(string) string, homeDir string) Paths {
+	base := func(env string, fallback ...string) string {
+		// The XDG spec says relative paths are invalid and should be ignored
+		if dir := getenv(env); filepath.IsAbs(dir) {
+			return filepath.Join(dir, appName)
+		}
+		return filepath.Join(append([]string{homeDir}, append(fallback, appName)...)...)
+	}
+
+	return Paths{
+		Config: base(EnvXDGConfigHome, ".config"),
+		Data:   base(EnvXDGDataHome, ".local", "share"),
+		Cache:  base(EnvXDGCacheHome, ".cache"),
+	}
+}
+
+// ConfigDir returns the gollama configuration directory
+func ConfigDir() (string, error) {
+	paths, err := ResolvePaths()
+	return paths.Config, err
+}
+
+// DataDir returns the gollama data directory
+func DataDir() (string, error) {
+	paths, err := ResolvePaths()
+	return paths.Data, err
+}
+
+// SettingsFile returns the default settings file
+func (p Paths) SettingsFile() string {
+	return filepath.Join(p.Config, "settings.json")
+}
+
+// MoveLegacyData moves knowledge base sync manifests, which older versions kept
+// next to the settings, into the data directory
+func MoveLegacyData(p Paths) error {
+	manifests, err := filepath.Glob(filepath.Join(p.Config, "rag-sync-*.json"))
+	if err != nil || len(manifests) == 0 {
+		return err
+	}
+
+	if err := os.MkdirAll(p.Data, 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %v", err)
+	}
+	for _, manifest := range manifests {
+		target := filepath.Join(p.Data, filepath.Base(manifest))
+		if _, err := os.Stat(target); err == nil {
+			continue
+		}
+		if err := os.Rename(manifest, target); err != nil {
+			return fmt.Errorf("failed to move %s to the data directory: %v", manifest, err)
+		}
+	}
+	return nil
+}
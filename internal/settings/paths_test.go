@@ -0,0 +1,78 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// Tests locate settings through HOME, which the XDG variables would take precedence over
+	for _, env := range []string{EnvXDGConfigHome, EnvXDGDataHome, EnvXDGCacheHome} {
+		os.Unsetenv(env)
+	}
+	os.Exit(m.Run())
+}
+
+func TestResolvePaths(t *testing.T) {
+	env := map[string]string{
+		EnvXDGConfigHome: "/etc/xdg-config",
+		EnvXDGDataHome:   "relative/data", // Ignored as the XDG spec requires
+	}
+	paths := resolvePaths(func(key string) string { return env[key] }, "/home/me")
+
+	want := Paths{
+		Config: "/etc/xdg-config/gollama",
+		Data:   "/home/me/.local/share/gollama",
+		Cache:  "/home/me/.cache/gollama",
+	}
+	if paths != want {
+		t.Errorf("resolvePaths() = %+v, want %+v", paths, want)
+	}
+}
+
+func TestLoadDoesNotCreateDirectories(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	settings, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".config")); !os.IsNotExist(err) {
+		t.Errorf("Load() created the config directory")
+	}
+
+	if err := settings.SetDarkMode(true); err != nil {
+		t.Fatalf("SetDarkMode() error = %v", err)
+	}
+	info, err := os.Stat(filepath.Join(home, ".config", "gollama", "settings.json"))
+	if err != nil {
+		t.Fatalf("Save() didn't create the settings file: %v", err)
+	}
+	// Settings can hold bearer tokens, so only the owner may read them
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("settings file mode = %v, want 0600", mode)
+	}
+	if info, err := os.Stat(filepath.Join(home, ".config", "gollama")); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("settings directory = %v, %v, want mode 0700", info, err)
+	}
+}
+
+func TestMoveLegacyData(t *testing.T) {
+	dir := t.TempDir()
+	paths := Paths{Config: filepath.Join(dir, "config"), Data: filepath.Join(dir, "data")}
+	os.MkdirAll(paths.Config, 0755)
+	os.WriteFile(filepath.Join(paths.Config, "rag-sync-0a1b2c3d.json"), []byte("{}"), 0644)
+	os.WriteFile(paths.SettingsFile(), []byte("{}"), 0644)
+
+	if err := MoveLegacyData(paths); err != nil {
+		t.Fatalf("MoveLegacyData() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(paths.Data, "rag-sync-0a1b2c3d.json")); err != nil {
+		t.Errorf("manifest was not moved: %v", err)
+	}
+	if _, err := os.Stat(paths.SettingsFile()); err != nil {
+		t.Errorf("settings file should stay in the config directory: %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package settings
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// Profile is a named bundle of settings for one way of working, such as a
+// project knowledge base or a remote GPU server
+// Empty fields leave the current setting unchanged when the profile is applied
+type Profile struct {
+	Name          string  `json:"name"`
+	Endpoint      string  `json:"endpoint,omitempty"`      // Endpoint to switch to
+	URL           string  `json:"url,omitempty"`           // Server to use when no endpoint is named
+	Provider      string  `json:"provider,omitempty"`      // Backend at URL: ollama or openai
+	Model         string  `json:"model,omitempty"`         // Model to start with
+	RAGEnabled    *bool   `json:"ragEnabled,omitempty"`    // Turn RAG on or off
+	ChromaDBURL   string  `json:"chromaDBURL,omitempty"`   // Knowledge base server
+	RAGCollection string  `json:"ragCollection,omitempty"` // Knowledge base collection
+	RAGMode       string  `json:"ragMode,omitempty"`       // vector, keyword or hybrid
+	RAGTopK       int     `json:"ragTopK,omitempty"`       // Number of documents retrieved
+	Persona       *string `json:"persona,omitempty"`       // System prompt, empty to remove it
+	Theme         string  `json:"theme,omitempty"`         // dark or light
+}
+
+// Profile returns the named profile
+func (s *Settings) Profile(name string) (Profile, bool) {
+	for _, profile := range s.Profiles {
+		if profile.Name == name {
+			return profile, true
+		}
+	}
+	return Profile{}, false
+}
+
+// ProfileNames returns the names of the configured profiles
+func (s *Settings) ProfileNames() []string {
+	var names []string
+	for _, profile := range s.Profiles {
+		names = append(names, profile.Name)
+	}
+	return names
+}
+
+// ApplyProfile applies every setting of a profile together and saves settings
+// Nothing changes if the profile is invalid or the settings can't be saved
+func (s *Settings) ApplyProfile(name string) error {
+	profile, ok := s.Profile(name)
+	if !ok {
+		return fmt.Errorf("profile not found: %s", name)
+	}
+	if err := s.validateProfile(profile); err != nil {
+		return fmt.Errorf("profile %s: %v", name, err)
+	}
+
+	previous := *s
+	s.Endpoints = slices.Clone(s.Endpoints)
+	s.overridden = maps.Clone(s.overridden)
+	s.applyProfile(profile)
+
+	if err := s.Save(); err != nil {
+		*s = previous
+		return err
+	}
+	return nil
+}
+
+// applyProfile copies the values set in a profile that has been validated
+func (s *Settings) applyProfile(profile Profile) {
+	s.ActiveProfile = profile.Name
+
+	endpoint := profile.Endpoint
+	if endpoint == "" && profile.URL != "" {
+		endpoint = s.profileEndpoint(profile)
+	}
+	if endpoint != "" {
+		s.useEndpoint(endpoint)
+	}
+
+	if profile.Model != "" {
+		s.release(overrideModel)
+		s.LastModel = profile.Model
+		if i := s.endpointIndex(s.ActiveEndpoint); i >= 0 {
+			s.Endpoints[i].LastModel = profile.Model
+		}
+	}
+	if profile.RAGEnabled != nil {
+		s.release(overrideRAG)
+		s.RAGEnabled = *profile.RAGEnabled
+	}
+	if profile.ChromaDBURL != "" {
+		s.release(overrideChromaURL)
+		s.ChromaDBURL = profile.ChromaDBURL
+	}
+	if profile.RAGCollection != "" {
+		s.RAGCollection = profile.RAGCollection
+	}
+	if profile.RAGMode != "" {
+		s.RAGMode = profile.RAGMode
+	}
+	if profile.RAGTopK > 0 {
+		s.RAGTopK = profile.RAGTopK
+	}
+	if profile.Persona != nil {
+		s.Persona = *profile.Persona
+	}
+	if profile.Theme != "" {
+		s.release(overrideTheme)
+		s.DarkMode, _ = ParseTheme(profile.Theme)
+	}
+}
+
+// profileEndpoint returns the endpoint serving a profile's URL, adding one
+// named after the profile if no endpoint has that URL
+func (s *Settings) profileEndpoint(profile Profile) string {
+	provider := profile.Provider
+	for _, endpoint := range s.Endpoints {
+		if endpoint.URL == profile.URL && (provider == "" || endpoint.Provider == provider) {
+			return endpoint.Name
+		}
+	}
+
+	if provider == "" {
+		provider = "ollama"
+	}
+	endpoint := Endpoint{Name: profile.Name, URL: profile.URL, Provider: provider}
+	if i := s.endpointIndex(profile.Name); i >= 0 {
+		endpoint.LastModel = s.Endpoints[i].LastModel
+		endpoint.HTTP = s.Endpoints[i].HTTP
+		s.Endpoints[i] = endpoint
+	} else {
+		s.Endpoints = append(s.Endpoints, endpoint)
+	}
+	return endpoint.Name
+}
+
+// validateProfile checks a profile's values and that its endpoint exists
+func (s *Settings) validateProfile(profile Profile) error {
+	if profile.Endpoint != "" {
+		if _, ok := s.Endpoint(profile.Endpoint); !ok {
+			return fmt.Errorf("endpoint not found: %s", profile.Endpoint)
+		}
+	}
+	if err := validateURL(profile.URL); err != nil {
+		return fmt.Errorf("url: %v", err)
+	}
+	if profile.Provider != "" && !slices.Contains(validProviders, profile.Provider) {
+		return fmt.Errorf("provider: unknown backend %q, use ollama or openai", profile.Provider)
+	}
+	if err := validateURL(profile.ChromaDBURL); err != nil {
+		return fmt.Errorf("chromaDBURL: %v", err)
+	}
+	if profile.RAGMode != "" && !slices.Contains(validRAGModes, profile.RAGMode) {
+		return fmt.Errorf("ragMode: unknown mode %q, use vector, keyword or hybrid", profile.RAGMode)
+	}
+	if profile.RAGTopK < 0 {
+		return fmt.Errorf("ragTopK: must be at least 1, got %d", profile.RAGTopK)
+	}
+	if profile.Theme != "" {
+		if _, err := ParseTheme(profile.Theme); err != nil {
+			return fmt.Errorf("theme: %v", err)
+		}
+	}
+	return nil
+}
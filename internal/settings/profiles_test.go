@@ -0,0 +1,72 @@
+package settings
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	settings, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom() error = %v", err)
+	}
+	settings.Endpoints = []Endpoint{{Name: "laptop", URL: "http://localhost:11434", Provider: "ollama"}}
+	settings.ActiveEndpoint = "laptop"
+	settings.OllamaURL = "http://localhost:11434"
+
+	enabled := true
+	persona := "You are a terse code reviewer."
+	settings.Profiles = []Profile{
+		{
+			Name:          "work",
+			Model:         "qwen2.5-coder",
+			RAGEnabled:    &enabled,
+			ChromaDBURL:   "http://chroma:8000",
+			RAGCollection: "work-repo",
+			RAGMode:       "hybrid",
+			Persona:       &persona,
+			Theme:         "dark",
+		},
+		{Name: "gpu", URL: "http://gpu-box:8080/v1", Provider: "openai", Model: "llama3.3:70b"},
+		{Name: "broken", Endpoint: "missing", Theme: "dark"},
+	}
+
+	if err := settings.ApplyProfile("work"); err != nil {
+		t.Fatalf("ApplyProfile(work) error = %v", err)
+	}
+	if !settings.RAGEnabled || settings.RAGCollection != "work-repo" || settings.RAGMode != "hybrid" ||
+		settings.Persona != persona || !settings.DarkMode || settings.LastModel != "qwen2.5-coder" {
+		t.Errorf("work profile not applied: %+v", settings)
+	}
+	if settings.ActiveEndpoint != "laptop" || settings.Endpoints[0].LastModel != "qwen2.5-coder" {
+		t.Errorf("work profile should keep the endpoint and remember its model, got %+v", settings.Endpoints)
+	}
+
+	// A URL without a matching endpoint adds one named after the profile
+	if err := settings.ApplyProfile("gpu"); err != nil {
+		t.Fatalf("ApplyProfile(gpu) error = %v", err)
+	}
+	if settings.ActiveEndpoint != "gpu" || settings.OllamaURL != "http://gpu-box:8080/v1" || settings.Provider != "openai" {
+		t.Errorf("gpu profile not applied: endpoint %q, URL %q, provider %q", settings.ActiveEndpoint, settings.OllamaURL, settings.Provider)
+	}
+	if !settings.RAGEnabled || settings.Persona != persona {
+		t.Error("gpu profile should leave settings it doesn't name alone")
+	}
+
+	// An invalid profile changes nothing
+	if err := settings.ApplyProfile("broken"); err == nil {
+		t.Error("ApplyProfile(broken) should fail")
+	}
+	if settings.ActiveProfile != "gpu" {
+		t.Errorf("ActiveProfile = %q after a failed apply", settings.ActiveProfile)
+	}
+
+	loaded, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom() error = %v", err)
+	}
+	if loaded.ActiveProfile != "gpu" || loaded.ActiveEndpoint != "gpu" || len(loaded.Endpoints) != 2 {
+		t.Errorf("saved settings = profile %q, endpoint %q, %d endpoints", loaded.ActiveProfile, loaded.ActiveEndpoint, len(loaded.Endpoints))
+	}
+}
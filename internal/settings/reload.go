@@ -0,0 +1,58 @@
+package settings
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// FileStamp identifies a version of the settings file
+type FileStamp struct {
+	ModTime time.Time
+	Size    int64
+}
+
+// stampFile returns the stamp of a file, zero if it doesn't exist
+func stampFile(path string) FileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return FileStamp{}
+	}
+	return FileStamp{ModTime: info.ModTime(), Size: info.Size()}
+}
+
+// Stamp returns the stamp of the settings file when it was last loaded or saved
+func (s *Settings) Stamp() FileStamp {
+	return s.stamp
+}
+
+// LoadIfChanged reads the settings file again if it was edited since it had the given stamp
+// A missing file is treated as unchanged so deleting it doesn't reset the running settings
+func LoadIfChanged(path string, since FileStamp) (*Settings, bool, error) {
+	stamp := stampFile(path)
+	if stamp == since || stamp == (FileStamp{}) {
+		return nil, false, nil
+	}
+
+	fresh, err := load(path)
+	return fresh, true, err
+}
+
+// Reload replaces the settings with ones read from the file again, keeping the
+// flags and environment variables still in effect for this session
+// Settings that fail to load or validate are rejected and nothing changes
+func (s *Settings) Reload(fresh *Settings) error {
+	if problems := fresh.Problems(); len(problems) > 0 {
+		return fmt.Errorf("settings file not reloaded: %v", problems[0])
+	}
+
+	overrides := s.activeOverrides()
+	fresh.path = s.path
+	if overrides != (Overrides{}) {
+		if err := fresh.ApplyOverrides(overrides); err != nil {
+			return err
+		}
+	}
+	*s = *fresh
+	return nil
+}
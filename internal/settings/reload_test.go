@@ -0,0 +1,65 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// editFile rewrites the settings file as another program would, moving its
+// modification time on so the edit is noticed on filesystems with coarse timestamps
+func editFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	settings, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom() error = %v", err)
+	}
+	if err := settings.SetChromaDBURL("http://chroma:8000"); err != nil {
+		t.Fatalf("SetChromaDBURL() error = %v", err)
+	}
+	if err := settings.ApplyOverrides(Overrides{Theme: "dark"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Our own save isn't an external edit
+	if _, changed, _ := LoadIfChanged(path, settings.Stamp()); changed {
+		t.Error("LoadIfChanged() reported a change after Save")
+	}
+
+	editFile(t, path, `{"version": 1, "ragEnabled": true, "chromaDBURL": "http://chroma:9000", "darkMode": false, "ragMode": "vector", "ragTopK": 5}`)
+	fresh, changed, err := LoadIfChanged(path, settings.Stamp())
+	if err != nil || !changed {
+		t.Fatalf("LoadIfChanged() = %v, %v", changed, err)
+	}
+	if err := settings.Reload(fresh); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if !settings.RAGEnabled || settings.ChromaDBURL != "http://chroma:9000" || settings.RAGTopK != 5 {
+		t.Errorf("edited settings not reloaded: %+v", settings)
+	}
+	if !settings.DarkMode {
+		t.Error("the --theme override should survive a reload")
+	}
+
+	// An invalid edit is rejected and the running settings are kept
+	editFile(t, path, `{"version": 1, "chromaDBURL": "chroma:9000", "ragMode": "vector", "ragTopK": 5}`)
+	fresh, _, _ = LoadIfChanged(path, settings.Stamp())
+	if err := settings.Reload(fresh); err == nil {
+		t.Error("Reload() should reject an invalid ChromaDB URL")
+	}
+	if settings.ChromaDBURL != "http://chroma:9000" {
+		t.Errorf("ChromaDBURL = %q after a rejected reload", settings.ChromaDBURL)
+	}
+}
@@ -0,0 +1,223 @@
+package settings
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+
+	"github.com/kevensen/gollama-bubbletea/internal/atomicfile"
+	"github.com/kevensen/gollama-bubbletea/internal/httpclient"
+)
+
+// CurrentVersion is the schema version written by Save
+//
+// Version history:
+//
+//	0 - unversioned, a single server in ollamaURL
+//	1 - named endpoints
+const CurrentVersion = 1
+
+// Values accepted by Validate; they mirror the names in the bot and rag packages,
+// which depend on settings and can't be imported here
+var (
+	validProviders = []string{"ollama", "openai"}
+	validRAGModes  = []string{"vector", "keyword", "hybrid"}
+)
+
+// Problems returns why the settings file couldn't be used when it was loaded,
+// followed by the current validation problems
+func (s *Settings) Problems() []error {
+	return append(slices.Clip(s.problems), s.Validate()...)
+}
+
+// Validate checks URLs and values, returning one error per problem
+func (s *Settings) Validate() []error {
+	var problems []error
+	report := func(field, format string, args ...any) {
+		problems = append(problems, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	if err := validateURL(s.OllamaURL); err != nil {
+		report("ollamaURL", "%v", err)
+	}
+	if err := validateURL(s.ChromaDBURL); err != nil {
+		report("chromaDBURL", "%v", err)
+	}
+	if s.Provider != "" && !slices.Contains(validProviders, s.Provider) {
+		report("provider", "unknown backend %q, use ollama or openai", s.Provider)
+	}
+
+	seen := make(map[string]bool)
+	for i, endpoint := range s.Endpoints {
+		field := fmt.Sprintf("endpoints[%d]", i)
+		if endpoint.Name == "" {
+			report(field+".name", "cannot be empty")
+		} else if seen[endpoint.Name] {
+			report(field+".name", "duplicate endpoint %q", endpoint.Name)
+		}
+		seen[endpoint.Name] = true
+
+		if endpoint.URL == "" {
+			report(field+".url", "cannot be empty")
+		} else if err := validateURL(endpoint.URL); err != nil {
+			report(field+".url", "%v", err)
+		}
+		if endpoint.Provider != "" && !slices.Contains(validProviders, endpoint.Provider) {
+			report(field+".provider", "unknown backend %q, use ollama or openai", endpoint.Provider)
+		}
+		if endpoint.HTTP != nil {
+			if err := httpclient.New(s.EndpointHTTP(endpoint)).Err(); err != nil {
+				report(field+".http", "%v", err)
+			}
+		}
+	}
+	if len(s.Endpoints) > 0 && !seen[s.ActiveEndpoint] {
+		report("activeEndpoint", "no endpoint named %q", s.ActiveEndpoint)
+	}
+
+	profiles := make(map[string]bool)
+	for i, profile := range s.Profiles {
+		field := fmt.Sprintf("profiles[%d]", i)
+		if profile.Name == "" {
+			report(field+".name", "cannot be empty")
+		} else if profiles[profile.Name] {
+			report(field+".name", "duplicate profile %q", profile.Name)
+		}
+		profiles[profile.Name] = true
+
+		if err := s.validateProfile(profile); err != nil {
+			report(field, "%v", err)
+		}
+	}
+	if s.ActiveProfile != "" && !profiles[s.ActiveProfile] {
+		report("activeProfile", "no profile named %q", s.ActiveProfile)
+	}
+
+	// Action names belong to the TUI, which reports unknown ones and conflicts
+	for _, action := range slices.Sorted(maps.Keys(s.Keys)) {
+		if slices.Contains(s.Keys[action], "") {
+			report("keys."+action, "key cannot be empty")
+		}
+	}
+
+	if err := httpclient.New(s.HTTP).Err(); err != nil {
+		report("http", "%v", err)
+	}
+	if s.ChromaDBHTTP != nil {
+		if err := httpclient.New(s.ChromaDBHTTPConfig()).Err(); err != nil {
+			report("chromaDBHTTP", "%v", err)
+		}
+	}
+
+	if s.RAGTopK < 1 {
+		report("ragTopK", "must be at least 1, got %d", s.RAGTopK)
+	}
+	if s.RAGMaxDistance < 0 {
+		report("ragMaxDistance", "must not be negative, got %g", s.RAGMaxDistance)
+	}
+	if !slices.Contains(validRAGModes, s.RAGMode) {
+		report("ragMode", "unknown mode %q, use vector, keyword or hybrid", s.RAGMode)
+	}
+	return problems
+}
+
+// Update applies several changes together and saves settings
+// Nothing changes if change fails, introduces a validation problem or the settings can't be saved
+func (s *Settings) Update(change func(*Settings) error) error {
+	before := s.Validate()
+
+	next := *s
+	next.Endpoints = slices.Clone(s.Endpoints)
+	next.Profiles = slices.Clone(s.Profiles)
+	next.Keys = maps.Clone(s.Keys)
+	next.RAGWatchFolders = slices.Clone(s.RAGWatchFolders)
+	next.RAGWhere = maps.Clone(s.RAGWhere)
+	next.overridden = maps.Clone(s.overridden)
+	if err := change(&next); err != nil {
+		return err
+	}
+
+	// Problems the file already had don't block unrelated changes
+	for _, problem := range next.Validate() {
+		if !slices.ContainsFunc(before, func(err error) bool { return err.Error() == problem.Error() }) {
+			return problem
+		}
+	}
+
+	next.releaseChanged(s)
+	previous := *s
+	*s = next
+	if err := s.Save(); err != nil {
+		*s = previous
+		return err
+	}
+	return nil
+}
+
+// validateURL accepts an empty string or an absolute http or https URL
+func validateURL(raw string) error {
+	if raw == "" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid URL %q: %v", raw, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%q must start with http:// or https://", raw)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%q has no host", raw)
+	}
+	return nil
+}
+
+// migrate upgrades settings read from an older schema version
+func (s *Settings) migrate() {
+	if s.Version < 1 {
+		s.migrateEndpoints()
+	}
+	s.Version = CurrentVersion
+}
+
+// describeJSONError adds the line and column to JSON syntax and type errors
+func describeJSONError(data []byte, err error) error {
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+		if typeErr.Field != "" {
+			err = fmt.Errorf("%s: expected %s, got %s", typeErr.Field, typeErr.Type, typeErr.Value)
+		}
+	default:
+		return err
+	}
+
+	line := bytes.Count(data[:offset], []byte("\n")) + 1
+	column := offset - int64(bytes.LastIndexByte(data[:offset], '\n'))
+	return fmt.Errorf("line %d, column %d: %v", line, column, err)
+}
+
+// backupFile copies the settings file aside before it is overwritten
+func backupFile(path, suffix string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to back up settings: %v", err)
+	}
+	if err := atomicfile.Write(path+"."+suffix+".bak", data); err != nil {
+		return fmt.Errorf("failed to back up settings: %v", err)
+	}
+	return nil
+}
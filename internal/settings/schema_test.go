@@ -0,0 +1,113 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	broken := "{\n  \"lastModel\": \"llama3.2\",\n  \"ragTopK\": \"three\"\n}\n"
+	if err := os.WriteFile(path, []byte(broken), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	settings, err := LoadFrom(path)
+	if err == nil || !strings.Contains(err.Error(), "line 3") || !strings.Contains(err.Error(), "ragTopK") {
+		t.Fatalf("LoadFrom() error = %v, want the line and field of the bad value", err)
+	}
+	if len(settings.Problems()) != 1 {
+		t.Errorf("Problems() = %v", settings.Problems())
+	}
+
+	// The broken file is set aside rather than lost when settings are saved
+	if err := settings.SetDarkMode(true); err != nil {
+		t.Fatalf("SetDarkMode() error = %v", err)
+	}
+	backup, err := os.ReadFile(path + ".invalid.bak")
+	if err != nil || string(backup) != broken {
+		t.Errorf("backup = %q, %v", backup, err)
+	}
+}
+
+func TestLoadMigratesOldVersion(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "settings.json")
+	legacy := `{"lastModel": "llama3.2", "ollamaURL": "http://localhost:11434", "provider": "ollama"}`
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	settings, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom() error = %v", err)
+	}
+	if settings.Version != CurrentVersion || settings.ActiveEndpoint != DefaultEndpointName {
+		t.Errorf("migrated settings = version %d, active %q", settings.Version, settings.ActiveEndpoint)
+	}
+	if settings.RAGTopK != 3 {
+		t.Errorf("RAGTopK = %d, want the default for a missing field", settings.RAGTopK)
+	}
+
+	if err := settings.SetLastModel("qwen2.5"); err != nil {
+		t.Fatalf("SetLastModel() error = %v", err)
+	}
+	backup, err := os.ReadFile(path + ".v0.bak")
+	if err != nil || string(backup) != legacy {
+		t.Errorf("backup = %q, %v", backup, err)
+	}
+
+	// Only the settings file and its backup remain, with no temporary files
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("settings directory has %d entries, want 2", len(entries))
+	}
+}
+
+func TestLoadNewerVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	newer := `{"version": 99, "lastModel": "llama3.2"}`
+	if err := os.WriteFile(path, []byte(newer), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	settings, err := LoadFrom(path)
+	if err != nil || len(settings.Problems()) == 0 {
+		t.Fatalf("LoadFrom() = %v, problems %v", err, settings.Problems())
+	}
+	if err := settings.SetDarkMode(true); err == nil {
+		t.Error("SetDarkMode() should refuse to overwrite a newer settings file")
+	}
+	if data, _ := os.ReadFile(path); string(data) != newer {
+		t.Errorf("settings file was modified: %s", data)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	settings := DefaultSettings()
+	if problems := settings.Validate(); len(problems) != 0 {
+		t.Errorf("Validate() of defaults = %v", problems)
+	}
+
+	settings.OllamaURL = "localhost:11434"
+	settings.ChromaDBURL = "http://"
+	settings.RAGTopK = 0
+	settings.RAGMode = "fuzzy"
+	settings.Endpoints = []Endpoint{
+		{Name: "gpu", URL: "http://gpu:11434", Provider: "ollama"},
+		{Name: "gpu", URL: "http://gpu2:11434", Provider: "bogus"},
+	}
+	settings.ActiveEndpoint = "laptop"
+	settings.Keys = map[string][]string{"quit": {"ctrl+q", ""}, "send": {"ctrl+s"}}
+
+	var fields []string
+	for _, problem := range settings.Validate() {
+		fields = append(fields, strings.SplitN(problem.Error(), ":", 2)[0])
+	}
+	want := []string{"ollamaURL", "chromaDBURL", "endpoints[1].name", "endpoints[1].provider", "activeEndpoint", "keys.quit", "ragTopK", "ragMode"}
+	if strings.Join(fields, " ") != strings.Join(want, " ") {
+		t.Errorf("Validate() reported %v, want %v", fields, want)
+	}
+}
@@ -2,112 +2,457 @@ package settings
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+
+	"github.com/kevensen/gollama-bubbletea/internal/atomicfile"
+	"github.com/kevensen/gollama-bubbletea/internal/httpclient"
 )
 
+// DefaultEndpointName names the endpoint created from a single configured URL
+const DefaultEndpointName = "default"
+
+// Endpoint is a named LLM server
+type Endpoint struct {
+	Name      string             `json:"name"`
+	URL       string             `json:"url"`
+	Provider  string             `json:"provider"`            // ollama or openai
+	LastModel string             `json:"lastModel,omitempty"` // Model last used on this endpoint
+	HTTP      *httpclient.Config `json:"http,omitempty"`      // Overrides the global HTTP options
+}
+
 // Settings holds the application settings
 type Settings struct {
+	Version int `json:"version"` // Schema version, see CurrentVersion
+
 	LastModel   string `json:"lastModel"`
 	RAGEnabled  bool   `json:"ragEnabled"`
 	OllamaURL   string `json:"ollamaURL"`
 	ChromaDBURL string `json:"chromaDBURL"`
 	DarkMode    bool   `json:"darkMode"`
+	Provider    string `json:"provider"` // Backend at OllamaURL: ollama or openai
+
+	// System prompt sent ahead of every conversation
+	Persona string `json:"persona,omitempty"`
+
+	// Named servers; LastModel, OllamaURL and Provider mirror the active one
+	Endpoints      []Endpoint `json:"endpoints,omitempty"`
+	ActiveEndpoint string     `json:"activeEndpoint"`
+
+	// Merge the models of all endpoints into one list
+	AggregateModels    bool   `json:"aggregateModels"`
+	AggregateLastModel string `json:"aggregateLastModel,omitempty"` // Qualified as model@endpoint
+
+	// Named bundles of settings applied together
+	Profiles      []Profile `json:"profiles,omitempty"`
+	ActiveProfile string    `json:"activeProfile,omitempty"`
+
+	// Key bindings replacing the defaults, by action name
+	Keys map[string][]string `json:"keys,omitempty"`
+
+	// Headers, bearer token, TLS and proxy for outbound requests
+	HTTP         httpclient.Config  `json:"http,omitzero"`          // Applies to every server
+	ChromaDBHTTP *httpclient.Config `json:"chromaDBHTTP,omitempty"` // Overrides the global options for ChromaDB
+
+	// RAG retrieval parameters
+	RAGCollection     string         `json:"ragCollection,omitempty"` // Empty for the default collection
+	RAGTopK           int            `json:"ragTopK"`
+	RAGMaxDistance    float64        `json:"ragMaxDistance"`
+	RAGWhere          map[string]any `json:"ragWhere,omitempty"`
+	RAGPromptTemplate string         `json:"ragPromptTemplate"`
+	RAGKeepContext    bool           `json:"ragKeepContext"` // Re-send past retrieval context on later turns
+	RAGMode           string         `json:"ragMode"`        // vector, keyword or hybrid
+	RAGRerank         bool           `json:"ragRerank"`      // Re-rank results with the current model
+	RAGWatchFolders   []string       `json:"ragWatchFolders,omitempty"`
+	RAGWatchEnabled   bool           `json:"ragWatchEnabled"` // Keep watch folders in sync while running
+
+	path       string                 // Settings file, empty for the default location
+	overridden map[overrideField]bool // Settings replaced by flags or environment variables
+	file       *Settings              // Values from the file before overrides were applied
+	problems   []error                // Why the file couldn't be used when it was loaded
+	backup     string                 // Suffix of the backup taken before the next Save replaces the file
+	newer      bool                   // The file has a newer schema and must not be overwritten
+	overrides  Overrides              // Flags and environment variables applied for this session
+	stamp      FileStamp              // The file as last loaded or saved, to notice external edits
 }
 
 // Default settings
 func DefaultSettings() *Settings {
 	return &Settings{
+		Version: CurrentVersion,
+
 		LastModel:   "",
 		RAGEnabled:  false,
 		OllamaURL:   "", // No default URL - user must configure
 		ChromaDBURL: "", // No default ChromaDB URL - user must configure
 		DarkMode:    false,
+		Provider:    "ollama",
+
+		Persona: "",
+
+		Endpoints:      nil,
+		ActiveEndpoint: "",
+
+		AggregateModels:    false,
+		AggregateLastModel: "",
+
+		Profiles:      nil,
+		ActiveProfile: "",
+
+		Keys: nil,
+
+		RAGCollection:     "",
+		RAGTopK:           3,
+		RAGMaxDistance:    0, // No distance threshold
+		RAGWhere:          nil,
+		RAGPromptTemplate: "", // Empty uses the built-in template
+		RAGKeepContext:    false,
+		RAGMode:           "vector",
+		RAGRerank:         false,
+		RAGWatchFolders:   nil,
+		RAGWatchEnabled:   false,
 	}
 }
 
 // getSettingsPath returns the path to the settings file
 func getSettingsPath() (string, error) {
-	homeDir, err := os.UserHomeDir()
+	paths, err := ResolvePaths()
 	if err != nil {
 		return "", err
 	}
 
-	// Create config directory if it doesn't exist
-	configDir := filepath.Join(homeDir, ".config", "gollama")
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return "", err
-	}
-
-	return filepath.Join(configDir, "settings.json"), nil
+	return paths.SettingsFile(), nil
 }
 
 // Load reads settings from the settings file
 func Load() (*Settings, error) {
-	settingsPath, err := getSettingsPath()
-	if err != nil {
-		return DefaultSettings(), err
+	return LoadFrom("")
+}
+
+// LoadFrom reads settings from path, or from the default location when path is empty
+// Settings loaded from a custom path are saved back to it
+func LoadFrom(path string) (*Settings, error) {
+	settingsPath := path
+	if settingsPath == "" {
+		defaultPath, err := getSettingsPath()
+		if err != nil {
+			return DefaultSettings(), err
+		}
+		settingsPath = defaultPath
+	}
+
+	settings, err := load(settingsPath)
+	settings.path = path
+	return settings, err
+}
+
+// Path returns the settings file
+func (s *Settings) Path() (string, error) {
+	if s.path != "" {
+		return s.path, nil
 	}
+	return getSettingsPath()
+}
 
+func load(settingsPath string) (*Settings, error) {
 	// If file doesn't exist, return default settings
 	if _, err := os.Stat(settingsPath); os.IsNotExist(err) {
 		return DefaultSettings(), nil
 	}
 
+	stamp := stampFile(settingsPath)
 	data, err := os.ReadFile(settingsPath)
 	if err != nil {
 		return DefaultSettings(), err
 	}
 
-	settings := &Settings{}
+	// Fields missing from the file keep their defaults
+	settings := DefaultSettings()
+	settings.Version = 0
 	if err := json.Unmarshal(data, settings); err != nil {
-		return DefaultSettings(), err
+		// Keep the unreadable file until the user changes a setting, then set it aside
+		settings = DefaultSettings()
+		settings.backup = "invalid"
+		err = fmt.Errorf("invalid settings file %s: %v", settingsPath, describeJSONError(data, err))
+		settings.problems = []error{err}
+		settings.stamp = stamp
+		return settings, err
+	}
+	settings.stamp = stamp
+
+	if settings.Version > CurrentVersion {
+		settings.newer = true
+		settings.problems = []error{fmt.Errorf("settings file version %d is newer than supported version %d, changes will not be saved", settings.Version, CurrentVersion)}
+	} else if settings.Version < CurrentVersion {
+		settings.backup = fmt.Sprintf("v%d", settings.Version)
+		settings.migrate()
 	}
 
 	return settings, nil
 }
 
 // Save writes settings to the settings file
+// Values overridden for this session are saved with their values from the file
 func (s *Settings) Save() error {
-	settingsPath, err := getSettingsPath()
+	if s.newer {
+		return fmt.Errorf("settings were written by a newer version of gollama and will not be overwritten")
+	}
+
+	settingsPath, err := s.Path()
 	if err != nil {
 		return err
 	}
+	if err := os.MkdirAll(filepath.Dir(settingsPath), 0700); err != nil {
+		return err
+	}
 
-	data, err := json.MarshalIndent(s, "", "  ")
+	data, err := json.MarshalIndent(s.persisted(), "", "  ")
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(settingsPath, data, 0644)
+	// Keep the file as it was before migrating or replacing it
+	if s.backup != "" {
+		if err := backupFile(settingsPath, s.backup); err != nil {
+			return err
+		}
+		s.backup = ""
+	}
+
+	if err := atomicfile.Write(settingsPath, data); err != nil {
+		return err
+	}
+	s.stamp = stampFile(settingsPath)
+	return nil
 }
 
-// SetLastModel updates the last model and saves settings
+// SetLastModel updates the last model, remembering it for the active endpoint, and saves settings
 func (s *Settings) SetLastModel(model string) error {
+	s.release(overrideModel)
 	s.LastModel = model
+	if i := s.endpointIndex(s.ActiveEndpoint); i >= 0 {
+		s.Endpoints[i].LastModel = model
+	}
 	return s.Save()
 }
 
 // SetRAGEnabled updates the RAG enabled state and saves settings
 func (s *Settings) SetRAGEnabled(enabled bool) error {
+	s.release(overrideRAG)
 	s.RAGEnabled = enabled
 	return s.Save()
 }
 
-// SetOllamaURL updates the Ollama URL and saves settings
+// SetOllamaURL updates the URL of the active endpoint and saves settings
+// The default endpoint is created if none is configured yet
 func (s *Settings) SetOllamaURL(url string) error {
+	s.release(overrideURL)
 	s.OllamaURL = url
+	if i := s.endpointIndex(s.ActiveEndpoint); i >= 0 {
+		s.Endpoints[i].URL = url
+	} else {
+		s.migrateEndpoints()
+	}
+	return s.Save()
+}
+
+// SetProvider updates the LLM backend type of the active endpoint and saves settings
+func (s *Settings) SetProvider(provider string) error {
+	s.Provider = provider
+	if i := s.endpointIndex(s.ActiveEndpoint); i >= 0 {
+		s.Endpoints[i].Provider = provider
+	}
+	return s.Save()
+}
+
+// EndpointHTTP returns the HTTP options for an endpoint, merging its overrides
+// into the global options
+func (s *Settings) EndpointHTTP(endpoint Endpoint) httpclient.Config {
+	return s.HTTP.Merge(endpoint.HTTP)
+}
+
+// ChromaDBHTTPConfig returns the HTTP options for ChromaDB
+func (s *Settings) ChromaDBHTTPConfig() httpclient.Config {
+	return s.HTTP.Merge(s.ChromaDBHTTP)
+}
+
+// SetAggregateModels updates whether models of all endpoints are merged and saves settings
+func (s *Settings) SetAggregateModels(enabled bool) error {
+	s.AggregateModels = enabled
+	return s.Save()
+}
+
+// SetAggregateLastModel records the model chosen from the merged list, remembering it
+// as the last model of the endpoint that serves it, and saves settings
+func (s *Settings) SetAggregateLastModel(model, endpoint, qualified string) error {
+	s.release(overrideModel)
+	s.AggregateLastModel = qualified
+	if i := s.endpointIndex(endpoint); i >= 0 {
+		s.Endpoints[i].LastModel = model
+		if endpoint == s.ActiveEndpoint {
+			s.LastModel = model
+		}
+	}
 	return s.Save()
 }
 
+// Endpoint returns the named endpoint
+func (s *Settings) Endpoint(name string) (Endpoint, bool) {
+	if i := s.endpointIndex(name); i >= 0 {
+		return s.Endpoints[i], true
+	}
+	return Endpoint{}, false
+}
+
+// SetEndpoint adds an endpoint or replaces the one with the same name and saves settings
+// Changes to the active endpoint are mirrored into OllamaURL and Provider
+func (s *Settings) SetEndpoint(endpoint Endpoint) error {
+	if endpoint.Name == "" {
+		return fmt.Errorf("endpoint name cannot be empty")
+	}
+	if endpoint.URL == "" {
+		return fmt.Errorf("endpoint URL cannot be empty")
+	}
+
+	if i := s.endpointIndex(endpoint.Name); i >= 0 {
+		s.Endpoints[i] = endpoint
+	} else {
+		s.Endpoints = append(s.Endpoints, endpoint)
+	}
+	if endpoint.Name == s.ActiveEndpoint {
+		s.release(overrideURL)
+		s.OllamaURL = endpoint.URL
+		s.Provider = endpoint.Provider
+	}
+	return s.Save()
+}
+
+// RemoveEndpoint deletes an endpoint other than the active one and saves settings
+func (s *Settings) RemoveEndpoint(name string) error {
+	i := s.endpointIndex(name)
+	if i < 0 {
+		return fmt.Errorf("endpoint not found: %s", name)
+	}
+	if name == s.ActiveEndpoint {
+		return fmt.Errorf("cannot remove the active endpoint")
+	}
+
+	s.Endpoints = append(s.Endpoints[:i], s.Endpoints[i+1:]...)
+	return s.Save()
+}
+
+// UseEndpoint makes an endpoint active, restoring its URL, backend and last model, and saves settings
+func (s *Settings) UseEndpoint(name string) error {
+	if err := s.useEndpoint(name); err != nil {
+		return err
+	}
+	return s.Save()
+}
+
+func (s *Settings) useEndpoint(name string) error {
+	endpoint, ok := s.Endpoint(name)
+	if !ok {
+		return fmt.Errorf("endpoint not found: %s", name)
+	}
+
+	s.release(overrideURL)
+	s.release(overrideModel)
+	s.ActiveEndpoint = name
+	s.OllamaURL = endpoint.URL
+	s.Provider = endpoint.Provider
+	s.LastModel = endpoint.LastModel
+	return nil
+}
+
+func (s *Settings) endpointIndex(name string) int {
+	for i, endpoint := range s.Endpoints {
+		if endpoint.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// migrateEndpoints turns a single configured URL from older settings into the default endpoint
+func (s *Settings) migrateEndpoints() {
+	if len(s.Endpoints) > 0 || s.OllamaURL == "" {
+		return
+	}
+
+	s.Endpoints = []Endpoint{{
+		Name:      DefaultEndpointName,
+		URL:       s.OllamaURL,
+		Provider:  s.Provider,
+		LastModel: s.LastModel,
+	}}
+	s.ActiveEndpoint = DefaultEndpointName
+}
+
 // SetDarkMode updates the dark mode state and saves settings
 func (s *Settings) SetDarkMode(enabled bool) error {
+	s.release(overrideTheme)
 	s.DarkMode = enabled
 	return s.Save()
 }
 
 // SetChromaDBURL updates the ChromaDB URL and saves settings
 func (s *Settings) SetChromaDBURL(url string) error {
+	s.release(overrideChromaURL)
 	s.ChromaDBURL = url
 	return s.Save()
 }
+
+// SetRAGTopK updates the number of documents retrieved for RAG and saves settings
+func (s *Settings) SetRAGTopK(topK int) error {
+	s.RAGTopK = topK
+	return s.Save()
+}
+
+// SetRAGMaxDistance updates the RAG distance threshold and saves settings
+func (s *Settings) SetRAGMaxDistance(distance float64) error {
+	s.RAGMaxDistance = distance
+	return s.Save()
+}
+
+// SetRAGWhere updates the RAG metadata filter and saves settings
+func (s *Settings) SetRAGWhere(where map[string]any) error {
+	s.RAGWhere = where
+	return s.Save()
+}
+
+// SetRAGPromptTemplate updates the RAG prompt template and saves settings
+func (s *Settings) SetRAGPromptTemplate(template string) error {
+	s.RAGPromptTemplate = template
+	return s.Save()
+}
+
+// SetRAGKeepContext updates whether past retrieval context is re-sent and saves settings
+func (s *Settings) SetRAGKeepContext(keep bool) error {
+	s.RAGKeepContext = keep
+	return s.Save()
+}
+
+// SetRAGMode updates the RAG retrieval mode and saves settings
+func (s *Settings) SetRAGMode(mode string) error {
+	s.RAGMode = mode
+	return s.Save()
+}
+
+// SetRAGRerank updates whether RAG results are re-ranked and saves settings
+func (s *Settings) SetRAGRerank(enabled bool) error {
+	s.RAGRerank = enabled
+	return s.Save()
+}
+
+// SetRAGWatchFolders updates the folders ingested into the knowledge base and saves settings
+func (s *Settings) SetRAGWatchFolders(folders []string) error {
+	s.RAGWatchFolders = folders
+	return s.Save()
+}
+
+// SetRAGWatchEnabled updates whether watch folders are kept in sync and saves settings
+func (s *Settings) SetRAGWatchEnabled(enabled bool) error {
+	s.RAGWatchEnabled = enabled
+	return s.Save()
+}
@@ -93,3 +93,108 @@ func TestSettingsLoadNonExistent(t *testing.T) {
 		t.Errorf("Expected RAGEnabled to be false, got %t", settings.RAGEnabled)
 	}
 }
+
+func TestSettingsRAGOptions(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir := t.TempDir()
+
+	// Override the home directory for testing
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	settings := DefaultSettings()
+	if settings.RAGTopK != 3 {
+		t.Errorf("Expected default RAGTopK to be 3, got %d", settings.RAGTopK)
+	}
+
+	if err := settings.SetRAGTopK(5); err != nil {
+		t.Fatalf("Failed to set RAG top K: %v", err)
+	}
+	if err := settings.SetRAGMaxDistance(0.75); err != nil {
+		t.Fatalf("Failed to set RAG max distance: %v", err)
+	}
+	if err := settings.SetRAGWhere(map[string]any{"source": "docs"}); err != nil {
+		t.Fatalf("Failed to set RAG where filter: %v", err)
+	}
+	if err := settings.SetRAGPromptTemplate("{{context}}\n{{question}}"); err != nil {
+		t.Fatalf("Failed to set RAG prompt template: %v", err)
+	}
+
+	loadedSettings, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load settings: %v", err)
+	}
+
+	if loadedSettings.RAGTopK != 5 {
+		t.Errorf("Expected RAGTopK to be 5, got %d", loadedSettings.RAGTopK)
+	}
+	if loadedSettings.RAGMaxDistance != 0.75 {
+		t.Errorf("Expected RAGMaxDistance to be 0.75, got %f", loadedSettings.RAGMaxDistance)
+	}
+	if loadedSettings.RAGWhere["source"] != "docs" {
+		t.Errorf("Expected RAGWhere source to be 'docs', got %v", loadedSettings.RAGWhere["source"])
+	}
+	if loadedSettings.RAGPromptTemplate != "{{context}}\n{{question}}" {
+		t.Errorf("Unexpected RAGPromptTemplate %q", loadedSettings.RAGPromptTemplate)
+	}
+}
+
+func TestSettingsEndpoints(t *testing.T) {
+	tempDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tempDir)
+	defer os.Setenv("HOME", originalHome)
+
+	// A single URL from older settings becomes the default endpoint
+	old := DefaultSettings()
+	old.Version = 0
+	old.OllamaURL = "http://localhost:11434"
+	old.LastModel = "llama3.2"
+	if err := old.Save(); err != nil {
+		t.Fatalf("Failed to save settings: %v", err)
+	}
+
+	settings, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load settings: %v", err)
+	}
+	if len(settings.Endpoints) != 1 || settings.ActiveEndpoint != DefaultEndpointName {
+		t.Fatalf("Expected migrated default endpoint, got %+v active %q", settings.Endpoints, settings.ActiveEndpoint)
+	}
+
+	workstation := Endpoint{Name: "workstation", URL: "http://gpu:11434", Provider: "ollama", LastModel: "llama3.3:70b"}
+	if err := settings.SetEndpoint(workstation); err != nil {
+		t.Fatalf("Failed to add endpoint: %v", err)
+	}
+
+	// Switching endpoints restores each endpoint's last model
+	if err := settings.UseEndpoint("workstation"); err != nil {
+		t.Fatalf("Failed to use endpoint: %v", err)
+	}
+	if settings.OllamaURL != "http://gpu:11434" || settings.LastModel != "llama3.3:70b" {
+		t.Errorf("Expected workstation URL and model, got %s %s", settings.OllamaURL, settings.LastModel)
+	}
+	settings.SetLastModel("qwen2.5:32b")
+
+	settings.UseEndpoint(DefaultEndpointName)
+	if settings.LastModel != "llama3.2" {
+		t.Errorf("Expected default endpoint model llama3.2, got %s", settings.LastModel)
+	}
+
+	if err := settings.RemoveEndpoint(DefaultEndpointName); err == nil {
+		t.Error("Expected an error removing the active endpoint")
+	}
+
+	loaded, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load settings: %v", err)
+	}
+	if endpoint, ok := loaded.Endpoint("workstation"); !ok || endpoint.LastModel != "qwen2.5:32b" {
+		t.Errorf("Expected workstation to remember qwen2.5:32b, got %+v", endpoint)
+	}
+
+	if err := loaded.RemoveEndpoint("workstation"); err != nil || len(loaded.Endpoints) != 1 {
+		t.Errorf("Failed to remove endpoint: %v", err)
+	}
+}
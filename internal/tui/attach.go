@@ -0,0 +1,147 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/kevensen/gollama-bubbletea/internal/bot/attachments"
+)
+
+// attach adds files and images to those sent with the next message, keeping the
+// files within MaxTotalSize
+func (m *model) attach(files []attachments.File, images []attachments.Image) error {
+	var added []attachments.File
+	for _, file := range files {
+		if !slices.ContainsFunc(m.attachments, func(f attachments.File) bool { return f.Path == file.Path }) {
+			added = append(added, file)
+		}
+	}
+	if total := attachments.TotalSize(m.attachments) + attachments.TotalSize(added); total > attachments.MaxTotalSize {
+		return fmt.Errorf("attachments would total %s, over the %s limit",
+			attachments.FormatSize(total), attachments.FormatSize(attachments.MaxTotalSize))
+	}
+	m.attachments = append(m.attachments, added...)
+	for _, image := range images {
+		if !slices.ContainsFunc(m.images, func(i attachments.Image) bool { return i.Path == image.Path }) {
+			m.images = append(m.images, image)
+		}
+	}
+	m.resizeInput()
+	return nil
+}
+
+// clearAttachments drops the files and images attached to the next message
+func (m *model) clearAttachments() {
+	m.attachments = nil
+	m.images = nil
+	m.resizeInput()
+}
+
+// readAttachments reads a file to attach, as an image if its extension names one
+func readAttachments(path string) ([]attachments.File, []attachments.Image, error) {
+	if attachments.IsImage(path) {
+		image, err := attachments.ReadImage(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, []attachments.Image{image}, nil
+	}
+	file, err := attachments.Read(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return []attachments.File{file}, nil, nil
+}
+
+// mentionedFiles reads the files and images named with @path in a message,
+// leaving words such as @someone alone when no such file exists
+func mentionedFiles(input string) ([]attachments.File, []attachments.Image, error) {
+	var files []attachments.File
+	var images []attachments.Image
+	for _, word := range strings.Fields(input) {
+		path, ok := strings.CutPrefix(word, "@")
+		if !ok || path == "" {
+			continue
+		}
+		if _, err := os.Stat(attachments.ExpandHome(path)); err != nil {
+			continue
+		}
+		file, image, err := readAttachments(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		files, images = append(files, file...), append(images, image...)
+	}
+	return files, images, nil
+}
+
+// visionWarning warns when images are attached but the current model's families
+// show it can't take them, empty when it can or that isn't known
+func (m *model) visionWarning() string {
+	if len(m.images) == 0 || m.bot.ModelManager == nil {
+		return ""
+	}
+	current := m.bot.ModelManager.CurrentModel()
+	info, err := m.bot.ModelManager.ModelInfo(current)
+	if err != nil {
+		return ""
+	}
+	if vision, known := info.SupportsVision(); known && !vision {
+		return fmt.Sprintf("%s may not support images - try a vision model such as llava or llama3.2-vision", current)
+	}
+	return ""
+}
+
+// attachmentChips renders the files attached to the next message, shown above the input
+func (m *model) attachmentChips() string {
+	if len(m.attachments) == 0 && len(m.images) == 0 {
+		return ""
+	}
+	chip := lipgloss.NewStyle().Foreground(lipgloss.Color("62")).Padding(0, 1)
+	if m.darkMode {
+		chip = chip.Foreground(darkModeAccentColor)
+	}
+	var chips []string
+	for _, file := range m.attachments {
+		chips = append(chips, chip.Render("📎 "+file.Path+" ("+attachments.FormatSize(file.Size())+")"))
+	}
+	for _, image := range m.images {
+		chips = append(chips, chip.Render(image.Placeholder()))
+	}
+	return lipgloss.NewStyle().MaxWidth(m.viewport.Width).Render(strings.Join(chips, " ")) + "\n"
+}
+
+func (m *model) attachCommand(args []string) tea.Cmd {
+	files, images, err := readAttachments(args[0])
+	if err == nil {
+		err = m.attach(files, images)
+	}
+	if err != nil {
+		m.inputError = err.Error()
+	} else if len(images) > 0 {
+		m.inputError = m.visionWarning()
+	}
+	return nil
+}
+
+func (m *model) detachCommand(args []string) tea.Cmd {
+	if len(args) == 0 {
+		m.clearAttachments()
+		return nil
+	}
+	if i := slices.IndexFunc(m.attachments, func(f attachments.File) bool { return f.Path == args[0] }); i >= 0 {
+		m.attachments = slices.Delete(m.attachments, i, i+1)
+	} else if i := slices.IndexFunc(m.images, func(i attachments.Image) bool { return i.Path == args[0] }); i >= 0 {
+		m.images = slices.Delete(m.images, i, i+1)
+	} else {
+		m.inputError = "No attachment named " + args[0]
+		return nil
+	}
+	m.resizeInput()
+	return nil
+}
@@ -0,0 +1,243 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kevensen/gollama-bubbletea/internal/bot"
+	"github.com/kevensen/gollama-bubbletea/internal/tui/commands"
+)
+
+// String returns the name commands use for the tab
+func (t tab) String() string {
+	return [...]string{"chat", "models", "rag", "settings"}[t]
+}
+
+// slashCommands holds every command typed in the input field or chosen in the command palette
+var slashCommands *commands.Registry[*model]
+
+// Commands refer to the help text listing them, so the registry is built once the package is initialized
+func init() {
+	slashCommands = newCommandRegistry()
+}
+
+func newCommandRegistry() *commands.Registry[*model] {
+	modelArg := commands.Arg[*model]{Name: "name", Complete: func(m *model, prefix string) []string {
+		return commands.FilterPrefix(m.models, prefix)
+	}}
+	profileArg := commands.Arg[*model]{Name: "name", Optional: true, Complete: func(m *model, prefix string) []string {
+		return commands.FilterPrefix(m.settings.ProfileNames(), prefix)
+	}}
+	pathArg := commands.Arg[*model]{Name: "path", Rest: true, Complete: func(_ *model, prefix string) []string {
+		return commands.CompletePath(prefix)
+	}}
+	attachedArg := commands.Arg[*model]{Name: "path", Optional: true, Rest: true, Complete: func(m *model, prefix string) []string {
+		var paths []string
+		for _, file := range m.attachments {
+			paths = append(paths, file.Path)
+		}
+		for _, image := range m.images {
+			paths = append(paths, image.Path)
+		}
+		return commands.FilterPrefix(paths, prefix)
+	}}
+
+	queryArg := commands.Arg[*model]{Name: "query", Optional: true, Rest: true}
+
+	registry := commands.NewRegistry[*model]()
+	for _, command := range []commands.Command[*model]{
+		{Name: "clear", Desc: "clear chat history", Tabs: []string{chatTab.String()}, Run: (*model).clearCommand},
+		{Name: "chat", Desc: "switch to chat tab", Run: (*model).chatCommand},
+		{Name: "models", Desc: "switch to models tab", Run: (*model).modelsCommand},
+		{Name: "rag", Desc: "switch to RAG tab", Run: (*model).ragCommand},
+		{Name: "settings", Desc: "switch to settings tab", Run: (*model).settingsCommand},
+		{Name: "attach", Args: []commands.Arg[*model]{pathArg}, Desc: "attach a file to the next message", Tabs: []string{chatTab.String()}, Run: (*model).attachCommand},
+		{Name: "detach", Args: []commands.Arg[*model]{attachedArg}, Desc: "remove an attachment, or all of them", Tabs: []string{chatTab.String()}, Run: (*model).detachCommand},
+		{Name: "find", Args: []commands.Arg[*model]{queryArg}, Desc: "search the conversation", Tabs: []string{chatTab.String()}, Run: (*model).findCommand},
+		{Name: "sessions", Args: []commands.Arg[*model]{queryArg}, Desc: "search saved sessions", Run: (*model).sessionsCommand},
+		{Name: "dark", Desc: "toggle dark mode", Run: (*model).darkCommand},
+		{Name: "model", Args: []commands.Arg[*model]{modelArg}, Desc: "switch to a model", Run: (*model).modelCommand},
+		{Name: "profile", Args: []commands.Arg[*model]{profileArg}, Desc: "apply a settings profile", Run: (*model).profileCommand},
+		{Name: "exit", Aliases: []string{"quit"}, Desc: "quit application", Run: func(m *model, _ []string) tea.Cmd { return m.quit() }},
+	} {
+		if err := registry.Register(command); err != nil {
+			panic(err)
+		}
+	}
+	return registry
+}
+
+// commandHelpLines renders the slash commands as a bulleted list
+func commandHelpLines() []string {
+	var lines []string
+	for _, command := range slashCommands.Commands() {
+		lines = append(lines, fmt.Sprintf("  • %s - %s", command.Usage(), command.Desc))
+	}
+	return lines
+}
+
+// runCommand runs a slash command typed in the input field or chosen in the command palette
+func (m *model) runCommand(input string) tea.Cmd {
+	// Clear any existing error when processing a command
+	m.inputError = ""
+
+	cmd, err := slashCommands.Run(m, m.activeTab.String(), input)
+	if err != nil {
+		m.inputError = err.Error()
+	}
+	return cmd
+}
+
+// commandCompletions returns the lines the slash command being typed could complete to
+func (m *model) commandCompletions() []string {
+	if m.focus != focusTextarea || m.editingField != "" {
+		return nil
+	}
+	return slashCommands.Complete(m, m.activeTab.String(), m.textarea.Value())
+}
+
+// completeCommand extends the slash command being typed as far as its completions
+// agree, reporting whether there was anything to complete
+func (m *model) completeCommand() bool {
+	completions := m.commandCompletions()
+	if len(completions) == 0 {
+		return false
+	}
+	if prefix := commands.CommonPrefix(completions); len(prefix) > len(m.textarea.Value()) {
+		m.textarea.SetValue(prefix)
+		m.textarea.CursorEnd()
+	}
+	return true
+}
+
+// completionHint lists the completions of the slash command being typed, shown above the input
+func (m *model) completionHint() string {
+	completions := m.commandCompletions()
+	if len(completions) == 0 || slices.Equal(completions, []string{m.textarea.Value()}) {
+		return ""
+	}
+
+	// Show only the part being completed, such as model names after /model
+	typed := m.textarea.Value()
+	start := strings.LastIndex(typed, " ") + 1
+	const limit = 8
+	var shown []string
+	for _, completion := range completions[:min(len(completions), limit)] {
+		shown = append(shown, strings.TrimSpace(completion[start:]))
+	}
+	hint := strings.Join(shown, "  ")
+	if len(completions) > limit {
+		hint += fmt.Sprintf("  (+%d more)", len(completions)-limit)
+	}
+	return hint + "  - " + formatKeys(m.keys.NextTab.Keys()) + " to complete"
+}
+
+func (m *model) clearCommand([]string) tea.Cmd {
+	if m.isThinking {
+		// The reply being received still reads the conversation
+		m.inputError = "Wait for the reply, or press " + formatKeys(m.keys.Back.Keys()) + " to stop it, before clearing the chat"
+		return nil
+	}
+	m.bot.ClearMessages()
+	m.selectedMessage = -1
+	m.session = nil // The next message starts a new session
+	m.viewport.SetContent(m.wrapChat(welcomeText(m.keys)))
+	// Update tab names to reflect cleared tokens (should be 0 now)
+	m.updateTabNames()
+	return nil
+}
+
+func (m *model) chatCommand([]string) tea.Cmd {
+	if !m.connectionValid {
+		m.inputError = "Please configure Ollama URL in Settings tab first"
+		return nil
+	}
+	if m.bot.ModelManager == nil {
+		m.inputError = "No models available. Please pull models using 'ollama pull <model-name>'"
+		return nil
+	}
+	m.activeTab = chatTab
+	return nil
+}
+
+func (m *model) modelsCommand([]string) tea.Cmd {
+	if !m.connectionValid {
+		m.inputError = "Please configure Ollama URL in Settings tab first"
+		return nil
+	}
+	m.activeTab = modelsTab
+	// Fetch models when switching via command
+	if m.bot.ModelManager != nil {
+		m.models = m.bot.ModelManager.ModelNames()
+	}
+	m.updateTabNames()
+	m.updateModelsViewportContent()
+	return nil
+}
+
+func (m *model) ragCommand([]string) tea.Cmd {
+	if !m.connectionValid {
+		m.inputError = "Please configure Ollama URL in Settings tab first"
+		return nil
+	}
+	m.activeTab = ragTab
+	return nil
+}
+
+func (m *model) settingsCommand([]string) tea.Cmd {
+	m.activeTab = settingsTab
+	return nil
+}
+
+func (m *model) darkCommand([]string) tea.Cmd {
+	m.darkMode = !m.darkMode
+	m.settings.SetDarkMode(m.darkMode)
+	m.applyTheme() // Apply the new theme
+	return nil
+}
+
+func (m *model) modelCommand(args []string) tea.Cmd {
+	if m.bot.ModelManager == nil {
+		m.inputError = "No models available. Please pull models using 'ollama pull <model-name>'"
+		return nil
+	}
+	if err := m.useModel(args[0]); err != nil {
+		m.inputError = err.Error()
+	}
+	return nil
+}
+
+func (m *model) profileCommand(args []string) tea.Cmd {
+	if len(args) == 0 {
+		if len(m.settings.Profiles) == 0 {
+			m.inputError = "No profiles configured - add them to the settings file"
+		} else {
+			m.inputError = "Usage: /profile <name> - profiles: " + strings.Join(m.settings.ProfileNames(), ", ")
+		}
+		return nil
+	}
+	return m.applyProfile(args[0])
+}
+
+// useModel switches the chat to a model and remembers it in settings
+// The bot must have a model manager
+func (m *model) useModel(name string) error {
+	if err := m.bot.ModelManager.UseModel(name); err != nil {
+		return err
+	}
+	if bot.Aggregating(m.settings) {
+		model, endpoint := bot.SplitModel(name)
+		m.settings.SetAggregateLastModel(model, endpoint, name)
+	} else {
+		m.settings.SetLastModel(name)
+	}
+	if i := slices.Index(m.models, name); i >= 0 {
+		m.selectedModel = i
+	}
+	m.updateTabNames()
+	m.updateModelsViewportContent()
+	return nil
+}
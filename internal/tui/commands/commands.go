@@ -0,0 +1,277 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Arg describes an argument of a command, run against a target of type T
+type Arg[T any] struct {
+	Name     string                                 // Shown in usage, such as name for <name>
+	Optional bool                                   // May be left out; only the last arguments can be optional
+	Rest     bool                                   // Takes the rest of the line, spaces included; only the last argument
+	Complete func(target T, prefix string) []string // Values starting with prefix, nil if values can't be listed
+}
+
+// Command is a slash command run against a target of type T, such as the TUI model
+type Command[T any] struct {
+	Name    string   // Typed after the slash
+	Aliases []string // Other names typed after the slash
+	Args    []Arg[T]
+	Desc    string
+	Tabs    []string                              // Tabs the command works on, empty for every tab
+	Run     func(target T, args []string) tea.Cmd // Called with one value per argument given
+}
+
+// Usage returns how the command is typed, such as /exit or /quit, or /model <name>
+func (c Command[T]) Usage() string {
+	var names []string
+	for _, name := range append([]string{c.Name}, c.Aliases...) {
+		names = append(names, "/"+name)
+	}
+	usage := strings.Join(names, " or ")
+	for _, arg := range c.Args {
+		if arg.Optional {
+			usage += " [" + arg.Name + "]"
+		} else {
+			usage += " <" + arg.Name + ">"
+		}
+	}
+	return usage
+}
+
+// AvailableOn reports whether the command works on a tab
+func (c Command[T]) AvailableOn(tab string) bool {
+	return len(c.Tabs) == 0 || slices.Contains(c.Tabs, tab)
+}
+
+// required returns how many arguments must be given
+func (c Command[T]) required() int {
+	n := 0
+	for _, arg := range c.Args {
+		if !arg.Optional {
+			n++
+		}
+	}
+	return n
+}
+
+// Registry holds the commands the TUI understands, in the order they were registered
+type Registry[T any] struct {
+	commands []Command[T]
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry[T any]() *Registry[T] {
+	return &Registry[T]{}
+}
+
+// Register adds a command, failing if its name or an alias is already taken
+func (r *Registry[T]) Register(command Command[T]) error {
+	for _, name := range append([]string{command.Name}, command.Aliases...) {
+		if _, ok := r.Find(name); ok {
+			return fmt.Errorf("command /%s is already registered", name)
+		}
+	}
+	for i, arg := range command.Args {
+		last := i == len(command.Args)-1
+		if arg.Rest && !last {
+			return fmt.Errorf("command /%s: only the last argument can take the rest of the line", command.Name)
+		}
+		if arg.Optional && !last && !command.Args[i+1].Optional {
+			return fmt.Errorf("command /%s: optional argument %s must come last", command.Name, arg.Name)
+		}
+	}
+	r.commands = append(r.commands, command)
+	return nil
+}
+
+// Commands returns the registered commands
+func (r *Registry[T]) Commands() []Command[T] {
+	return slices.Clone(r.commands)
+}
+
+// Find returns the command with a name or alias, given without the slash
+func (r *Registry[T]) Find(name string) (Command[T], bool) {
+	for _, command := range r.commands {
+		if command.Name == name || slices.Contains(command.Aliases, name) {
+			return command, true
+		}
+	}
+	return Command[T]{}, false
+}
+
+// Parse splits a typed line into its command and arguments
+func (r *Registry[T]) Parse(line string) (Command[T], []string, error) {
+	name, rest, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(line), "/"), " ")
+	command, ok := r.Find(name)
+	if !ok {
+		return Command[T]{}, nil, fmt.Errorf("invalid command: %s", strings.TrimSpace(line))
+	}
+
+	var args []string
+	rest = strings.TrimSpace(rest)
+	for _, arg := range command.Args {
+		if rest == "" {
+			break
+		}
+		if arg.Rest {
+			args, rest = append(args, rest), ""
+			break
+		}
+		var value string
+		value, rest, _ = strings.Cut(rest, " ")
+		args, rest = append(args, value), strings.TrimSpace(rest)
+	}
+	if rest != "" || len(args) < command.required() {
+		return command, nil, fmt.Errorf("usage: %s", command.Usage())
+	}
+	return command, args, nil
+}
+
+// Run parses a typed line and runs its command on the given tab
+func (r *Registry[T]) Run(target T, tab, line string) (tea.Cmd, error) {
+	command, args, err := r.Parse(line)
+	if err != nil {
+		return nil, err
+	}
+	if !command.AvailableOn(tab) {
+		return nil, fmt.Errorf("/%s is only available on the %s tab", command.Name, strings.Join(command.Tabs, " or "))
+	}
+	return command.Run(target, args), nil
+}
+
+// Complete returns the lines that typing could lead to from line: command names
+// while the name is typed, then values of the argument being typed
+// Commands not available on tab aren't offered
+func (r *Registry[T]) Complete(target T, tab, line string) []string {
+	if !strings.HasPrefix(line, "/") {
+		return nil
+	}
+
+	name, rest, typingArgs := strings.Cut(line[1:], " ")
+	if !typingArgs {
+		var lines []string
+		for _, command := range r.commands {
+			if !command.AvailableOn(tab) {
+				continue
+			}
+			for _, candidate := range append([]string{command.Name}, command.Aliases...) {
+				if strings.HasPrefix(candidate, name) {
+					if len(command.Args) > 0 {
+						candidate += " "
+					}
+					lines = append(lines, "/"+candidate)
+				}
+			}
+		}
+		return lines
+	}
+
+	command, ok := r.Find(name)
+	if !ok || !command.AvailableOn(tab) {
+		return nil
+	}
+
+	// Arguments before the one being typed are kept as they are
+	typed, i := "/"+name+" ", 0
+	for ; i < len(command.Args)-1 && !command.Args[i].Rest; i++ {
+		value, remaining, more := strings.Cut(rest, " ")
+		if !more {
+			break
+		}
+		typed += value + " "
+		rest = remaining
+	}
+	if i >= len(command.Args) || command.Args[i].Complete == nil {
+		return nil
+	}
+
+	var lines []string
+	for _, value := range command.Args[i].Complete(target, rest) {
+		lines = append(lines, typed+value)
+	}
+	return lines
+}
+
+// CommonPrefix returns the longest prefix shared by all lines
+func CommonPrefix(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	prefix := lines[0]
+	for _, line := range lines[1:] {
+		for !strings.HasPrefix(line, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	// Don't end part way through a multi-byte character
+	for !utf8.ValidString(prefix) {
+		prefix = prefix[:len(prefix)-1]
+	}
+	return prefix
+}
+
+// FilterPrefix returns the values starting with prefix
+func FilterPrefix(values []string, prefix string) []string {
+	var matches []string
+	for _, value := range values {
+		if strings.HasPrefix(value, prefix) {
+			matches = append(matches, value)
+		}
+	}
+	return matches
+}
+
+// CompletePath returns the files and directories starting with prefix, with a
+// trailing separator on directories so completion can continue into them
+// A leading ~ stands for the home directory
+func CompletePath(prefix string) []string {
+	path := prefix
+	if prefix == "~" {
+		return []string{"~" + string(filepath.Separator)}
+	}
+	if rest, ok := strings.CutPrefix(prefix, "~"+string(filepath.Separator)); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		path = filepath.Join(home, rest)
+		if rest == "" || strings.HasSuffix(rest, string(filepath.Separator)) {
+			path += string(filepath.Separator)
+		}
+	}
+
+	dir, base := filepath.Split(path)
+	entries, err := os.ReadDir(dirOrCurrent(dir))
+	if err != nil {
+		return nil
+	}
+
+	var matches []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, base) || strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".") {
+			continue
+		}
+		match := prefix[:len(prefix)-len(base)] + name
+		if entry.IsDir() {
+			match += string(filepath.Separator)
+		}
+		matches = append(matches, match)
+	}
+	return matches
+}
+
+func dirOrCurrent(dir string) string {
+	if dir == "" {
+		return "."
+	}
+	return dir
+}
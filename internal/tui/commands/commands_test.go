@@ -0,0 +1,132 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// target records the commands run in tests
+type target struct {
+	models []string
+	ran    [][]string
+}
+
+func testRegistry(t *testing.T) *Registry[*target] {
+	t.Helper()
+	record := func(name string) func(*target, []string) tea.Cmd {
+		return func(tg *target, args []string) tea.Cmd {
+			tg.ran = append(tg.ran, append([]string{name}, args...))
+			return nil
+		}
+	}
+	completeModel := func(tg *target, prefix string) []string { return FilterPrefix(tg.models, prefix) }
+
+	registry := NewRegistry[*target]()
+	for _, command := range []Command[*target]{
+		{Name: "clear", Tabs: []string{"chat"}, Run: record("clear")},
+		{Name: "model", Args: []Arg[*target]{{Name: "name", Complete: completeModel}}, Run: record("model")},
+		{Name: "models", Run: record("models")},
+		{Name: "rename", Args: []Arg[*target]{{Name: "from", Complete: completeModel}, {Name: "to", Optional: true, Rest: true}}, Run: record("rename")},
+		{Name: "exit", Aliases: []string{"quit"}, Run: record("exit")},
+	} {
+		if err := registry.Register(command); err != nil {
+			t.Fatalf("Register(%s) error = %v", command.Name, err)
+		}
+	}
+	return registry
+}
+
+func TestRegister(t *testing.T) {
+	registry := testRegistry(t)
+	if err := registry.Register(Command[*target]{Name: "quit"}); err == nil {
+		t.Error("Register() should reject a name used as an alias")
+	}
+	if err := registry.Register(Command[*target]{Name: "bad", Args: []Arg[*target]{{Name: "a", Rest: true}, {Name: "b"}}}); err == nil {
+		t.Error("Register() should reject a rest argument that isn't last")
+	}
+	if usage := registry.Commands()[3].Usage(); usage != "/rename <from> [to]" {
+		t.Errorf("Usage() = %q", usage)
+	}
+}
+
+func TestRun(t *testing.T) {
+	registry := testRegistry(t)
+	tg := &target{}
+
+	tests := []struct {
+		tab, line string
+		wantErr   bool
+	}{
+		{"chat", "/clear", false},
+		{"rag", "/clear", true},
+		{"rag", "/quit", false},
+		{"chat", "/model llama3", false},
+		{"chat", "/model", true},
+		{"chat", "/model a b", true},
+		{"chat", "/rename old new name", false},
+		{"chat", "/rename old", false},
+		{"chat", "/bogus", true},
+	}
+	for _, test := range tests {
+		if _, err := registry.Run(tg, test.tab, test.line); (err != nil) != test.wantErr {
+			t.Errorf("Run(%s, %q) error = %v, wantErr %t", test.tab, test.line, err, test.wantErr)
+		}
+	}
+
+	want := [][]string{{"clear"}, {"exit"}, {"model", "llama3"}, {"rename", "old", "new name"}, {"rename", "old"}}
+	if !slices.EqualFunc(tg.ran, want, slices.Equal) {
+		t.Errorf("ran %v, want %v", tg.ran, want)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	registry := testRegistry(t)
+	tg := &target{models: []string{"llama3:8b", "llama3:70b", "qwen2.5"}}
+
+	tests := []struct {
+		tab, line string
+		want      []string
+	}{
+		{"chat", "/mo", []string{"/model ", "/models"}},
+		{"rag", "/c", nil},
+		{"chat", "/q", []string{"/quit"}},
+		{"chat", "/model ll", []string{"/model llama3:8b", "/model llama3:70b"}},
+		{"chat", "/rename q", []string{"/rename qwen2.5"}},
+		{"chat", "/rename qwen2.5 x", nil},
+		{"chat", "hello", nil},
+	}
+	for _, test := range tests {
+		if got := registry.Complete(tg, test.tab, test.line); !slices.Equal(got, test.want) {
+			t.Errorf("Complete(%s, %q) = %q, want %q", test.tab, test.line, got, test.want)
+		}
+	}
+
+	if prefix := CommonPrefix([]string{"/model llama3:8b", "/model llama3:70b"}); prefix != "/model llama3:" {
+		t.Errorf("CommonPrefix() = %q", prefix)
+	}
+}
+
+func TestCompletePath(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"notes.md", "notebook.txt", ".hidden"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "notes"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	got := CompletePath(filepath.Join(dir, "note"))
+	want := []string{filepath.Join(dir, "notebook.txt"), filepath.Join(dir, "notes") + string(filepath.Separator), filepath.Join(dir, "notes.md")}
+	if !slices.Equal(got, want) {
+		t.Errorf("CompletePath() = %q, want %q", got, want)
+	}
+	if got := CompletePath(dir + string(filepath.Separator)); len(got) != 3 {
+		t.Errorf("CompletePath(dir) = %q, want the three entries that aren't hidden", got)
+	}
+}
@@ -0,0 +1,131 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Rows the prompt input grows between as lines are typed or pasted
+const (
+	inputMinHeight = 3
+	inputMaxHeight = 12
+)
+
+// tabHeaderHeight is the height of the tab row above the viewports
+const tabHeaderHeight = 3
+
+// editorFinishedMsg is sent when the external editor opened on the prompt exits
+type editorFinishedMsg struct {
+	path string // Temporary file holding the prompt
+	err  error
+}
+
+// inputLines returns the rows the prompt takes once long lines are wrapped
+func (m *model) inputLines() int {
+	width := max(m.textarea.Width(), 1)
+	rows := 0
+	for _, line := range strings.Split(m.textarea.Value(), "\n") {
+		rows += max(1, (lipgloss.Width(line)+width-1)/width)
+	}
+	return rows
+}
+
+// resizeInput grows or shrinks the prompt input to fit its text and gives the
+// remaining height to the viewports
+func (m *model) resizeInput() {
+	if m.windowHeight == 0 {
+		return
+	}
+
+	// Leave the viewports at least a few rows however much is typed
+	maxHeight := min(inputMaxHeight, max(inputMinHeight, m.windowHeight/2))
+	m.textarea.SetHeight(min(max(m.inputLines(), inputMinHeight), maxHeight))
+
+	atBottom := m.viewport.AtBottom()
+	availableHeight := m.windowHeight - m.textarea.Height() - lipgloss.Height(gap) - tabHeaderHeight
+	if len(m.attachments) > 0 || len(m.images) > 0 {
+		availableHeight-- // Attachment chips above the input
+	}
+	availableHeight = max(availableHeight, 3) // A border around at least one row
+	m.viewport.Height = availableHeight
+	m.modelsViewport.Height = availableHeight
+	m.ragViewport.Height = availableHeight
+	m.settingsViewport.Height = availableHeight
+	if atBottom {
+		m.viewport.GotoBottom()
+	}
+}
+
+// editorCommand returns the editor named by $VISUAL or $EDITOR, falling back to vi
+func editorCommand(path string) (*exec.Cmd, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// Editors are often given with arguments, such as code --wait
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		return nil, errors.New("no editor configured, set $EDITOR")
+	}
+	return exec.Command(args[0], append(args[1:], path)...), nil
+}
+
+// openEditor suspends the TUI and edits the prompt in an external editor
+func (m *model) openEditor() tea.Cmd {
+	file, err := os.CreateTemp("", "gollama-prompt-*.md")
+	if err != nil {
+		m.inputError = fmt.Sprintf("Failed to create prompt file: %v", err)
+		return nil
+	}
+	_, err = file.WriteString(m.textarea.Value())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		m.inputError = fmt.Sprintf("Failed to write prompt file: %v", err)
+		return nil
+	}
+
+	cmd, err := editorCommand(file.Name())
+	if err != nil {
+		os.Remove(file.Name())
+		m.inputError = err.Error()
+		return nil
+	}
+	m.inputError = ""
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return editorFinishedMsg{path: file.Name(), err: err}
+	})
+}
+
+// finishEditing reads the prompt back from the editor's file, keeping the prompt
+// unchanged if the editor failed
+func (m *model) finishEditing(msg editorFinishedMsg) {
+	defer os.Remove(msg.path)
+	if msg.err != nil {
+		m.inputError = fmt.Sprintf("Editor failed, prompt unchanged: %v", msg.err)
+		return
+	}
+	content, err := os.ReadFile(msg.path)
+	if err != nil {
+		m.inputError = fmt.Sprintf("Failed to read prompt file: %v", err)
+		return
+	}
+
+	// Editors usually end the file with a newline that isn't part of the prompt
+	m.textarea.SetValue(strings.TrimRight(string(content), "\r\n"))
+	m.focus = focusTextarea
+	m.textarea.Focus()
+	m.updateInputPlaceholder()
+}
@@ -0,0 +1,266 @@
+package tui
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// keyScope is where a key binding applies
+type keyScope int
+
+const (
+	scopeGlobal   keyScope = iota // Every tab
+	scopeRAG                      // RAG tab viewport
+	scopeSettings                 // Settings tab viewport
+)
+
+// keyMap holds the binding of every action the TUI handles
+type keyMap struct {
+	NextTab    key.Binding
+	FocusInput key.Binding
+	Palette    key.Binding
+	Up         key.Binding
+	Down       key.Binding
+	Enter      key.Binding
+	Newline    key.Binding
+	Editor     key.Binding
+	ClearInput key.Binding
+	LineStart  key.Binding
+	LineEnd    key.Binding
+	Back       key.Binding
+	Quit       key.Binding
+
+	CopyMessage key.Binding
+	EditMessage key.Binding
+	Find        key.Binding
+	Sessions    key.Binding
+
+	RAGChromaURL      key.Binding
+	RAGMode           key.Binding
+	RAGRerank         key.Binding
+	RAGTopK           key.Binding
+	RAGMaxDistance    key.Binding
+	RAGWhere          key.Binding
+	RAGPromptTemplate key.Binding
+	RAGKeepContext    key.Binding
+	RAGInspect        key.Binding
+	RAGWatchFolders   key.Binding
+	RAGSync           key.Binding
+	RAGWatch          key.Binding
+
+	EndpointEdit    key.Binding
+	EndpointAdd     key.Binding
+	EndpointRemove  key.Binding
+	EndpointBackend key.Binding
+	EndpointRecheck key.Binding
+	AggregateModels key.Binding
+	SettingsSave    key.Binding
+	SettingsRevert  key.Binding
+}
+
+// keyAction names a binding for the keys setting
+type keyAction struct {
+	name    string
+	scope   keyScope
+	binding *key.Binding
+}
+
+// defaultKeyMap returns the built-in bindings
+func defaultKeyMap() keyMap {
+	bind := func(help string, keys ...string) key.Binding {
+		return key.NewBinding(key.WithKeys(keys...), key.WithHelp(formatKeys(keys), help))
+	}
+	return keyMap{
+		NextTab:    bind("switch tabs", "tab"),
+		FocusInput: bind("focus the input field", "ctrl+t"),
+		Palette:    bind("open the command palette", "ctrl+p"),
+		Up:         bind("move up", "up"),
+		Down:       bind("move down", "down"),
+		Enter:      bind("send or select", "enter"),
+		Newline:    bind("insert a newline", "alt+enter", "ctrl+j"),
+		Editor:     bind("edit the prompt in $EDITOR", "ctrl+g"),
+		ClearInput: bind("clear input", "ctrl+u"),
+		LineStart:  bind("go to start", "ctrl+a"),
+		LineEnd:    bind("go to end", "ctrl+e"),
+		Back:       bind("cancel or go back", "esc"),
+		Quit:       bind("quit", "ctrl+c"),
+
+		CopyMessage: bind("copy the selected message", "ctrl+y"),
+		EditMessage: bind("edit the selected message", "ctrl+o"),
+		Find:        bind("search the conversation", "ctrl+f"),
+		Sessions:    bind("search saved sessions", "ctrl+r"),
+
+		RAGChromaURL:      bind("configure ChromaDB URL", "c"),
+		RAGMode:           bind("cycle retrieval mode", "m"),
+		RAGRerank:         bind("toggle re-ranking", "r"),
+		RAGTopK:           bind("set top K", "k"),
+		RAGMaxDistance:    bind("set max distance", "d"),
+		RAGWhere:          bind("set where filter", "w"),
+		RAGPromptTemplate: bind("edit prompt template", "p"),
+		RAGKeepContext:    bind("toggle keeping past context", "h"),
+		RAGInspect:        bind("inspect a query", "i"),
+		RAGWatchFolders:   bind("set watch folders", "f"),
+		RAGSync:           bind("sync now", "s"),
+		RAGWatch:          bind("toggle watching folders", "a"),
+
+		EndpointEdit:    bind("edit selected endpoint or setting", "e"),
+		EndpointAdd:     bind("add endpoint", "n"),
+		EndpointRemove:  bind("remove selected endpoint", "x"),
+		EndpointBackend: bind("switch backend of selected endpoint", "b"),
+		EndpointRecheck: bind("recheck endpoints", "r"),
+		AggregateModels: bind("merge models from all endpoints", "a"),
+		SettingsSave:    bind("save changed settings", "s"),
+		SettingsRevert:  bind("revert changed settings", "u"),
+	}
+}
+
+// actions lists every binding with the name used for it in the keys setting
+func (k *keyMap) actions() []keyAction {
+	return []keyAction{
+		{"nextTab", scopeGlobal, &k.NextTab},
+		{"focusInput", scopeGlobal, &k.FocusInput},
+		{"palette", scopeGlobal, &k.Palette},
+		{"up", scopeGlobal, &k.Up},
+		{"down", scopeGlobal, &k.Down},
+		{"enter", scopeGlobal, &k.Enter},
+		{"newline", scopeGlobal, &k.Newline},
+		{"editor", scopeGlobal, &k.Editor},
+		{"clearInput", scopeGlobal, &k.ClearInput},
+		{"lineStart", scopeGlobal, &k.LineStart},
+		{"lineEnd", scopeGlobal, &k.LineEnd},
+		{"back", scopeGlobal, &k.Back},
+		{"quit", scopeGlobal, &k.Quit},
+		{"copyMessage", scopeGlobal, &k.CopyMessage},
+		{"editMessage", scopeGlobal, &k.EditMessage},
+		{"find", scopeGlobal, &k.Find},
+		{"sessions", scopeGlobal, &k.Sessions},
+
+		{"ragChromaURL", scopeRAG, &k.RAGChromaURL},
+		{"ragMode", scopeRAG, &k.RAGMode},
+		{"ragRerank", scopeRAG, &k.RAGRerank},
+		{"ragTopK", scopeRAG, &k.RAGTopK},
+		{"ragMaxDistance", scopeRAG, &k.RAGMaxDistance},
+		{"ragWhere", scopeRAG, &k.RAGWhere},
+		{"ragPromptTemplate", scopeRAG, &k.RAGPromptTemplate},
+		{"ragKeepContext", scopeRAG, &k.RAGKeepContext},
+		{"ragInspect", scopeRAG, &k.RAGInspect},
+		{"ragWatchFolders", scopeRAG, &k.RAGWatchFolders},
+		{"ragSync", scopeRAG, &k.RAGSync},
+		{"ragWatch", scopeRAG, &k.RAGWatch},
+
+		{"endpointEdit", scopeSettings, &k.EndpointEdit},
+		{"endpointAdd", scopeSettings, &k.EndpointAdd},
+		{"endpointRemove", scopeSettings, &k.EndpointRemove},
+		{"endpointBackend", scopeSettings, &k.EndpointBackend},
+		{"endpointRecheck", scopeSettings, &k.EndpointRecheck},
+		{"aggregateModels", scopeSettings, &k.AggregateModels},
+		{"settingsSave", scopeSettings, &k.SettingsSave},
+		{"settingsRevert", scopeSettings, &k.SettingsRevert},
+	}
+}
+
+// newKeyMap applies the keys setting over the defaults
+// Unknown actions and bindings that conflict are reported and left at their defaults
+func newKeyMap(overrides map[string][]string) (keyMap, []error) {
+	keys := defaultKeyMap()
+	defaults := defaultKeyMap()
+	actions, defaultActions := keys.actions(), defaults.actions()
+
+	var problems []error
+	for _, name := range slices.Sorted(maps.Keys(overrides)) {
+		i := slices.IndexFunc(actions, func(a keyAction) bool { return a.name == name })
+		if i < 0 {
+			problems = append(problems, fmt.Errorf("keys.%s: unknown action", name))
+			continue
+		}
+		bound := overrides[name]
+		actions[i].binding.SetKeys(bound...)
+		actions[i].binding.SetHelp(formatKeys(bound), actions[i].binding.Help().Desc)
+	}
+
+	// Reverting a binding can expose another conflict, so repeat until none remain
+	for {
+		reverted := false
+		for i, action := range actions {
+			if _, ok := overrides[action.name]; !ok || slices.Equal(action.binding.Keys(), defaultActions[i].binding.Keys()) {
+				continue
+			}
+			if err := keyConflict(action, actions); err != nil {
+				problems = append(problems, fmt.Errorf("keys.%s: %v, using %s", action.name, err, formatKeys(defaultActions[i].binding.Keys())))
+				*action.binding = *defaultActions[i].binding
+				reverted = true
+			}
+		}
+		if !reverted {
+			return keys, problems
+		}
+	}
+}
+
+// keyConflict reports a key of action that another action in an overlapping scope
+// also uses, or a printable key that would stop the character being typed
+func keyConflict(action keyAction, actions []keyAction) error {
+	for _, k := range action.binding.Keys() {
+		if action.scope == scopeGlobal && utf8.RuneCountInString(k) == 1 {
+			return fmt.Errorf("%s can't be typed if it is bound everywhere", k)
+		}
+		for _, other := range actions {
+			if other.name == action.name || (action.scope != other.scope && action.scope != scopeGlobal && other.scope != scopeGlobal) {
+				continue
+			}
+			if slices.Contains(other.binding.Keys(), k) {
+				return fmt.Errorf("%s is already bound to %s", formatKeys([]string{k}), other.name)
+			}
+		}
+	}
+	return nil
+}
+
+// formatKeys renders keys as shown in help text, such as Ctrl+T or ↑/↓
+func formatKeys(keys []string) string {
+	if len(keys) == 0 {
+		return "(unbound)"
+	}
+	names := map[string]string{"up": "↑", "down": "↓", "left": "←", "right": "→", " ": "Space"}
+	var formatted []string
+	for _, k := range keys {
+		if name, ok := names[k]; ok {
+			formatted = append(formatted, name)
+			continue
+		}
+		parts := strings.Split(k, "+")
+		for i, part := range parts {
+			if part != "" {
+				parts[i] = strings.ToUpper(part[:1]) + part[1:]
+			}
+		}
+		formatted = append(formatted, strings.Join(parts, "+"))
+	}
+	return strings.Join(formatted, "/")
+}
+
+// controlLine renders a Controls entry for one or more bindings
+func controlLine(desc string, bindings ...key.Binding) string {
+	var keys []string
+	for _, binding := range bindings {
+		keys = append(keys, binding.Keys()...)
+	}
+	return formatKeys(keys) + " - " + desc
+}
+
+// keyHelpLines renders the bindings of a scope as a bulleted list
+func (k *keyMap) keyHelpLines(scope keyScope) []string {
+	var lines []string
+	for _, action := range k.actions() {
+		if action.scope == scope {
+			help := action.binding.Help()
+			lines = append(lines, fmt.Sprintf("  • %s - %s", help.Key, help.Desc))
+		}
+	}
+	return lines
+}
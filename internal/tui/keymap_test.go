@@ -0,0 +1,90 @@
+package tui
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNewKeyMap(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string][]string
+		want      map[string][]string // Keys of actions after applying the overrides
+		problems  []string            // Text of each problem reported, in order
+	}{
+		{
+			name:      "unknown action",
+			overrides: map[string][]string{"teleport": {"t"}},
+			problems:  []string{"keys.teleport: unknown action"},
+		},
+		{
+			name:      "same-scope conflict",
+			overrides: map[string][]string{"ragMode": {"c"}},
+			want:      map[string][]string{"ragMode": {"m"}, "ragChromaURL": {"c"}},
+			problems:  []string{"keys.ragMode: C is already bound to ragChromaURL, using M"},
+		},
+		{
+			name:      "RAG and Settings scopes don't overlap",
+			overrides: map[string][]string{"ragWatch": {"r"}, "ragRerank": {"t"}},
+			want:      map[string][]string{"ragWatch": {"r"}, "ragRerank": {"t"}, "endpointRecheck": {"r"}},
+		},
+		{
+			name:      "scoped key bound everywhere",
+			overrides: map[string][]string{"ragSync": {"ctrl+f"}},
+			want:      map[string][]string{"ragSync": {"s"}},
+			problems:  []string{"keys.ragSync: Ctrl+F is already bound to find, using S"},
+		},
+		{
+			name:      "printable key bound everywhere",
+			overrides: map[string][]string{"find": {"/"}},
+			want:      map[string][]string{"find": {"ctrl+f"}},
+			problems:  []string{"keys.find: / can't be typed if it is bound everywhere, using Ctrl+F"},
+		},
+		{
+			// Reverting ragSync gives s back to it, which ragMode had taken
+			name:      "revert exposes another conflict",
+			overrides: map[string][]string{"ragMode": {"s"}, "ragSync": {"c"}},
+			want:      map[string][]string{"ragMode": {"m"}, "ragSync": {"s"}},
+			problems: []string{
+				"keys.ragSync: C is already bound to ragChromaURL, using S",
+				"keys.ragMode: S is already bound to ragSync, using M",
+			},
+		},
+		{
+			name:      "unbound action",
+			overrides: map[string][]string{"ragWatch": {}},
+			want:      map[string][]string{"ragWatch": nil},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			keys, problems := newKeyMap(test.overrides)
+
+			var got []string
+			for _, problem := range problems {
+				got = append(got, problem.Error())
+			}
+			if !slices.Equal(got, test.problems) {
+				t.Errorf("problems = %q, want %q", got, test.problems)
+			}
+
+			for _, action := range keys.actions() {
+				want, ok := test.want[action.name]
+				if ok && !slices.Equal(action.binding.Keys(), want) {
+					t.Errorf("%s bound to %q, want %q", action.name, action.binding.Keys(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultKeyMapHasNoConflicts(t *testing.T) {
+	keys := defaultKeyMap()
+	actions := keys.actions()
+	for _, action := range actions {
+		if err := keyConflict(action, actions); err != nil {
+			t.Errorf("default %s: %v", action.name, err)
+		}
+	}
+}
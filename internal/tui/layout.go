@@ -0,0 +1,143 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Smallest terminal the layout is drawn in; below it a notice asks for more room
+const (
+	minWindowWidth  = 40
+	minWindowHeight = 12
+)
+
+// shortTabNames replace the tab names, which carry status, when they don't fit on one row
+var shortTabNames = []string{"Chat", "Models", "RAG", "Settings"}
+
+// resize lays out the viewports and inputs for the terminal size and re-wraps
+// their content to the new widths
+func (m *model) resize(width, height int) {
+	m.windowWidth, m.windowHeight = width, height
+
+	// Full width for the chat, less the border and padding
+	chatWidth := max(width-4, 1)
+
+	modelsWidth := min(chatWidth, 60) // Wider width for the help shown without models
+	if m.bot.ModelManager != nil {
+		modelsWidth = min(chatWidth, m.bot.ModelManager.MaxModelNameLength()+10)
+	}
+
+	m.viewport.Width = chatWidth
+	m.modelsViewport.Width = modelsWidth
+	m.ragViewport.Width = min(chatWidth, 70)      // Wide enough for RAG status messages
+	m.settingsViewport.Width = min(chatWidth, 60) // Wide enough for endpoint URLs
+	m.textarea.SetWidth(chatWidth)
+
+	// Single line inputs keep their preferred width when there is room for it
+	inputWidth := func(preferred int, prompt string) int {
+		return max(min(preferred, chatWidth-lipgloss.Width(prompt)-1), 1)
+	}
+	m.urlTextInput.Width = inputWidth(40, m.urlTextInput.Prompt)
+	m.chromaDBTextInput.Width = inputWidth(40, m.chromaDBTextInput.Prompt)
+	m.ragOptionInput.Width = inputWidth(60, m.ragOptionInput.Prompt)
+	m.ragInspectInput.Width = inputWidth(60, m.ragInspectInput.Prompt)
+	m.paletteInput.Width = inputWidth(60, m.paletteInput.Prompt)
+	m.searchInput.Width = inputWidth(60, m.searchInput.Prompt)
+	m.sessionsInput.Width = inputWidth(60, m.sessionsInput.Prompt)
+
+	// Heights follow the prompt input, see resizeInput
+	m.resizeInput()
+
+	if m.bot.MessageLen() > 0 {
+		m.refreshChat()
+	} else {
+		m.viewport.SetContent(m.wrapChat(welcomeText(m.keys)))
+	}
+	m.viewport.GotoBottom()
+	m.updateModelsViewportContent()
+	m.updateRAGViewportContent()
+	m.updateSettingsViewportContent()
+}
+
+// wrapChat wraps content to the inside of the chat viewport's border
+func (m *model) wrapChat(content string) string {
+	width := max(m.viewport.Width-m.viewport.Style.GetHorizontalFrameSize(), 1)
+	return lipgloss.NewStyle().Width(width).Render(content)
+}
+
+// refreshChat shows the conversation in the chat viewport, wrapped to its width,
+// with a bar beside the selected message, search matches highlighted and the thinking
+// indicator while waiting
+// It records the message shown on each line, for mouse clicks
+func (m *model) refreshChat() {
+	accent := lipgloss.Color("62")
+	if m.darkMode {
+		accent = darkModeAccentColor
+	}
+	selected := lipgloss.NewStyle().
+		Border(lipgloss.ThickBorder(), false, false, false, true).
+		BorderForeground(accent).
+		Width(max(m.viewport.Width-m.viewport.Style.GetHorizontalFrameSize()-1, 1))
+
+	var highlight func(int, string) string
+	if m.focus == focusSearch && len(m.searchMatches) > 0 {
+		highlight = m.highlightMatches
+	}
+
+	var blocks []string
+	m.chatLines = nil
+	for _, styled := range m.bot.MessageManager.StyledHighlighted(highlight) {
+		block := m.wrapChat(styled.Text)
+		if styled.Index >= 0 && styled.Index == m.selectedMessage {
+			block = selected.Render(styled.Text)
+		}
+		blocks = append(blocks, block)
+		for range lipgloss.Height(block) {
+			m.chatLines = append(m.chatLines, styled.Index)
+		}
+	}
+	if m.isThinking && len(blocks) > 0 {
+		if m.responseBuffer != "" {
+			// The reply streamed so far, added to the conversation once complete
+			role := lipgloss.NewStyle().Foreground(lipgloss.Color("3")).Render("Assistant")
+			block := m.wrapChat(role + ": " + m.responseBuffer)
+			blocks = append(blocks, block)
+			for range lipgloss.Height(block) {
+				m.chatLines = append(m.chatLines, -1)
+			}
+		}
+		blocks = append(blocks, "", m.wrapChat(m.getThinkingIndicator()))
+	}
+	m.viewport.SetContent(strings.Join(blocks, "\n"))
+}
+
+// tabNames returns the names shown on the tab row, without their status when
+// they don't fit on one row
+func (m *model) tabNames() []string {
+	if m.windowWidth > 0 && lipgloss.Width(m.renderTabs(m.tabs)) > m.windowWidth {
+		return shortTabNames
+	}
+	return m.tabs
+}
+
+// tooSmall reports whether the terminal is too small to lay out the tabs
+func (m *model) tooSmall() bool {
+	return m.windowWidth > 0 && (m.windowWidth < minWindowWidth || m.windowHeight < minWindowHeight)
+}
+
+// tooSmallView asks for a larger terminal in place of a layout that wouldn't fit
+func (m *model) tooSmallView() string {
+	notice := fmt.Sprintf("Terminal too small (%d×%d)\nResize to at least %d×%d", m.windowWidth, m.windowHeight, minWindowWidth, minWindowHeight)
+	notice = lipgloss.NewStyle().MaxWidth(m.windowWidth).Render(notice)
+	return lipgloss.Place(m.windowWidth, m.windowHeight, lipgloss.Center, lipgloss.Center, notice)
+}
+
+// fitStatus shortens a status line above the input to a single row of the window
+func (m *model) fitStatus(text string) string {
+	if m.windowWidth == 0 {
+		return text
+	}
+	return truncateText(text, max(m.windowWidth-4, 1))
+}
@@ -0,0 +1,163 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/kevensen/gollama-bubbletea/internal/bot/attachments"
+)
+
+// updateMouse scrolls the active tab with the wheel and handles clicks on the
+// tab row, the Models list and chat messages
+func (m *model) updateMouse(msg tea.MouseMsg) tea.Cmd {
+	if m.focus == focusPalette || m.focus == focusSessions || m.tooSmall() {
+		return nil
+	}
+
+	if tea.MouseEvent(msg).IsWheel() {
+		var cmd tea.Cmd
+		switch m.activeTab {
+		case chatTab:
+			m.viewport, cmd = m.viewport.Update(msg)
+		case modelsTab:
+			m.modelsViewport, cmd = m.modelsViewport.Update(msg)
+		case ragTab:
+			m.ragViewport, cmd = m.ragViewport.Update(msg)
+		case settingsTab:
+			m.settingsViewport, cmd = m.settingsViewport.Update(msg)
+		}
+		return cmd
+	}
+	if msg.Action != tea.MouseActionPress || msg.Button != tea.MouseButtonLeft {
+		return nil
+	}
+
+	if msg.Y < tabHeaderHeight {
+		if t, ok := m.tabAt(msg.X); ok {
+			return m.clickTab(t)
+		}
+		return nil
+	}
+
+	// Rows of the active viewport start below its top border
+	row := msg.Y - tabHeaderHeight - 1
+	switch m.activeTab {
+	case chatTab:
+		m.clickMessage(row + m.viewport.YOffset)
+	case modelsTab:
+		m.clickModel(row + m.modelsViewport.YOffset)
+	}
+	return nil
+}
+
+// tabAt returns the tab drawn at a column of the tab row
+func (m *model) tabAt(x int) (tab, bool) {
+	right := 0
+	for i, cell := range m.tabCells(m.tabNames()) {
+		right += lipgloss.Width(cell)
+		if x < right {
+			return tab(i), true
+		}
+	}
+	return 0, false
+}
+
+// clickTab switches to a tab the same way as its slash command, such as /models
+func (m *model) clickTab(t tab) tea.Cmd {
+	if t == m.activeTab {
+		return nil
+	}
+	cmd := m.runCommand("/" + t.String())
+	if m.activeTab == t {
+		m.focusActiveTab()
+	}
+	return cmd
+}
+
+// clickModel selects the model on a line of the Models list, or switches to it
+// when it is already selected
+func (m *model) clickModel(line int) {
+	for i, modelLine := range m.modelLines {
+		if modelLine != line {
+			continue
+		}
+		if i == m.selectedModel && m.focus == focusModelsViewport {
+			if err := m.useModel(m.models[i]); err != nil {
+				m.inputError = err.Error()
+			}
+			return
+		}
+		m.selectedModel = i
+		m.focus = focusModelsViewport
+		m.textarea.Blur()
+		m.updateModelsViewportContent()
+		m.updateInputPlaceholder()
+		return
+	}
+}
+
+// clickMessage selects the chat message on a line of the conversation, or clears
+// the selection when it is clicked again
+func (m *model) clickMessage(line int) {
+	if line < 0 || line >= len(m.chatLines) || m.chatLines[line] < 0 {
+		return
+	}
+	if m.chatLines[line] == m.selectedMessage {
+		m.selectMessage(-1)
+	} else {
+		m.selectMessage(m.chatLines[line])
+	}
+}
+
+// selectMessage highlights a message of the conversation, -1 for none
+func (m *model) selectMessage(index int) {
+	m.selectedMessage = index
+	m.refreshChat()
+}
+
+// selectionHint names the keys that act on the selected message, shown above the input
+func (m *model) selectionHint() string {
+	if m.selectedMessage < 0 || m.activeTab != chatTab || m.focus == focusSessions {
+		return ""
+	}
+	return fmt.Sprintf("Message selected - %s to copy, %s to edit, %s to clear",
+		formatKeys(m.keys.CopyMessage.Keys()), formatKeys(m.keys.EditMessage.Keys()), formatKeys(m.keys.Back.Keys()))
+}
+
+// copyMessage copies the text of the selected message to the clipboard, without
+// any files attached to it
+func (m *model) copyMessage() {
+	msg, ok := m.bot.MessageManager.MessageAt(m.selectedMessage)
+	if !ok {
+		m.selectMessage(-1)
+		return
+	}
+	text, _ := attachments.Split(msg.Content)
+	if err := clipboard.WriteAll(text); err != nil {
+		m.inputError = fmt.Sprintf("Failed to copy message: %v", err)
+		return
+	}
+	m.inputError = ""
+	m.selectMessage(-1)
+}
+
+// editMessage puts the selected message back in the prompt with its attachments,
+// to change and send again
+func (m *model) editMessage() {
+	msg, ok := m.bot.MessageManager.MessageAt(m.selectedMessage)
+	m.selectMessage(-1)
+	if !ok {
+		return
+	}
+	text, files := attachments.Split(msg.Content)
+	m.attachments, m.images = files, msg.Images
+	m.textarea.SetValue(text)
+	m.textarea.CursorEnd()
+	m.focus = focusTextarea
+	m.textarea.Focus()
+	m.inputError = ""
+	m.updateInputPlaceholder()
+}
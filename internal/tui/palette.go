@@ -0,0 +1,294 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/kevensen/gollama-bubbletea/internal/bot/sessions"
+)
+
+// paletteEntry is something the command palette can run
+type paletteEntry struct {
+	title   string // Shown and matched against the query
+	detail  string // Description or key binding
+	command string // Slash command passed to runCommand, empty for a key action
+	action  string // Key action replayed when command is empty
+	session string // Saved session opened in place of a command or action
+}
+
+// paletteEntries lists every slash command available on the active tab, with
+// each model and profile, every saved session and every tab action
+func (m *model) paletteEntries() []paletteEntry {
+	var entries []paletteEntry
+	for _, command := range slashCommands.Commands() {
+		if !command.AvailableOn(m.activeTab.String()) {
+			continue
+		}
+		// Commands taking an argument are listed once for each value it can have,
+		// and on their own when it is optional
+		name := "/" + command.Name
+		if len(command.Args) == 0 || command.Args[0].Optional {
+			entries = append(entries, paletteEntry{title: name, detail: command.Desc, command: name})
+		}
+		if len(command.Args) > 0 && command.Args[0].Complete != nil {
+			for _, value := range command.Args[0].Complete(m, "") {
+				entries = append(entries, paletteEntry{title: name + " " + value, detail: command.Desc, command: name + " " + value})
+			}
+		}
+	}
+
+	if m.sessionStore != nil {
+		for _, result := range m.sessionStore.Search("") {
+			date := result.Session.Updated.Local().Format("2006-01-02 15:04")
+			entries = append(entries, paletteEntry{title: "Session: " + result.Session.Title(), detail: date, session: result.Session.ID})
+		}
+	}
+
+	scopes := map[keyScope]string{scopeRAG: "RAG", scopeSettings: "Settings"}
+	for _, action := range m.keys.actions() {
+		if action.scope == scopeGlobal || len(action.binding.Keys()) == 0 {
+			continue
+		}
+		help := action.binding.Help()
+		entries = append(entries, paletteEntry{title: scopes[action.scope] + ": " + help.Desc, detail: help.Key, action: action.name})
+	}
+	return entries
+}
+
+// openPalette shows the command palette over the active tab
+func (m *model) openPalette() {
+	m.paletteReturn = m.focus
+	m.focus = focusPalette
+	m.textarea.Blur()
+	m.inputError = ""
+	m.paletteInput.Reset()
+	m.paletteInput.Focus()
+	if m.sessionStore != nil {
+		// Sessions saved by another instance are listed too
+		m.sessionStore.Refresh()
+	}
+	m.filterPalette()
+}
+
+// closePalette returns focus to where it was before the palette opened
+func (m *model) closePalette() {
+	m.paletteInput.Blur()
+	m.focus = m.paletteReturn
+	if m.focus == focusTextarea {
+		m.textarea.Focus()
+	}
+}
+
+// filterPalette keeps the entries matching the query, best matches first
+func (m *model) filterPalette() {
+	type match struct {
+		entry paletteEntry
+		score int
+	}
+	query := strings.TrimSpace(m.paletteInput.Value())
+	var matches []match
+	for _, entry := range m.paletteEntries() {
+		if score, ok := fuzzyScore(query, entry.title+" "+entry.detail); ok {
+			matches = append(matches, match{entry, score})
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b match) int { return b.score - a.score })
+
+	m.paletteMatches = nil
+	for _, match := range matches {
+		m.paletteMatches = append(m.paletteMatches, match.entry)
+	}
+	m.paletteSelected = 0
+}
+
+// updatePalette handles a key pressed while the command palette is open
+func (m *model) updatePalette(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Back, m.keys.Quit, m.keys.Palette):
+		m.closePalette()
+		return m, nil
+	case key.Matches(msg, m.keys.Up):
+		m.paletteSelected = max(m.paletteSelected-1, 0)
+		return m, nil
+	case key.Matches(msg, m.keys.Down):
+		m.paletteSelected = min(m.paletteSelected+1, max(len(m.paletteMatches)-1, 0))
+		return m, nil
+	case key.Matches(msg, m.keys.Enter):
+		if len(m.paletteMatches) == 0 {
+			return m, nil
+		}
+		return m.runPaletteEntry(m.paletteMatches[m.paletteSelected])
+	}
+
+	query := m.paletteInput.Value()
+	var cmd tea.Cmd
+	m.paletteInput, cmd = m.paletteInput.Update(msg)
+	if m.paletteInput.Value() != query {
+		m.filterPalette()
+	}
+	return m, cmd
+}
+
+// runPaletteEntry closes the palette and runs the chosen entry the same way as
+// typing its command or pressing its key
+func (m *model) runPaletteEntry(entry paletteEntry) (tea.Model, tea.Cmd) {
+	m.closePalette()
+	if entry.session != "" {
+		m.openSession(sessions.Result{Session: &sessions.Session{ID: entry.session}, Message: -1})
+		return m, nil
+	}
+	if entry.command != "" {
+		activeTab := m.activeTab
+		cmd := m.runCommand(entry.command)
+		if m.activeTab != activeTab {
+			m.focusActiveTab()
+		}
+		return m, cmd
+	}
+
+	actions := m.keys.actions()
+	i := slices.IndexFunc(actions, func(a keyAction) bool { return a.name == entry.action })
+	if i < 0 || len(actions[i].binding.Keys()) == 0 {
+		return m, nil
+	}
+	action := actions[i]
+	if action.scope == scopeRAG && !m.connectionValid {
+		m.inputError = "Please configure Ollama URL in Settings tab first"
+		return m, nil
+	}
+	switch action.scope {
+	case scopeRAG:
+		m.activeTab = ragTab
+	case scopeSettings:
+		m.activeTab = settingsTab
+	}
+	m.focusActiveTab()
+
+	msg, ok := keyMsg(action.binding.Keys()[0])
+	if !ok {
+		m.inputError = "Can't replay key " + action.binding.Keys()[0]
+		return m, nil
+	}
+	return m.Update(msg)
+}
+
+// focusActiveTab gives focus to the main element of the active tab
+func (m *model) focusActiveTab() {
+	switch m.activeTab {
+	case chatTab:
+		m.focus = focusTextarea
+		m.textarea.Focus()
+	case modelsTab:
+		m.focus = focusModelsViewport
+		m.textarea.Blur()
+	case ragTab:
+		m.focus = focusRAGViewport
+		m.textarea.Blur()
+	case settingsTab:
+		m.focus = focusSettingsViewport
+		m.textarea.Blur()
+	}
+	m.updateModelsViewportContent()
+	m.updateRAGViewportContent()
+	m.updateSettingsViewportContent()
+	m.updateInputPlaceholder()
+}
+
+// keyMsg returns the key press a binding's key name describes
+func keyMsg(name string) (tea.KeyMsg, bool) {
+	alt := false
+	if rest, ok := strings.CutPrefix(name, "alt+"); ok && rest != "" {
+		alt, name = true, rest
+	}
+	if utf8.RuneCountInString(name) == 1 {
+		return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(name), Alt: alt}, true
+	}
+	// Named keys are negative and control keys are ASCII control codes
+	for t := tea.KeyType(-128); t <= 127; t++ {
+		if t != tea.KeyRunes && t.String() == name {
+			return tea.KeyMsg{Type: t, Alt: alt}, true
+		}
+	}
+	return tea.KeyMsg{}, false
+}
+
+// fuzzyScore reports whether the characters of query appear in order in text,
+// ignoring case, and scores consecutive characters and word starts higher
+func fuzzyScore(query, text string) (int, bool) {
+	if query == "" {
+		return 0, true
+	}
+	target := []rune(strings.ToLower(text))
+	score, pos, previous := 0, 0, -2
+	for _, r := range strings.ToLower(query) {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		i := slices.Index(target[pos:], r)
+		if i < 0 {
+			return 0, false
+		}
+		i += pos
+		score++
+		if i == previous+1 {
+			score += 5
+		}
+		if i == 0 || !unicode.IsLetter(target[i-1]) && !unicode.IsDigit(target[i-1]) {
+			score += 3
+		}
+		previous, pos = i, i+1
+	}
+	// Prefer shorter entries when matches are otherwise equal
+	return score*100 - len(target), true
+}
+
+// paletteView renders the command palette in place of the active tab
+func (m *model) paletteView() string {
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	accent := lipgloss.NewStyle().Foreground(lipgloss.Color("62")).Bold(true)
+	if m.darkMode {
+		dim = lipgloss.NewStyle().Foreground(darkModeTextColor)
+		accent = lipgloss.NewStyle().Foreground(darkModeAccentColor).Bold(true)
+	}
+
+	style := m.viewport.Style
+	width := m.viewport.Width - style.GetHorizontalFrameSize()
+	height := m.viewport.Height - style.GetVerticalFrameSize()
+
+	lines := []string{
+		accent.Render("Command Palette") + dim.Render(fmt.Sprintf("  %d matches - %s to run, %s to close",
+			len(m.paletteMatches), formatKeys(m.keys.Enter.Keys()), formatKeys(m.keys.Back.Keys()))),
+		"",
+	}
+	if len(m.paletteMatches) == 0 {
+		lines = append(lines, dim.Render("  No matches"))
+	}
+
+	// Scroll the list so the highlighted entry stays visible
+	rows := max(height-len(lines), 1)
+	first := max(0, m.paletteSelected-rows+1)
+	titleWidth := 0
+	for _, entry := range m.paletteMatches {
+		titleWidth = max(titleWidth, len(entry.title))
+	}
+	for i := first; i < len(m.paletteMatches) && i < first+rows; i++ {
+		entry := m.paletteMatches[i]
+		cursor := "  "
+		title := fmt.Sprintf("%-*s", titleWidth, entry.title)
+		if i == m.paletteSelected {
+			cursor = "▶ "
+			title = accent.Render(title)
+		}
+		lines = append(lines, cursor+title+"  "+dim.Render(entry.detail))
+	}
+
+	content := lipgloss.NewStyle().Width(width).Height(height).MaxWidth(width).MaxHeight(height).Render(strings.Join(lines, "\n"))
+	return style.UnsetWidth().UnsetHeight().Render(content)
+}
@@ -0,0 +1,74 @@
+package tui
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	matches := []struct {
+		query, text string
+		want        bool
+	}{
+		{"", "anything", true},
+		{"mdl", "model", true},
+		{"MOD", "switch model", true},
+		{"sw mo", "switch model", true}, // Spaces are ignored
+		{"ledom", "model", false},       // Characters must appear in order
+		{"xyz", "model", false},
+	}
+	for _, test := range matches {
+		if _, ok := fuzzyScore(test.query, test.text); ok != test.want {
+			t.Errorf("fuzzyScore(%q, %q) matched = %t, want %t", test.query, test.text, ok, test.want)
+		}
+	}
+
+	ranks := []struct {
+		why, query, better, worse string
+	}{
+		{"consecutive characters", "set", "settings", "sweet treat"},
+		{"word starts", "m", "a model", "a summ"},
+		{"word starts after punctuation", "s", "rag/sync", "rags/x12"},
+		{"shorter entry on a tie", "rag", "rag", "rag sync"},
+	}
+	for _, test := range ranks {
+		better, _ := fuzzyScore(test.query, test.better)
+		worse, _ := fuzzyScore(test.query, test.worse)
+		if better <= worse {
+			t.Errorf("%s: fuzzyScore(%q) of %q = %d, not above %q = %d", test.why, test.query, test.better, better, test.worse, worse)
+		}
+	}
+}
+
+func TestKeyMsg(t *testing.T) {
+	tests := []struct {
+		name string
+		want tea.KeyMsg
+	}{
+		{"a", tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")}},
+		{"alt+a", tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a"), Alt: true}},
+		{"enter", tea.KeyMsg{Type: tea.KeyEnter}},
+		{"alt+enter", tea.KeyMsg{Type: tea.KeyEnter, Alt: true}},
+		{"ctrl+f", tea.KeyMsg{Type: tea.KeyCtrlF}},
+		{"up", tea.KeyMsg{Type: tea.KeyUp}},
+		{"esc", tea.KeyMsg{Type: tea.KeyEsc}},
+	}
+	for _, test := range tests {
+		got, ok := keyMsg(test.name)
+		if !ok || got.Type != test.want.Type || got.Alt != test.want.Alt || string(got.Runes) != string(test.want.Runes) {
+			t.Errorf("keyMsg(%q) = %+v, %t, want %+v", test.name, got, ok, test.want)
+		}
+		// Replaying the key press must match the binding it came from
+		if got.String() != test.name {
+			t.Errorf("keyMsg(%q) replays as %q", test.name, got.String())
+		}
+	}
+
+	// Keys Bubble Tea has no key press for can't be replayed
+	for _, name := range []string{"hyper+x", "alt+", "f99"} {
+		if got, ok := keyMsg(name); ok {
+			t.Errorf("keyMsg(%q) = %+v, want no key press", name, got)
+		}
+	}
+}
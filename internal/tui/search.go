@@ -0,0 +1,161 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/kevensen/gollama-bubbletea/internal/bot/messages"
+)
+
+// openSearch shows the search bar in place of the input to find text in the conversation
+func (m *model) openSearch(query string) {
+	m.selectedMessage = -1
+	m.focus = focusSearch
+	m.textarea.Blur()
+	m.inputError = ""
+	m.searchInput.SetValue(query)
+	m.searchInput.CursorEnd()
+	m.searchInput.Focus()
+	m.runSearch()
+}
+
+// closeSearch hides the search bar, leaving the chat scrolled to the last match shown
+func (m *model) closeSearch() {
+	m.searchInput.Blur()
+	m.searchMatches = nil
+	m.inputError = ""
+	m.focus = focusTextarea
+	m.textarea.Focus()
+	if m.bot.MessageLen() > 0 {
+		m.refreshChat()
+	}
+}
+
+// updateSearch handles a key pressed while the search bar is open
+func (m *model) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Back, m.keys.Quit, m.keys.Find):
+		m.closeSearch()
+		return m, nil
+	case key.Matches(msg, m.keys.Up):
+		m.showMatch(m.searchCurrent - 1)
+		return m, nil
+	case key.Matches(msg, m.keys.Down, m.keys.Enter):
+		m.showMatch(m.searchCurrent + 1)
+		return m, nil
+	}
+
+	query := m.searchInput.Value()
+	var cmd tea.Cmd
+	m.searchInput, cmd = m.searchInput.Update(msg)
+	if m.searchInput.Value() != query {
+		m.runSearch()
+	}
+	return m, cmd
+}
+
+// runSearch finds the typed query in the conversation and shows the most recent match
+func (m *model) runSearch() {
+	m.searchMatches = nil
+	query, opts, err := messages.ParseSearch(m.searchInput.Value())
+	if err == nil {
+		m.searchMatches, err = m.bot.MessageManager.Search(query, opts)
+	}
+	m.inputError = ""
+	if err != nil {
+		m.inputError = err.Error()
+	}
+	m.showMatch(len(m.searchMatches) - 1)
+}
+
+// showMatch highlights a match, wrapping around either end of the conversation,
+// and scrolls the chat to it
+func (m *model) showMatch(i int) {
+	if len(m.searchMatches) == 0 {
+		m.searchCurrent = 0
+		if m.bot.MessageLen() > 0 {
+			m.refreshChat()
+		}
+		return
+	}
+	m.searchCurrent = (i + len(m.searchMatches)) % len(m.searchMatches)
+	m.refreshChat()
+
+	// Center the match when it is out of view
+	line := m.matchLine(m.searchMatches[m.searchCurrent])
+	height := m.viewport.Height - m.viewport.Style.GetVerticalFrameSize()
+	if line < m.viewport.YOffset || line >= m.viewport.YOffset+height {
+		m.viewport.SetYOffset(line - height/2)
+	}
+}
+
+// matchLine returns the chat line a match is shown on
+func (m *model) matchLine(match messages.Match) int {
+	first := -1
+	for line, index := range m.chatLines {
+		if index == match.Index {
+			first = line
+			break
+		}
+	}
+	msg, ok := m.bot.MessageManager.MessageAt(match.Index)
+	if first < 0 || !ok {
+		return 0
+	}
+
+	// Wrap the text before the match as the message is, after its role label
+	shown := messages.Displayed(msg.Role, msg.Content)
+	before := strings.Repeat("x", len(msg.Role)+2) + shown[:min(match.Start, len(shown))]
+	return first + lipgloss.Height(m.wrapChat(before)) - 1
+}
+
+// highlightMatches marks the search matches in the content shown for a message,
+// the current match more strongly than the others
+func (m *model) highlightMatches(index int, content string) string {
+	matchStyle := lipgloss.NewStyle().Background(lipgloss.Color("11")).Foreground(lipgloss.Color("0"))
+	currentStyle := lipgloss.NewStyle().Background(lipgloss.Color("208")).Foreground(lipgloss.Color("0")).Bold(true)
+
+	// Work back from the end so earlier offsets stay valid
+	for i := len(m.searchMatches) - 1; i >= 0; i-- {
+		match := m.searchMatches[i]
+		if match.Index != index || match.End > len(content) {
+			continue
+		}
+		style := matchStyle
+		if i == m.searchCurrent {
+			style = currentStyle
+		}
+		// Style each line on its own, as a multi-line render is padded into a block
+		lines := strings.Split(content[match.Start:match.End], "\n")
+		for j, line := range lines {
+			lines[j] = style.Render(line)
+		}
+		content = content[:match.Start] + strings.Join(lines, "\n") + content[match.End:]
+	}
+	return content
+}
+
+// searchHint reports the matches found and how to move between them, shown above the input
+func (m *model) searchHint() string {
+	if m.focus != focusSearch {
+		return ""
+	}
+	keys := fmt.Sprintf("%s next, %s previous, %s close",
+		formatKeys(m.keys.Down.Keys()), formatKeys(m.keys.Up.Keys()), formatKeys(m.keys.Back.Keys()))
+	switch {
+	case strings.TrimSpace(m.searchInput.Value()) == "":
+		return "Search the conversation, role:user to narrow it, /regexp/ for a pattern - " + keys
+	case len(m.searchMatches) == 0:
+		return "No matches - " + keys
+	}
+	return fmt.Sprintf("Match %d of %d - %s", m.searchCurrent+1, len(m.searchMatches), keys)
+}
+
+func (m *model) findCommand(args []string) tea.Cmd {
+	m.openSearch(strings.Join(args, " "))
+	return nil
+}
@@ -0,0 +1,172 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/kevensen/gollama-bubbletea/internal/bot/sessions"
+)
+
+// saveSession saves the conversation in the chat, starting a session for it the first time
+func (m *model) saveSession() {
+	if m.sessionStore == nil || m.bot.MessageLen() == 0 {
+		return
+	}
+	if m.session == nil {
+		m.session = m.sessionStore.New(time.Now())
+	}
+	m.session.Updated = time.Now()
+	if m.bot.ModelManager != nil {
+		m.session.Model = m.bot.ModelManager.CurrentModel()
+	}
+	m.session.Capture(m.bot.MessageManager)
+	if err := m.sessionStore.Save(m.session); err != nil {
+		m.inputError = err.Error()
+	}
+}
+
+// openSessions shows the saved sessions matching a query over the active tab
+func (m *model) openSessions(query string) {
+	if m.sessionStore == nil {
+		m.inputError = "Sessions aren't saved, the data directory couldn't be found"
+		return
+	}
+	if err := m.sessionStore.Refresh(); err != nil {
+		m.inputError = fmt.Sprintf("Failed to read saved sessions: %v", err)
+		return
+	}
+	m.sessionsReturn = m.focus
+	m.focus = focusSessions
+	m.textarea.Blur()
+	m.inputError = ""
+	m.sessionsInput.SetValue(query)
+	m.sessionsInput.CursorEnd()
+	m.sessionsInput.Focus()
+	m.filterSessions()
+}
+
+// closeSessions returns focus to where it was before the sessions opened
+func (m *model) closeSessions() {
+	m.sessionsInput.Blur()
+	m.focus = m.sessionsReturn
+	if m.focus == focusTextarea {
+		m.textarea.Focus()
+	}
+}
+
+// filterSessions searches the saved sessions for the query
+func (m *model) filterSessions() {
+	m.sessionResults = m.sessionStore.Search(m.sessionsInput.Value())
+	m.sessionSelected = 0
+}
+
+// updateSessions handles a key pressed while the saved sessions are shown
+func (m *model) updateSessions(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Back, m.keys.Quit, m.keys.Sessions):
+		m.closeSessions()
+		return m, nil
+	case key.Matches(msg, m.keys.Up):
+		m.sessionSelected = max(m.sessionSelected-1, 0)
+		return m, nil
+	case key.Matches(msg, m.keys.Down):
+		m.sessionSelected = min(m.sessionSelected+1, max(len(m.sessionResults)-1, 0))
+		return m, nil
+	case key.Matches(msg, m.keys.Enter):
+		if len(m.sessionResults) > 0 {
+			m.closeSessions()
+			m.openSession(m.sessionResults[m.sessionSelected])
+		}
+		return m, nil
+	}
+
+	query := m.sessionsInput.Value()
+	var cmd tea.Cmd
+	m.sessionsInput, cmd = m.sessionsInput.Update(msg)
+	if m.sessionsInput.Value() != query {
+		m.filterSessions()
+	}
+	return m, cmd
+}
+
+// openSession replaces the conversation in the chat with a saved session and
+// selects the message that matched the search
+func (m *model) openSession(result sessions.Result) {
+	if m.isThinking {
+		m.inputError = "Wait for the reply before opening another session"
+		return
+	}
+	// The conversation being replaced is kept with its own session
+	m.saveSession()
+	session, err := m.sessionStore.Load(result.Session.ID)
+	if err != nil {
+		m.inputError = err.Error()
+		return
+	}
+	session.Restore(m.bot.MessageManager)
+	m.session = session
+
+	m.activeTab = chatTab
+	m.focusActiveTab()
+	m.updateTabNames()
+	m.selectMessage(result.Message)
+	m.viewport.GotoBottom()
+	for line, index := range m.chatLines {
+		if index == result.Message {
+			m.viewport.SetYOffset(line)
+			break
+		}
+	}
+}
+
+// sessionsView renders the saved sessions matching the query in place of the active tab
+func (m *model) sessionsView() string {
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	accent := lipgloss.NewStyle().Foreground(lipgloss.Color("62")).Bold(true)
+	if m.darkMode {
+		dim = lipgloss.NewStyle().Foreground(darkModeTextColor)
+		accent = lipgloss.NewStyle().Foreground(darkModeAccentColor).Bold(true)
+	}
+
+	style := m.viewport.Style
+	width := m.viewport.Width - style.GetHorizontalFrameSize()
+	height := m.viewport.Height - style.GetVerticalFrameSize()
+
+	lines := []string{
+		accent.Render("Saved Sessions") + dim.Render(fmt.Sprintf("  %d found - %s to open, %s to close",
+			len(m.sessionResults), formatKeys(m.keys.Enter.Keys()), formatKeys(m.keys.Back.Keys()))),
+		"",
+	}
+	if len(m.sessionResults) == 0 {
+		lines = append(lines, dim.Render("  No sessions"))
+	}
+
+	// Each session takes a line for its date and title and one for the snippet
+	rows := max((height-len(lines))/2, 1)
+	first := max(0, m.sessionSelected-rows+1)
+	for i := first; i < len(m.sessionResults) && i < first+rows; i++ {
+		result := m.sessionResults[i]
+		cursor := "  "
+		title := result.Session.Updated.Local().Format("2006-01-02 15:04") + "  " + result.Session.Title()
+		if i == m.sessionSelected {
+			cursor = "▶ "
+			title = accent.Render(truncateText(title, max(width-2, 1)))
+		} else {
+			title = truncateText(title, max(width-2, 1))
+		}
+		lines = append(lines, cursor+title, "    "+dim.Render(truncateText(result.Snippet, max(width-4, 1))))
+	}
+
+	content := lipgloss.NewStyle().Width(width).Height(height).MaxWidth(width).MaxHeight(height).Render(strings.Join(lines, "\n"))
+	return style.UnsetWidth().UnsetHeight().Render(content)
+}
+
+func (m *model) sessionsCommand(args []string) tea.Cmd {
+	m.openSessions(strings.Join(args, " "))
+	return nil
+}
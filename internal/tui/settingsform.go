@@ -0,0 +1,484 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/kevensen/gollama-bubbletea/internal/bot/rag"
+	"github.com/kevensen/gollama-bubbletea/internal/httpclient"
+	"github.com/kevensen/gollama-bubbletea/internal/settings"
+)
+
+// settingKind is how a setting is edited in the Settings tab form
+type settingKind int
+
+const (
+	settingText   settingKind = iota // Typed into the input box
+	settingBool                      // Toggled with Enter
+	settingChoice                    // Cycled through its choices with Enter
+	settingInfo                      // Shown only, edited in the settings file
+)
+
+// settingField is a row of the Settings tab form
+// New settings become editable by adding a field to settingFields
+type settingField struct {
+	key     string // JSON name, which validation problems are reported under
+	section string
+	label   string
+	kind    settingKind
+	choices []string
+	help    string
+	get     func(s *settings.Settings) string
+	set     func(s *settings.Settings, value string) error
+}
+
+// settingFields lists every setting edited through the form; the server URL,
+// backend and endpoints are edited in the endpoint list above it
+var settingFields = []settingField{
+	{
+		key: "lastModel", section: "General", label: "Model", kind: settingText,
+		help: "Model selected on the active endpoint, also chosen on the Models tab",
+		get:  func(s *settings.Settings) string { return s.LastModel },
+		set: func(s *settings.Settings, value string) error {
+			s.LastModel = value
+			for i := range s.Endpoints {
+				if s.Endpoints[i].Name == s.ActiveEndpoint {
+					s.Endpoints[i].LastModel = value
+				}
+			}
+			return nil
+		},
+	},
+	{
+		key: "darkMode", section: "General", label: "Dark mode", kind: settingBool,
+		get: func(s *settings.Settings) string { return formatBool(s.DarkMode) },
+		set: func(s *settings.Settings, value string) error { s.DarkMode = value == "on"; return nil },
+	},
+	{
+		key: "persona", section: "General", label: "Persona", kind: settingText,
+		help: "System prompt sent ahead of every conversation, empty for none",
+		get:  func(s *settings.Settings) string { return s.Persona },
+		set:  func(s *settings.Settings, value string) error { s.Persona = value; return nil },
+	},
+	{
+		key: "aggregateModels", section: "General", label: "Merge models from all endpoints", kind: settingBool,
+		get: func(s *settings.Settings) string { return formatBool(s.AggregateModels) },
+		set: func(s *settings.Settings, value string) error { s.AggregateModels = value == "on"; return nil },
+	},
+	{
+		key: "profiles", section: "General", label: "Profiles", kind: settingInfo,
+		help: "Apply a profile with /profile <name>; profiles are defined in the settings file",
+		get: func(s *settings.Settings) string {
+			if len(s.Profiles) == 0 {
+				return "none"
+			}
+			names := s.ProfileNames()
+			for i, name := range names {
+				if name == s.ActiveProfile {
+					names[i] = name + " (active)"
+				}
+			}
+			return strings.Join(names, ", ")
+		},
+	},
+	{
+		key: "ragEnabled", section: "RAG", label: "Enabled", kind: settingBool,
+		get: func(s *settings.Settings) string { return formatBool(s.RAGEnabled) },
+		set: func(s *settings.Settings, value string) error { s.RAGEnabled = value == "on"; return nil },
+	},
+	{
+		key: "chromaDBURL", section: "RAG", label: "ChromaDB URL", kind: settingText,
+		help: "e.g. http://localhost:8000",
+		get:  func(s *settings.Settings) string { return s.ChromaDBURL },
+		set:  func(s *settings.Settings, value string) error { s.ChromaDBURL = value; return nil },
+	},
+	{
+		key: "ragCollection", section: "RAG", label: "Collection", kind: settingText,
+		help: "ChromaDB collection, empty for " + rag.DefaultCollection,
+		get:  func(s *settings.Settings) string { return s.RAGCollection },
+		set:  func(s *settings.Settings, value string) error { s.RAGCollection = value; return nil },
+	},
+	{
+		key: "ragMode", section: "RAG", label: "Mode", kind: settingChoice,
+		choices: []string{string(rag.ModeVector), string(rag.ModeKeyword), string(rag.ModeHybrid)},
+		get:     func(s *settings.Settings) string { return s.RAGMode },
+		set:     func(s *settings.Settings, value string) error { s.RAGMode = value; return nil },
+	},
+	{
+		key: "ragTopK", section: "RAG", label: "Top K", kind: settingText,
+		help: "Number of documents retrieved, empty for " + strconv.Itoa(rag.DefaultTopK),
+		get:  func(s *settings.Settings) string { return strconv.Itoa(s.RAGTopK) },
+		set: func(s *settings.Settings, value string) (err error) {
+			s.RAGTopK, err = parseRAGTopK(value)
+			return err
+		},
+	},
+	{
+		key: "ragMaxDistance", section: "RAG", label: "Max distance", kind: settingText,
+		help: "Distance threshold for retrieved documents, 0 for none",
+		get:  func(s *settings.Settings) string { return strconv.FormatFloat(s.RAGMaxDistance, 'g', -1, 64) },
+		set: func(s *settings.Settings, value string) (err error) {
+			s.RAGMaxDistance, err = parseRAGMaxDistance(value)
+			return err
+		},
+	},
+	{
+		key: "ragWhere", section: "RAG", label: "Where filter", kind: settingText,
+		help: `Metadata filter such as {"source": "docs"}, empty for none`,
+		get:  func(s *settings.Settings) string { return rag.FormatWhere(s.RAGWhere) },
+		set: func(s *settings.Settings, value string) (err error) {
+			s.RAGWhere, err = rag.ParseWhere(value)
+			return err
+		},
+	},
+	{
+		key: "ragPromptTemplate", section: "RAG", label: "Prompt template", kind: settingText,
+		help: "Use " + rag.ContextPlaceholder + " and " + rag.QuestionPlaceholder + ", \\n for newlines, empty for default",
+		get:  func(s *settings.Settings) string { return rag.EscapeTemplate(s.RAGPromptTemplate) },
+		set: func(s *settings.Settings, value string) (err error) {
+			s.RAGPromptTemplate, err = parseRAGPromptTemplate(value)
+			return err
+		},
+	},
+	{
+		key: "ragKeepContext", section: "RAG", label: "Re-send past context", kind: settingBool,
+		get: func(s *settings.Settings) string { return formatBool(s.RAGKeepContext) },
+		set: func(s *settings.Settings, value string) error { s.RAGKeepContext = value == "on"; return nil },
+	},
+	{
+		key: "ragRerank", section: "RAG", label: "Re-rank results", kind: settingBool,
+		get: func(s *settings.Settings) string { return formatBool(s.RAGRerank) },
+		set: func(s *settings.Settings, value string) error { s.RAGRerank = value == "on"; return nil },
+	},
+	{
+		key: "ragWatchFolders", section: "RAG", label: "Watch folders", kind: settingText,
+		help: "Comma separated, e.g. ~/docs, ./notes",
+		get:  func(s *settings.Settings) string { return strings.Join(s.RAGWatchFolders, ", ") },
+		set: func(s *settings.Settings, value string) (err error) {
+			s.RAGWatchFolders, err = parseWatchFolders(value)
+			return err
+		},
+	},
+	{
+		key: "ragWatchEnabled", section: "RAG", label: "Keep folders in sync", kind: settingBool,
+		get: func(s *settings.Settings) string { return formatBool(s.RAGWatchEnabled) },
+		set: func(s *settings.Settings, value string) error { s.RAGWatchEnabled = value == "on"; return nil },
+	},
+	{
+		key: "http", section: "HTTP", label: "All servers", kind: settingInfo,
+		help: "Headers, token, TLS and proxy are set in the settings file",
+		get:  func(s *settings.Settings) string { return formatHTTP(&s.HTTP) },
+	},
+	{
+		key: "chromaDBHTTP", section: "HTTP", label: "ChromaDB", kind: settingInfo,
+		help: "Headers, token, TLS and proxy are set in the settings file",
+		get:  func(s *settings.Settings) string { return formatHTTP(s.ChromaDBHTTP) },
+	},
+}
+
+func formatBool(value bool) string {
+	if value {
+		return "on"
+	}
+	return "off"
+}
+
+// formatHTTP summarises HTTP options without revealing secrets
+func formatHTTP(cfg *httpclient.Config) string {
+	if cfg == nil {
+		return "inherited"
+	}
+	var parts []string
+	if len(cfg.Headers) > 0 {
+		parts = append(parts, fmt.Sprintf("%d headers", len(cfg.Headers)))
+	}
+	switch {
+	case cfg.BearerToken != "":
+		parts = append(parts, "bearer token")
+	case cfg.BearerTokenEnv != "":
+		parts = append(parts, "bearer token from $"+cfg.BearerTokenEnv)
+	case cfg.BearerTokenFile != "":
+		parts = append(parts, "bearer token from "+cfg.BearerTokenFile)
+	}
+	if cfg.CAFile != "" {
+		parts = append(parts, "CA "+cfg.CAFile)
+	}
+	if cfg.CertFile != "" {
+		parts = append(parts, "client certificate")
+	}
+	if cfg.Proxy != "" {
+		parts = append(parts, "proxy "+cfg.Proxy)
+	}
+	if cfg.InsecureSkipVerify {
+		parts = append(parts, "TLS verification off")
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// parseRAGTopK parses a number of documents to retrieve, empty for the default
+func parseRAGTopK(value string) (int, error) {
+	if value == "" {
+		return rag.DefaultTopK, nil
+	}
+	topK, err := strconv.Atoi(value)
+	if err != nil || topK < 1 {
+		return 0, fmt.Errorf("top K must be a positive whole number")
+	}
+	return topK, nil
+}
+
+// parseRAGMaxDistance parses a distance threshold, empty for none
+func parseRAGMaxDistance(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	distance, err := strconv.ParseFloat(value, 64)
+	if err != nil || distance < 0 {
+		return 0, fmt.Errorf("max distance must be a non-negative number")
+	}
+	return distance, nil
+}
+
+// parseRAGPromptTemplate parses an escaped prompt template, returning empty for the default
+func parseRAGPromptTemplate(value string) (string, error) {
+	template := rag.UnescapeTemplate(value)
+	if template == "" || template == rag.DefaultPromptTemplate {
+		return "", nil
+	}
+	if err := rag.ValidatePromptTemplate(template); err != nil {
+		return "", err
+	}
+	return template, nil
+}
+
+// parseWatchFolders parses a comma separated list of existing folders
+func parseWatchFolders(value string) ([]string, error) {
+	var folders []string
+	for _, folder := range strings.Split(value, ",") {
+		folder = strings.TrimSpace(folder)
+		if folder == "" {
+			continue
+		}
+		path, err := rag.ExpandPath(folder)
+		if err != nil {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("watch folder %s is not a directory", folder)
+		}
+		folders = append(folders, folder)
+	}
+	return folders, nil
+}
+
+// endpointRows returns the number of Settings tab rows used by the endpoint list
+// An empty list has one row inviting the user to add an endpoint
+func (m *model) endpointRows() int {
+	return max(1, len(m.settings.Endpoints))
+}
+
+// settingsRows returns the number of selectable rows in the Settings tab
+func (m *model) settingsRows() int {
+	return m.endpointRows() + len(settingFields)
+}
+
+// selectedField returns the form field highlighted in the Settings tab, if any
+func (m *model) selectedField() (settingField, bool) {
+	i := m.settingsRow - m.endpointRows()
+	if i < 0 || i >= len(settingFields) {
+		return settingField{}, false
+	}
+	return settingFields[i], true
+}
+
+// formValue returns a field's unsaved value, or its saved value if unchanged
+func (m *model) formValue(field settingField) string {
+	if value, ok := m.formEdits[field.key]; ok {
+		return value
+	}
+	return field.get(m.settings)
+}
+
+// formDirty reports whether the form has unsaved changes
+func (m *model) formDirty() bool {
+	return len(m.formEdits) > 0
+}
+
+// activateField edits the highlighted field: booleans are toggled, choices
+// cycled and text fields opened in the input box
+func (m *model) activateField(field settingField) {
+	value := m.formValue(field)
+	switch field.kind {
+	case settingBool:
+		if value == "on" {
+			value = "off"
+		} else {
+			value = "on"
+		}
+	case settingChoice:
+		next := (slices.Index(field.choices, value) + 1) % len(field.choices)
+		value = field.choices[next]
+	case settingText:
+		m.editingField = field.key
+		m.focus = focusTextarea
+		m.textarea.Reset()
+		m.textarea.Focus()
+		m.textarea.SetValue(value)
+		m.updateInputPlaceholder()
+		return
+	default:
+		m.inputError = field.help
+		return
+	}
+
+	if err := m.setFormValue(field, value); err != nil {
+		m.inputError = err.Error()
+	}
+}
+
+// setFormValue records an unsaved value after checking it parses and validates
+func (m *model) setFormValue(field settingField, value string) error {
+	if value == field.get(m.settings) {
+		delete(m.formEdits, field.key)
+		return nil
+	}
+
+	scratch := *m.settings
+	scratch.Endpoints = slices.Clone(m.settings.Endpoints)
+	if err := field.set(&scratch, value); err != nil {
+		return fmt.Errorf("%s: %v", field.label, err)
+	}
+	for _, problem := range scratch.Validate() {
+		if strings.HasPrefix(problem.Error(), field.key+":") {
+			return fmt.Errorf("%s: %v", field.label, strings.TrimSpace(strings.TrimPrefix(problem.Error(), field.key+":")))
+		}
+	}
+
+	if m.formEdits == nil {
+		m.formEdits = make(map[string]string)
+	}
+	m.formEdits[field.key] = value
+	return nil
+}
+
+// submitFieldEdit stores the value typed for the field being edited
+func (m *model) submitFieldEdit(value string) error {
+	for _, field := range settingFields {
+		if field.key == m.editingField {
+			if err := m.setFormValue(field, strings.TrimSpace(value)); err != nil {
+				return err
+			}
+		}
+	}
+	m.cancelFieldEdit()
+	return nil
+}
+
+// cancelFieldEdit returns focus from the input box to the form
+func (m *model) cancelFieldEdit() {
+	m.editingField = ""
+	m.focus = focusSettingsViewport
+	m.textarea.Reset()
+	m.textarea.Blur()
+	m.updateInputPlaceholder()
+}
+
+// saveSettingsForm saves every unsaved value together and applies them
+func (m *model) saveSettingsForm() tea.Cmd {
+	if !m.formDirty() {
+		return nil
+	}
+
+	previous := *m.settings
+	err := m.settings.Update(func(s *settings.Settings) error {
+		for _, field := range settingFields {
+			if value, ok := m.formEdits[field.key]; ok {
+				if err := field.set(s, value); err != nil {
+					return fmt.Errorf("%s: %v", field.label, err)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		m.inputError = "Settings not saved: " + err.Error()
+		return nil
+	}
+
+	m.formEdits = nil
+	m.inputError = ""
+	return m.syncWithSettings(&previous)
+}
+
+// revertSettingsForm discards unsaved values
+func (m *model) revertSettingsForm() {
+	m.formEdits = nil
+	m.inputError = ""
+}
+
+// settingsFormLines renders the form, returning the line of the highlighted row
+func (m *model) settingsFormLines() ([]string, int) {
+	accent := lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	if m.darkMode {
+		accent = lipgloss.NewStyle().Foreground(darkModeAccentColor)
+		dim = lipgloss.NewStyle().Foreground(darkModeTextColor)
+	}
+
+	labelWidth := 0
+	for _, field := range settingFields {
+		labelWidth = max(labelWidth, len(field.label))
+	}
+
+	header := "Settings"
+	if m.formDirty() {
+		header += accent.Render(fmt.Sprintf("  ● %d unsaved - %s to save, %s to revert",
+			len(m.formEdits), formatKeys(m.keys.SettingsSave.Keys()), formatKeys(m.keys.SettingsRevert.Keys())))
+	}
+	lines := []string{header}
+
+	selected, selectedLine := -1, 0
+	if field, ok := m.selectedField(); ok {
+		selected = slices.IndexFunc(settingFields, func(f settingField) bool { return f.key == field.key })
+	}
+
+	section := ""
+	for i, field := range settingFields {
+		if field.section != section {
+			section = field.section
+			lines = append(lines, "", "  "+section)
+		}
+
+		cursor := "  "
+		if i == selected {
+			cursor = "▶ "
+			selectedLine = len(lines)
+		}
+		dirty := "  "
+		if _, ok := m.formEdits[field.key]; ok {
+			dirty = accent.Render("● ")
+		}
+
+		value := m.formValue(field)
+		switch {
+		case field.kind == settingInfo:
+			value = dim.Render(value)
+		case value == "":
+			value = dim.Render("(empty)")
+		}
+		lines = append(lines, fmt.Sprintf("%s%s%-*s  %s", cursor, dirty, labelWidth, field.label, value))
+
+		if i == selected && field.help != "" {
+			lines = append(lines, dim.Render("      "+field.help))
+		}
+	}
+	return lines, selectedLine
+}
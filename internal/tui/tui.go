@@ -1,17 +1,29 @@
 package tui
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/kevensen/gollama-bubbletea/internal/bot"
+	"github.com/kevensen/gollama-bubbletea/internal/bot/attachments"
+	"github.com/kevensen/gollama-bubbletea/internal/bot/messages"
+	"github.com/kevensen/gollama-bubbletea/internal/bot/rag"
+	"github.com/kevensen/gollama-bubbletea/internal/bot/sessions"
+	"github.com/kevensen/gollama-bubbletea/internal/httpclient"
 	"github.com/kevensen/gollama-bubbletea/internal/settings"
 	"github.com/parakeet-nest/parakeet/llm"
 )
@@ -27,6 +39,59 @@ type tickMsg time.Time
 type chatResponseMsg struct {
 	response *llm.Answer
 	err      error
+	context  string // Retrieval-augmented prompt sent in place of the message, empty without RAG
+}
+
+// chatChunkMsg carries part of a reply as it is streamed, with the stream to read the rest from
+type chatChunkMsg struct {
+	content string
+	stream  <-chan tea.Msg
+}
+
+// ragSyncMsg is sent when a knowledge base sync finishes
+type ragSyncMsg struct {
+	stats  rag.SyncStats
+	err    error
+	status *ragStatusMsg // Knowledge base after the sync, nil when the syncer couldn't be created
+}
+
+// ragStatusMsg carries the knowledge base status of a ChromaDB URL, read off the UI goroutine
+type ragStatusMsg struct {
+	chromaDBURL string
+	status      rag.SyncStatus
+}
+
+// ragWatchTickMsg is sent when the watch folders should be checked for changes
+// seq identifies the watch schedule so stale ticks can be ignored
+type ragWatchTickMsg struct {
+	seq int
+}
+
+// endpointStatusMsg is sent when the reachability of the configured endpoints has been checked
+// A nil error means the endpoint is reachable
+type endpointStatusMsg struct {
+	statuses map[string]error
+}
+
+// ragWatchInterval is how often watch folders are checked while watching is enabled
+const ragWatchInterval = 30 * time.Second
+
+// settingsFileMsg reports whether the settings file was edited outside the TUI
+type settingsFileMsg struct {
+	fresh   *settings.Settings // Settings read from the edited file
+	changed bool
+	err     error
+}
+
+// settingsWatchInterval is how often the settings file is checked for external edits
+const settingsWatchInterval = 2 * time.Second
+
+// ragInspectMsg is sent when a RAG inspector query has been retrieved
+type ragInspectMsg struct {
+	query   string
+	results []rag.Result
+	prompt  string
+	err     error
 }
 
 // Tab styling
@@ -90,26 +155,318 @@ func (m *model) getThinkingIndicator() string {
 	}
 
 	style := lipgloss.NewStyle().Foreground(color)
-	return style.Render(fmt.Sprintf("%s Assistant is thinking...", frame))
+	return style.Render(fmt.Sprintf("%s Assistant is thinking... (%s to cancel)", frame, formatKeys(m.keys.Back.Keys())))
 }
 
 // sendChatMessage creates a command to send a message asynchronously
+// The reply is streamed as chatChunkMsg values and ends with a chatResponseMsg;
+// it can be stopped with m.cancelChat until then
 func (m *model) sendChatMessage(input string) tea.Cmd {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancelChat = cancel
+	useRAG := m.ragEnabled && m.settings.ChromaDBURL != ""
+	chromaDBURL := m.settings.ChromaDBURL
+	opts := m.ragOptions()
+
 	return func() tea.Msg {
-		ctx := context.Background()
-		var ans *llm.Answer
-		var err error
+		stream := make(chan tea.Msg)
+		go func() {
+			onChunk := func(chunk llm.Answer) error {
+				if chunk.Message.Content == "" {
+					return nil
+				}
+				select {
+				case stream <- chatChunkMsg{content: chunk.Message.Content, stream: stream}:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
 
-		// Use RAG if enabled and ChromaDB URL is configured
-		if m.ragEnabled && m.settings.ChromaDBURL != "" {
-			ans, err = m.bot.SendRAGMessageWithoutAdding(ctx, "user", input, m.settings.ChromaDBURL)
-		} else {
-			// Regular message handling
-			ans, err = m.bot.SendMessageWithoutAdding(ctx, "user", input)
+			var ans *llm.Answer
+			var retrievalContext string
+			var err error
+
+			// Use RAG if enabled and ChromaDB URL is configured
+			if useRAG {
+				ans, retrievalContext, err = m.bot.SendRAGMessageWithoutAdding(ctx, "user", input, chromaDBURL, opts, onChunk)
+			} else {
+				// Regular message handling
+				ans, err = m.bot.SendMessageWithoutAdding(ctx, onChunk)
+			}
+			// The history is only changed by Update, as the chat reads it while this runs
+			stream <- chatResponseMsg{response: ans, err: err, context: retrievalContext}
+		}()
+		return <-stream
+	}
+}
+
+// waitForChat reads the next part of a streamed reply
+func waitForChat(stream <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-stream
+	}
+}
+
+// quit stops any reply being received and quits
+func (m *model) quit() tea.Cmd {
+	if m.cancelChat != nil {
+		m.cancelChat()
+	}
+	return tea.Quit
+}
+
+// inspectRAGQuery creates a command to retrieve chunks and build the prompt for a query without sending it
+func (m *model) inspectRAGQuery(query string) tea.Cmd {
+	chromaDBURL := m.settings.ChromaDBURL
+	opts := m.ragOptions()
+	return func() tea.Msg {
+		results, prompt, err := m.bot.InspectRAG(context.Background(), chromaDBURL, query, opts)
+		return ragInspectMsg{query: query, results: results, prompt: prompt, err: err}
+	}
+}
+
+// syncKnowledgeBase creates a command to sync the configured watch folders into the knowledge base
+func (m *model) syncKnowledgeBase() tea.Cmd {
+	chromaDBURL := m.settings.ChromaDBURL
+	folders := append([]string(nil), m.settings.RAGWatchFolders...)
+	m.ragSyncing = true
+	return func() tea.Msg {
+		dataDir, err := settings.DataDir()
+		if err != nil {
+			return ragSyncMsg{err: err}
+		}
+		syncer, err := m.bot.KnowledgeBaseSyncer(chromaDBURL, dataDir)
+		if err != nil {
+			return ragSyncMsg{err: err}
+		}
+		stats, err := syncer.Sync(context.Background(), folders)
+		return ragSyncMsg{stats: stats, err: err, status: &ragStatusMsg{chromaDBURL, syncer.Status()}}
+	}
+}
+
+// loadRAGStatus creates a command to read the knowledge base status recorded by
+// earlier syncs, so the RAG tab can show it without touching the disk
+func (m *model) loadRAGStatus() tea.Cmd {
+	chromaDBURL := m.settings.ChromaDBURL
+	if chromaDBURL == "" {
+		return nil
+	}
+	return func() tea.Msg {
+		dataDir, err := settings.DataDir()
+		if err != nil {
+			return nil
+		}
+		syncer, err := m.bot.KnowledgeBaseSyncer(chromaDBURL, dataDir)
+		if err != nil {
+			return nil
+		}
+		return ragStatusMsg{chromaDBURL, syncer.Status()}
+	}
+}
+
+// checkEndpoints tests every configured endpoint concurrently
+func (m *model) checkEndpoints() tea.Cmd {
+	if len(m.settings.Endpoints) == 0 {
+		return nil
+	}
+	providers := make(map[string]bot.Provider)
+	for _, endpoint := range m.settings.Endpoints {
+		providers[endpoint.Name] = bot.EndpointProvider(m.settings, endpoint)
+	}
+	m.endpointChecking = true
+	return func() tea.Msg {
+		var (
+			mu       sync.Mutex
+			wg       sync.WaitGroup
+			statuses = make(map[string]error)
+		)
+		for name, provider := range providers {
+			wg.Add(1)
+			go func(name string, provider bot.Provider) {
+				defer wg.Done()
+				err := bot.TestConnection(provider)
+				mu.Lock()
+				statuses[name] = err
+				mu.Unlock()
+			}(name, provider)
+		}
+		wg.Wait()
+		return endpointStatusMsg{statuses: statuses}
+	}
+}
+
+// selectedEndpointName returns the name of the endpoint highlighted in the Settings tab
+func (m *model) selectedEndpointName() string {
+	if m.settingsRow < 0 || m.settingsRow >= len(m.settings.Endpoints) {
+		return ""
+	}
+	return m.settings.Endpoints[m.settingsRow].Name
+}
+
+// startEndpointEdit moves focus to the textarea to edit an endpoint URL or add a new endpoint
+func (m *model) startEndpointEdit(name string, adding bool) {
+	m.editingEndpoint = name
+	m.addingEndpoint = adding
+	m.focus = focusTextarea
+	m.textarea.Reset()
+	m.textarea.Focus()
+	// Pre-fill with the current URL if any for editing
+	if endpoint, ok := m.settings.Endpoint(name); ok && !adding {
+		m.textarea.SetValue(endpoint.URL)
+	}
+	m.updateInputPlaceholder()
+}
+
+// switchEndpoint makes an endpoint active if it is reachable and loads its models
+func (m *model) switchEndpoint(name string) {
+	endpoint, ok := m.settings.Endpoint(name)
+	if !ok {
+		return
+	}
+
+	provider := bot.EndpointProvider(m.settings, endpoint)
+	if err := bot.TestConnection(provider); err != nil {
+		m.inputError = "Endpoint " + name + " is not reachable - keeping current endpoint"
+		return
+	}
+
+	m.settings.UseEndpoint(name)
+	if err := m.connectProvider(bot.SettingsProvider(m.settings)); err != nil {
+		m.inputError = err.Error()
+		m.bot.ModelManager = nil
+	}
+}
+
+// reconnect rebuilds the backend from settings after the endpoints changed
+func (m *model) reconnect() {
+	if m.settings.OllamaURL == "" {
+		return
+	}
+	if err := m.connectProvider(bot.SettingsProvider(m.settings)); err != nil {
+		m.connectionValid = false
+		m.bot.ModelManager = nil
+		m.inputError = "Connection failed: " + err.Error()
+	}
+}
+
+// applyProfile applies a settings profile and brings every tab up to date with it
+func (m *model) applyProfile(name string) tea.Cmd {
+	previous := *m.settings
+	if err := m.settings.ApplyProfile(name); err != nil {
+		m.inputError = "Failed to apply profile: " + err.Error()
+		return nil
+	}
+
+	if profile, _ := m.settings.Profile(name); profile.Model != "" && bot.Aggregating(m.settings) {
+		// The merged model list needs the model qualified with the endpoint serving it
+		active := m.settings.ActiveEndpoint
+		m.settings.SetAggregateLastModel(profile.Model, active, bot.QualifyModel(profile.Model, active))
+	}
+	return m.syncWithSettings(&previous)
+}
+
+// watchSettings checks the settings file for external edits after settingsWatchInterval
+func (m *model) watchSettings() tea.Cmd {
+	path, err := m.settings.Path()
+	if err != nil {
+		return nil
+	}
+	since := m.settings.Stamp()
+	return tea.Tick(settingsWatchInterval, func(time.Time) tea.Msg {
+		fresh, changed, err := settings.LoadIfChanged(path, since)
+		return settingsFileMsg{fresh: fresh, changed: changed, err: err}
+	})
+}
+
+// reloadSettings applies settings edited outside the TUI, keeping the current
+// settings if the edited file is invalid
+func (m *model) reloadSettings(fresh *settings.Settings) tea.Cmd {
+	previous := *m.settings
+	if err := m.settings.Reload(fresh); err != nil {
+		// Report each rejected edit once rather than on every check
+		m.settingsRejected = fresh.Stamp()
+		m.settingsReloadErr = err
+		m.inputError = err.Error()
+		m.updateSettingsViewportContent()
+		return nil
+	}
+
+	m.settingsReloadErr = nil
+	m.inputError = ""
+	return m.syncWithSettings(&previous)
+}
+
+// syncWithSettings brings the bot and every tab up to date after several settings
+// changed at once, reconnecting only if the server settings changed
+func (m *model) syncWithSettings(previous *settings.Settings) tea.Cmd {
+	m.darkMode = m.settings.DarkMode
+	m.applyTheme()
+	m.ragEnabled = m.settings.RAGEnabled
+	m.bot.SetPersona(m.settings.Persona)
+	m.bot.SetRAGCollection(m.settings.RAGCollection)
+	if !reflect.DeepEqual(previous.ChromaDBHTTPConfig(), m.settings.ChromaDBHTTPConfig()) {
+		m.bot.SetChromaHTTP(httpclient.New(m.settings.ChromaDBHTTPConfig()))
+	}
+	if m.settings.RAGKeepContext {
+		m.bot.MessageManager.SetContextPolicy(messages.ContextPolicyKeep)
+	} else {
+		m.bot.MessageManager.SetContextPolicy(messages.ContextPolicyDrop)
+	}
+
+	m.settingsRow = 0
+	for i, endpoint := range m.settings.Endpoints {
+		if endpoint.Name == m.settings.ActiveEndpoint {
+			m.settingsRow = i
+		}
+	}
+	if serverChanged(previous, m.settings) {
+		m.reconnect()
+	}
+	if !reflect.DeepEqual(previous.Keys, m.settings.Keys) {
+		m.keys, m.keyProblems = newKeyMap(m.settings.Keys)
+		m.textarea.KeyMap.InsertNewline = m.keys.Newline
+		if m.bot.MessageLen() == 0 {
+			m.viewport.SetContent(m.wrapChat(welcomeText(m.keys)))
 		}
+	}
+
+	m.updateTabNames()
+	m.updateModelsViewportContent()
+	m.updateRAGViewportContent()
+	m.updateSettingsViewportContent()
+	m.updateInputPlaceholder()
 
-		return chatResponseMsg{response: ans, err: err}
+	cmds := []tea.Cmd{m.checkEndpoints()}
+	if m.settings.RAGWatchEnabled && !previous.RAGWatchEnabled && m.ragSyncReady() && !m.ragSyncing {
+		cmds = append(cmds, m.syncKnowledgeBase())
 	}
+	return tea.Batch(cmds...)
+}
+
+// serverChanged reports whether the backend or its model must be set up again
+func serverChanged(previous, current *settings.Settings) bool {
+	return previous.OllamaURL != current.OllamaURL ||
+		previous.Provider != current.Provider ||
+		previous.ActiveEndpoint != current.ActiveEndpoint ||
+		bot.Aggregating(previous) != bot.Aggregating(current) ||
+		lastModel(previous) != lastModel(current) ||
+		!reflect.DeepEqual(previous.Endpoints, current.Endpoints) ||
+		!reflect.DeepEqual(previous.HTTP, current.HTTP)
+}
+
+// scheduleRAGWatch schedules the next watch folder check, superseding any earlier schedule
+func (m *model) scheduleRAGWatch() tea.Cmd {
+	m.ragWatchSeq++
+	seq := m.ragWatchSeq
+	return tea.Tick(ragWatchInterval, func(time.Time) tea.Msg {
+		return ragWatchTickMsg{seq: seq}
+	})
+}
+
+// ragSyncReady returns true when there is somewhere to sync from and to
+func (m *model) ragSyncReady() bool {
+	return m.settings.ChromaDBURL != "" && len(m.settings.RAGWatchFolders) > 0
 }
 
 type focus int
@@ -121,6 +478,22 @@ const (
 	focusSettingsViewport
 	focusURLInput
 	focusChromaDBInput
+	focusRAGOptionInput
+	focusRAGInspectInput
+	focusPalette
+	focusSearch
+	focusSessions
+)
+
+// ragField identifies which RAG retrieval option is being edited
+type ragField int
+
+const (
+	ragFieldTopK ragField = iota
+	ragFieldMaxDistance
+	ragFieldWhere
+	ragFieldPromptTemplate
+	ragFieldWatchFolders
 )
 
 type tab int
@@ -138,8 +511,22 @@ type model struct {
 	ragViewport       viewport.Model
 	settingsViewport  viewport.Model
 	textarea          textarea.Model
-	urlTextInput      textinput.Model // Text input for URL in settings
-	chromaDBTextInput textinput.Model // Text input for ChromaDB URL in RAG tab
+	urlTextInput      textinput.Model  // Text input for URL in settings
+	chromaDBTextInput textinput.Model  // Text input for ChromaDB URL in RAG tab
+	ragOptionInput    textinput.Model  // Text input for RAG retrieval options in RAG tab
+	ragOptionField    ragField         // Which RAG option ragOptionInput is editing
+	ragInspectInput   textinput.Model  // Text input for RAG inspector queries
+	ragInspecting     bool             // Whether the RAG tab shows the query inspector
+	ragInspection     *ragInspectMsg   // Latest inspector result, nil while retrieving
+	ragSyncing        bool             // Whether a knowledge base sync is running
+	ragSyncResult     *ragSyncMsg      // Result of the latest sync run in this session
+	ragStatus         *ragStatusMsg    // Latest known knowledge base status, shown on the RAG tab
+	ragWatchSeq       int              // Sequence number of the current watch schedule
+	settingsRow       int              // Row highlighted in the Settings tab, endpoints before form fields
+	endpointStatus    map[string]error // Reachability of each endpoint, nil error when reachable
+	endpointChecking  bool             // Whether endpoint reachability is being checked
+	editingEndpoint   string           // Endpoint whose URL is being edited in the textarea
+	addingEndpoint    bool             // Whether the textarea holds a new "name URL" endpoint
 	senderStyle       lipgloss.Style
 	bot               *bot.Bot
 	err               error
@@ -157,20 +544,52 @@ type model struct {
 	darkMode          bool   // Dark mode state
 	isThinking        bool   // Whether the bot is currently processing a response
 	thinkingFrame     int    // Current frame of the thinking animation
+
+	settingsReloadErr error              // Why the last external edit of the settings file was rejected
+	settingsRejected  settings.FileStamp // The rejected edit, so it is reported only once
+	formEdits         map[string]string  // Unsaved Settings tab form values by setting key
+	editingField      string             // Form field whose value is being typed in the textarea
+
+	keys        keyMap  // Key bindings, the defaults with the keys setting applied
+	keyProblems []error // Why parts of the keys setting were ignored
+
+	paletteInput    textinput.Model // Query typed in the command palette
+	paletteMatches  []paletteEntry  // Entries matching the query, best first
+	paletteSelected int             // Highlighted entry in paletteMatches
+	paletteReturn   focus           // Focus to restore when the palette closes
+
+	windowWidth  int // Terminal width, 0 until the terminal size is known
+	windowHeight int // Terminal height, shared between the viewports and the growing prompt input
+
+	attachments []attachments.File  // Files sent with the next chat message
+	images      []attachments.Image // Images sent with the next chat message
+
+	selectedMessage int   // Conversation position of the message clicked in the chat, -1 for none
+	chatLines       []int // Conversation position of the message on each chat line, -1 for spacing
+	modelLines      []int // Line of each model in the Models viewport
+
+	searchInput   textinput.Model  // Query typed in the conversation search bar
+	searchMatches []messages.Match // Matches of the query, in conversation order
+	searchCurrent int              // Match in searchMatches the chat is scrolled to
+
+	sessionStore    *sessions.Store   // Saved conversations, nil when there is no data directory
+	session         *sessions.Session // Session the chat is saved to, nil until the first reply
+	sessionsInput   textinput.Model   // Query typed to search saved sessions
+	sessionResults  []sessions.Result // Saved sessions matching the query, best first
+	sessionSelected int               // Highlighted result in sessionResults
+	sessionsReturn  focus             // Focus to restore when the sessions close
+
+	cancelChat context.CancelFunc // Stops the reply being received, nil when none is
 }
 
-func New(b *bot.Bot) *model {
-	// Load settings
-	appSettings, err := settings.Load()
-	if err != nil {
-		// If settings can't be loaded, use defaults
-		appSettings = settings.DefaultSettings()
-	}
+// New creates the TUI model sharing the settings the bot was created from
+// The settings are kept up to date with edits made to the settings file while running
+func New(b *bot.Bot, appSettings *settings.Settings) *model {
 
 	// Test connection to determine initial state
 	connectionValid := false
 	if appSettings.OllamaURL != "" {
-		if bot.TestConnection(appSettings.OllamaURL) == nil {
+		if bot.TestConnection(bot.SettingsProvider(appSettings)) == nil {
 			connectionValid = true
 		}
 	}
@@ -179,15 +598,15 @@ func New(b *bot.Bot) *model {
 	ta.Placeholder = "Send a message..."
 
 	ta.Prompt = "┃ "
-	ta.CharLimit = 280
+	ta.CharLimit = 0 // Unlimited, so pasted code and stack traces are kept whole
+	ta.MaxHeight = 0 // The height follows the text, see resizeInput
 
 	ta.SetWidth(30)
-	ta.SetHeight(3)
+	ta.SetHeight(inputMinHeight)
 
 	// Remove cursor line styling
 	ta.FocusedStyle.CursorLine = lipgloss.NewStyle()
 	ta.ShowLineNumbers = false
-	ta.KeyMap.InsertNewline.SetEnabled(false)
 
 	// Initialize URL text input for settings
 	urlInput := textinput.New()
@@ -207,27 +626,44 @@ func New(b *bot.Bot) *model {
 		chromaDBInput.SetValue(appSettings.ChromaDBURL)
 	}
 
+	// Initialize RAG option input for the RAG tab, configured per field when editing starts
+	ragOptionInput := textinput.New()
+	ragOptionInput.Width = 60
+
+	// Initialize RAG inspector input for the RAG tab
+	ragInspectInput := textinput.New()
+	ragInspectInput.Placeholder = "Query to inspect (not sent to the model)"
+	ragInspectInput.Width = 60
+	ragInspectInput.Prompt = "Inspect: "
+
+	// Initialize the command palette query input
+	paletteInput := textinput.New()
+	paletteInput.Placeholder = "Type to filter commands, models and actions"
+	paletteInput.Width = 60
+	paletteInput.Prompt = "> "
+
+	// Initialize the conversation search input
+	searchInput := textinput.New()
+	searchInput.Placeholder = "Text, /regexp/ or role:user"
+	searchInput.Width = 60
+	searchInput.Prompt = "Find: "
+
+	// Initialize the saved sessions query input
+	sessionsInput := textinput.New()
+	sessionsInput.Placeholder = "Words from any saved conversation"
+	sessionsInput.Width = 60
+	sessionsInput.Prompt = "Sessions: "
+
+	// Conversations are saved in the data directory, when there is one
+	var sessionStore *sessions.Store
+	if dataDir, err := settings.DataDir(); err == nil {
+		sessionStore = sessions.NewStore(filepath.Join(dataDir, "sessions"))
+	}
+
 	vp := viewport.New(30, 5)
-	vp.SetContent(`Welcome to Gollama-Chat!
-Type a message and press Enter to send.` + ascii + `
-
-Use the Tab key to switch between Chat, Models, RAG, and Settings tabs.
-Use Ctrl+T to focus the input field for commands from any tab.
-
-Special commands:
-  • /clear - clear chat history
-  • /chat - switch to chat tab
-  • /models - switch to models tab
-  • /rag - switch to RAG tab
-  • /settings - switch to settings tab
-  • /dark - toggle dark mode
-  • /exit or /quit - quit application
-
-Key bindings:
-  • Ctrl+U - clear input
-  • Ctrl+A - go to start
-  • Ctrl+E - go to end
-  • Ctrl+C or Esc - quit`)
+	keys, keyProblems := newKeyMap(appSettings.Keys)
+	ta.KeyMap.InsertNewline = keys.Newline
+	vp.SetContent(welcomeText(keys))
 
 	vp.Style = lipgloss.NewStyle().
 		BorderStyle(lipgloss.RoundedBorder()).
@@ -275,15 +711,27 @@ Key bindings:
 		ta.Focus()
 		urlInput.Blur()
 		// Apply saved last model if it exists and is valid
-		if appSettings.LastModel != "" && b.ModelManager != nil {
-			err := b.ModelManager.UseModel(appSettings.LastModel)
-			if err != nil {
+		if last := lastModel(appSettings); last != "" && b.ModelManager != nil {
+			err := b.ModelManager.UseModel(last)
+			if err != nil && !bot.Aggregating(appSettings) {
 				// If saved model is invalid, keep default but don't error
 				appSettings.LastModel = b.ModelManager.CurrentModel()
 			}
 		}
 	}
 
+	// ChromaDB may sit behind a proxy needing its own credentials
+	b.SetChromaHTTP(httpclient.New(appSettings.ChromaDBHTTPConfig()))
+	b.SetRAGCollection(appSettings.RAGCollection)
+	b.SetPersona(appSettings.Persona)
+
+	// Apply the saved retrieval context policy to the conversation history
+	if appSettings.RAGKeepContext {
+		b.MessageManager.SetContextPolicy(messages.ContextPolicyKeep)
+	} else {
+		b.MessageManager.SetContextPolicy(messages.ContextPolicyDrop)
+	}
+
 	// Initialize models list if we have a valid connection
 	var initialModels []string
 	if connectionValid && b.ModelManager != nil {
@@ -298,11 +746,14 @@ Key bindings:
 		settingsViewport:  settingsVp,
 		urlTextInput:      urlInput,
 		chromaDBTextInput: chromaDBInput,
+		ragOptionInput:    ragOptionInput,
+		ragInspectInput:   ragInspectInput,
 		senderStyle:       lipgloss.NewStyle().Foreground(lipgloss.Color("5")),
 		bot:               b,
 		err:               nil,
 		focus:             initialFocus,
 		selectedModel:     0,
+		selectedMessage:   -1,
 		activeTab:         initialTab,
 		tabs:              []string{"Chat", "Models", "RAG", "Settings"},
 		ragEnabled:        appSettings.RAGEnabled, // Load RAG state from settings
@@ -311,7 +762,30 @@ Key bindings:
 		urlInput:          appSettings.OllamaURL,
 		darkMode:          appSettings.DarkMode, // Load dark mode state from settings
 		models:            initialModels,        // Initialize models list
+		keys:              keys,
+		keyProblems:       keyProblems,
+		paletteInput:      paletteInput,
+		searchInput:       searchInput,
+		sessionsInput:     sessionsInput,
+		sessionStore:      sessionStore,
+	}
+}
+
+// welcomeText is shown in the Chat tab before the first message, listing the current key bindings
+func welcomeText(keys keyMap) string {
+	lines := []string{
+		"Welcome to Gollama-Chat!",
+		"Type a message and press " + formatKeys(keys.Enter.Keys()) + " to send." + ascii,
+		"",
+		"Use " + formatKeys(keys.NextTab.Keys()) + " to switch between Chat, Models, RAG, and Settings tabs.",
+		"Use " + formatKeys(keys.FocusInput.Keys()) + " to focus the input field for commands from any tab.",
+		"",
+		"Special commands:",
 	}
+	lines = append(lines, commandHelpLines()...)
+	lines = append(lines, "", "Key bindings:")
+	lines = append(lines, keys.keyHelpLines(scopeGlobal)...)
+	return strings.Join(lines, "\n")
 }
 
 func (m *model) handleChatResponse(resp llm.Answer) error {
@@ -320,7 +794,7 @@ func (m *model) handleChatResponse(resp llm.Answer) error {
 		m.isThinking = false // Stop thinking indicator
 		m.bot.MessageManager.AddMessage(resp.Message)
 		m.responseBuffer = ""
-		m.viewport.SetContent(lipgloss.NewStyle().Width(m.viewport.Width).Render(strings.Join(m.bot.MessageManager.StyledMessages(), "\n")))
+		m.refreshChat()
 		m.viewport.GotoBottom()
 		// Update tab names to reflect new token count
 		m.updateTabNames()
@@ -473,12 +947,24 @@ func (m *model) getTabStyles() (inactive, active, inactiveModels, activeModels,
 
 func (m *model) Init() tea.Cmd {
 	m.applyTheme() // Apply theme first
+	// Start the endpoint picker on the active endpoint
+	for i, endpoint := range m.settings.Endpoints {
+		if endpoint.Name == m.settings.ActiveEndpoint {
+			m.settingsRow = i
+		}
+	}
 	m.updateTabNames()
 	m.updateModelsViewportContent()
 	m.updateRAGViewportContent()
 	m.updateSettingsViewportContent()
 	m.updateInputPlaceholder()
-	return tea.Batch(textarea.Blink, tickEvery(100*time.Millisecond))
+
+	cmds := []tea.Cmd{textarea.Blink, tickEvery(100 * time.Millisecond), m.checkEndpoints(), m.watchSettings(), m.loadRAGStatus()}
+	// Bring the knowledge base up to date on startup when watching is enabled
+	if m.settings.RAGWatchEnabled && m.ragSyncReady() {
+		cmds = append(cmds, m.syncKnowledgeBase())
+	}
+	return tea.Batch(cmds...)
 }
 
 func (m *model) fetchModels() tea.Msg {
@@ -527,9 +1013,26 @@ func (m *model) updateModelsViewportContent() {
 
 	currentModel := m.bot.ModelManager.CurrentModel()
 	styledModels := []string{"Available Models:", ""}
+	m.modelLines = nil
+	grouped := bot.Aggregating(m.settings)
+	lastHost := ""
 	for i, model := range m.models {
 		style := lipgloss.NewStyle()
 		prefix := "  "
+		name := model
+
+		// Merged lists show a heading per host and the plain model names below it
+		if grouped {
+			var host string
+			name, host = bot.SplitModel(model)
+			if host != lastHost {
+				if lastHost != "" {
+					styledModels = append(styledModels, "")
+				}
+				styledModels = append(styledModels, lipgloss.NewStyle().Bold(true).Render(host))
+				lastHost = host
+			}
+		}
 
 		if model == currentModel {
 			// Use theme-aware colors
@@ -547,23 +1050,32 @@ func (m *model) updateModelsViewportContent() {
 				style = style.Background(lipgloss.Color("7")).Foreground(lipgloss.Color("0"))
 			}
 		}
-		styledModels = append(styledModels, prefix+style.Render(model))
+		m.modelLines = append(m.modelLines, len(styledModels))
+		styledModels = append(styledModels, prefix+style.Render(name))
 	}
 
 	// Add instructions
-	styledModels = append(styledModels, "", "Controls:", "↑/↓ - Navigate", "Enter - Select Model", "Tab - Switch tabs")
+	styledModels = append(styledModels, "", "Controls:",
+		controlLine("Navigate", m.keys.Up, m.keys.Down),
+		controlLine("Select Model", m.keys.Enter),
+		controlLine("Switch tabs", m.keys.NextTab))
 
 	m.modelsViewport.SetContent(strings.Join(styledModels, "\n"))
 }
 
 func (m *model) updateRAGViewportContent() {
+	if m.ragInspecting {
+		m.updateRAGInspectorContent()
+		return
+	}
+
 	var statusColor lipgloss.Color
 	statusText := "DISABLED"
-	toggleText := "Press Enter to Enable"
+	toggleText := "Press " + formatKeys(m.keys.Enter.Keys()) + " to Enable"
 
 	if m.ragEnabled {
 		statusText = "ENABLED"
-		toggleText = "Press Enter to Disable"
+		toggleText = "Press " + formatKeys(m.keys.Enter.Keys()) + " to Disable"
 	}
 
 	// Use theme-aware colors
@@ -599,30 +1111,342 @@ func (m *model) updateRAGViewportContent() {
 		}
 	}
 
+	// Retrieval options
+	opts := m.ragOptions()
+	maxDistance := "none"
+	if opts.MaxDistance > 0 {
+		maxDistance = strconv.FormatFloat(opts.MaxDistance, 'g', -1, 64)
+	}
+	where := rag.FormatWhere(opts.Where)
+	if where == "" {
+		where = "none"
+	}
+	template := "(default)"
+	if strings.TrimSpace(m.settings.RAGPromptTemplate) != "" {
+		template = rag.EscapeTemplate(m.settings.RAGPromptTemplate)
+	}
+	rerank := "off"
+	if opts.Rerank {
+		rerank = "on (scored by current model)"
+	}
+	pastContext := "dropped on later turns"
+	if m.settings.RAGKeepContext {
+		pastContext = "re-sent on later turns"
+	}
+	folders := "none"
+	if len(m.settings.RAGWatchFolders) > 0 {
+		folders = strings.Join(m.settings.RAGWatchFolders, ", ")
+	}
+	collection := m.settings.RAGCollection
+	if collection == "" {
+		collection = rag.DefaultCollection
+	}
+	watching := "off"
+	if m.settings.RAGWatchEnabled {
+		watching = fmt.Sprintf("on (every %s)", ragWatchInterval)
+	}
+
 	content := []string{
 		"RAG Settings",
 		"",
 		"Status: " + lipgloss.NewStyle().Foreground(statusColor).Bold(true).Render(statusText),
 		"",
 		"ChromaDB URL: " + chromaDBStatus,
+		"Collection: " + collection,
 		"",
 		ragReadyStatus,
 		"",
+		"Retrieval:",
+		"  Mode: " + string(opts.Mode),
+		"  Re-rank: " + rerank,
+		fmt.Sprintf("  Top K: %d", opts.EffectiveTopK()),
+		"  Max distance: " + maxDistance,
+		"  Where filter: " + where,
+		"  Prompt template: " + template,
+		"  Past context: " + pastContext,
+		"",
+		"Knowledge base sync:",
+		"  Watch folders: " + folders,
+		"  Watching: " + watching,
+		"  Status: " + m.ragSyncStatusText(),
+		"  Last indexed: " + m.ragLastIndexedText(),
+		"",
 		toggleText,
 		"",
 		"Controls:",
-		"Enter - Toggle RAG On/Off",
-		"C - Configure ChromaDB URL",
-		"Tab - Switch tabs",
+		controlLine("Toggle RAG On/Off", m.keys.Enter),
+		controlLine("Configure ChromaDB URL", m.keys.RAGChromaURL),
+		controlLine("Cycle mode (vector/keyword/hybrid)", m.keys.RAGMode),
+		controlLine("Toggle re-ranking", m.keys.RAGRerank),
+		controlLine("Set top K", m.keys.RAGTopK),
+		controlLine("Set max distance", m.keys.RAGMaxDistance),
+		controlLine("Set where filter (JSON)", m.keys.RAGWhere),
+		controlLine("Edit prompt template", m.keys.RAGPromptTemplate),
+		controlLine("Toggle keeping past context", m.keys.RAGKeepContext),
+		controlLine("Inspect a query", m.keys.RAGInspect),
+		controlLine("Set watch folders", m.keys.RAGWatchFolders),
+		controlLine("Sync now", m.keys.RAGSync),
+		controlLine("Toggle watching folders", m.keys.RAGWatch),
+		controlLine("Switch tabs", m.keys.NextTab),
 	}
 
 	m.ragViewport.SetContent(strings.Join(content, "\n"))
 }
 
+// ragSyncStatusText describes the state of the knowledge base sync
+func (m *model) ragSyncStatusText() string {
+	if m.ragSyncing {
+		return "syncing..."
+	}
+	status := m.currentRAGStatus()
+	if m.ragSyncResult == nil {
+		if status != nil && status.Files > 0 {
+			return fmt.Sprintf("%d files, %d chunks indexed", status.Files, status.Chunks)
+		}
+		return "not synced this session"
+	}
+	if m.ragSyncResult.err != nil {
+		return "error: " + m.ragSyncResult.err.Error()
+	}
+	if status == nil {
+		return m.ragSyncResult.stats.String()
+	}
+	return fmt.Sprintf("%s (%d files, %d chunks)", m.ragSyncResult.stats, status.Files, status.Chunks)
+}
+
+// ragLastIndexedText returns when the knowledge base was last indexed
+func (m *model) ragLastIndexedText() string {
+	status := m.currentRAGStatus()
+	if status == nil || status.LastIndexed.IsZero() {
+		return "never"
+	}
+	return status.LastIndexed.Format("2006-01-02 15:04:05")
+}
+
+// currentRAGStatus returns the latest known knowledge base status when it is for
+// the configured ChromaDB URL
+func (m *model) currentRAGStatus() *rag.SyncStatus {
+	if m.ragStatus == nil || m.ragStatus.chromaDBURL != m.settings.ChromaDBURL {
+		return nil
+	}
+	return &m.ragStatus.status
+}
+
+// updateRAGInspectorContent renders the ranked chunks and final prompt of the latest inspector query
+func (m *model) updateRAGInspectorContent() {
+	opts := m.ragOptions()
+	rerank := "off"
+	if opts.Rerank {
+		rerank = "on"
+	}
+
+	content := []string{
+		"RAG Query Inspector",
+		"",
+		fmt.Sprintf("Mode: %s | Top K: %d | Re-rank: %s", opts.Mode, opts.EffectiveTopK(), rerank),
+		"",
+	}
+
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	if m.darkMode {
+		dim = lipgloss.NewStyle().Foreground(darkModeTextColor)
+	}
+
+	switch {
+	case m.ragInspection == nil && m.ragInspectInput.Value() == "":
+		content = append(content, "Press "+formatKeys(m.keys.RAGInspect.Keys())+" and type a query to see what would be retrieved.")
+	case m.ragInspection == nil:
+		content = append(content, "Retrieving chunks for: "+m.ragInspectInput.Value())
+	default:
+		inspection := m.ragInspection
+		content = append(content, "Query: "+inspection.query, "")
+		if inspection.err != nil {
+			content = append(content, "Retrieval failed: "+inspection.err.Error(), "")
+		} else if len(inspection.results) == 0 {
+			content = append(content, "No chunks retrieved.", "")
+		} else {
+			content = append(content, "Ranked chunks:")
+			for i, result := range inspection.results {
+				content = append(content, fmt.Sprintf("#%d  %s", i+1, describeRAGScores(result)))
+				if result.Chunk.ID != "" {
+					content = append(content, dim.Render("    id: "+result.Chunk.ID))
+				}
+				if metadata := rag.FormatWhere(result.Chunk.Metadata); metadata != "" {
+					content = append(content, dim.Render("    metadata: "+metadata))
+				}
+				content = append(content, "    "+truncateText(result.Chunk.Text, 300), "")
+			}
+		}
+		content = append(content, "Final prompt:", inspection.prompt, "")
+	}
+
+	content = append(content,
+		"Controls:",
+		controlLine("Inspect another query", m.keys.RAGInspect),
+		controlLine("Scroll", m.keys.Up, m.keys.Down),
+		controlLine("Back to RAG settings", m.keys.Back),
+	)
+
+	m.ragViewport.SetContent(lipgloss.NewStyle().Width(m.ragViewport.Width).Render(strings.Join(content, "\n")))
+}
+
+// describeRAGScores summarises the ranking signals of a retrieved chunk
+func describeRAGScores(result rag.Result) string {
+	parts := []string{fmt.Sprintf("fused %.4f", result.Score)}
+	if result.VectorRank > 0 {
+		parts = append(parts, fmt.Sprintf("vector #%d (distance %.3f)", result.VectorRank, result.Distance))
+	}
+	if result.KeywordRank > 0 {
+		parts = append(parts, fmt.Sprintf("keyword #%d (bm25 %.2f)", result.KeywordRank, result.KeywordScore))
+	}
+	if result.Reranked {
+		parts = append(parts, fmt.Sprintf("rerank %.1f", result.RerankScore))
+	}
+	return strings.Join(parts, "  ")
+}
+
+// truncateText shortens text to at most n runes, adding an ellipsis when cut
+func truncateText(text string, n int) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	if len(runes) <= n {
+		return string(runes)
+	}
+	return string(runes[:n]) + "…"
+}
+
+// ragInputFocused returns true when one of the RAG tab's own inputs has focus
+func (m *model) ragInputFocused() bool {
+	return m.focus == focusChromaDBInput || m.focus == focusRAGOptionInput || m.focus == focusRAGInspectInput
+}
+
+// ragViewportFocused returns true when the RAG tab's own keys apply
+func (m *model) ragViewportFocused() bool {
+	return m.activeTab == ragTab && m.focus == focusRAGViewport
+}
+
+// settingsViewportFocused returns true when the Settings tab's own keys apply
+func (m *model) settingsViewportFocused() bool {
+	return m.activeTab == settingsTab && m.focus == focusSettingsViewport
+}
+
+// lastModel returns the saved model for the current model list, if any
+func lastModel(s *settings.Settings) string {
+	if bot.Aggregating(s) {
+		return s.AggregateLastModel
+	}
+	return s.LastModel
+}
+
+// connectProvider tests a backend and reinitialises the model manager with it
+func (m *model) connectProvider(provider bot.Provider) error {
+	if err := bot.TestConnection(provider); err != nil {
+		return err
+	}
+	m.connectionValid = true
+
+	defaultModel := "tinyllama:latest"
+	if last := lastModel(m.settings); last != "" {
+		defaultModel = last
+	}
+	if err := m.bot.InitializeModelManager(provider, defaultModel); err != nil {
+		return fmt.Errorf("failed to initialize models: %v", err)
+	}
+
+	m.models = m.bot.ModelManager.ModelNames()
+	m.selectedModel = 0
+	return nil
+}
+
+// ragOptions builds the retrieval options from the current settings
+func (m *model) ragOptions() rag.Options {
+	return rag.Options{
+		TopK:           m.settings.RAGTopK,
+		MaxDistance:    m.settings.RAGMaxDistance,
+		Where:          m.settings.RAGWhere,
+		PromptTemplate: m.settings.RAGPromptTemplate,
+		Mode:           rag.ParseMode(m.settings.RAGMode),
+		Rerank:         m.settings.RAGRerank,
+	}
+}
+
+// startRAGOptionEdit focuses the RAG option input for the given field
+func (m *model) startRAGOptionEdit(field ragField) {
+	m.ragOptionField = field
+	m.ragOptionInput.Reset()
+
+	switch field {
+	case ragFieldTopK:
+		m.ragOptionInput.Prompt = "Top K: "
+		m.ragOptionInput.Placeholder = strconv.Itoa(rag.DefaultTopK)
+		m.ragOptionInput.SetValue(strconv.Itoa(m.ragOptions().EffectiveTopK()))
+	case ragFieldMaxDistance:
+		m.ragOptionInput.Prompt = "Max distance: "
+		m.ragOptionInput.Placeholder = "0 for no threshold"
+		if m.settings.RAGMaxDistance > 0 {
+			m.ragOptionInput.SetValue(strconv.FormatFloat(m.settings.RAGMaxDistance, 'g', -1, 64))
+		}
+	case ragFieldWhere:
+		m.ragOptionInput.Prompt = "Where: "
+		m.ragOptionInput.Placeholder = `{"source": "docs"} or empty for none`
+		m.ragOptionInput.SetValue(rag.FormatWhere(m.settings.RAGWhere))
+	case ragFieldPromptTemplate:
+		m.ragOptionInput.Prompt = "Template: "
+		m.ragOptionInput.Placeholder = "Use " + rag.ContextPlaceholder + " and " + rag.QuestionPlaceholder + ", \\n for newlines, empty for default"
+		m.ragOptionInput.SetValue(rag.EscapeTemplate(m.ragOptions().EffectivePromptTemplate()))
+	case ragFieldWatchFolders:
+		m.ragOptionInput.Prompt = "Watch folders: "
+		m.ragOptionInput.Placeholder = "Comma separated, e.g. ~/docs, ./notes"
+		m.ragOptionInput.SetValue(strings.Join(m.settings.RAGWatchFolders, ", "))
+	}
+
+	m.focus = focusRAGOptionInput
+	m.ragOptionInput.Focus()
+	m.ragOptionInput.CursorEnd()
+}
+
+// submitRAGOptionInput validates and saves the value in the RAG option input
+func (m *model) submitRAGOptionInput() error {
+	value := strings.TrimSpace(m.ragOptionInput.Value())
+
+	switch m.ragOptionField {
+	case ragFieldTopK:
+		topK, err := parseRAGTopK(value)
+		if err != nil {
+			return err
+		}
+		return m.settings.SetRAGTopK(topK)
+	case ragFieldMaxDistance:
+		distance, err := parseRAGMaxDistance(value)
+		if err != nil {
+			return err
+		}
+		return m.settings.SetRAGMaxDistance(distance)
+	case ragFieldWhere:
+		where, err := rag.ParseWhere(value)
+		if err != nil {
+			return err
+		}
+		return m.settings.SetRAGWhere(where)
+	case ragFieldPromptTemplate:
+		template, err := parseRAGPromptTemplate(value)
+		if err != nil {
+			return err
+		}
+		return m.settings.SetRAGPromptTemplate(template)
+	case ragFieldWatchFolders:
+		folders, err := parseWatchFolders(value)
+		if err != nil {
+			return err
+		}
+		return m.settings.SetRAGWatchFolders(folders)
+	}
+	return nil
+}
+
 func (m *model) updateInputPlaceholder() {
 	// Handle special input focuses first
-	if m.focus == focusChromaDBInput {
-		// ChromaDB input has its own placeholder, no need to change textarea
+	if m.ragInputFocused() {
+		// RAG tab inputs have their own placeholders, no need to change textarea
 		return
 	}
 
@@ -661,7 +1485,17 @@ func (m *model) updateInputPlaceholder() {
 			m.textarea.Placeholder = "RAG configuration..."
 		}
 	case settingsTab:
-		m.textarea.Placeholder = "Enter Ollama URL (e.g., http://localhost:11434)"
+		if m.editingField != "" {
+			for _, field := range settingFields {
+				if field.key == m.editingField {
+					m.textarea.Placeholder = field.label + ": " + field.help + " (" + formatKeys(m.keys.Back.Keys()) + " to cancel)"
+				}
+			}
+		} else if m.addingEndpoint {
+			m.textarea.Placeholder = "Enter endpoint name and URL (e.g., workstation http://gpu-box:11434)"
+		} else {
+			m.textarea.Placeholder = "Enter server URL (e.g., http://localhost:11434)"
+		}
 	}
 }
 
@@ -677,7 +1511,7 @@ func (m *model) updateSettingsViewportContent() {
 		} else {
 			connectionColor = lipgloss.Color("2") // Green for connected in light mode
 		}
-		statusMessage = "Connection established! Press Enter to edit URL if needed."
+		statusMessage = "Connection established! Select another endpoint and press " + formatKeys(m.keys.Enter.Keys()) + " to switch."
 	} else {
 		if m.darkMode {
 			connectionColor = darkModeTextColor // Light gray for disconnected in dark mode
@@ -691,87 +1525,190 @@ func (m *model) updateSettingsViewportContent() {
 		currentURL = "(not configured)"
 	}
 
-	content := []string{
-		"Ollama Server URL",
+	modelListText := "active endpoint"
+	if m.settings.AggregateModels {
+		modelListText = "all endpoints, grouped by host"
+	}
+
+	var content []string
+	problems := m.settings.Problems()
+	if m.settingsReloadErr != nil {
+		problems = append([]error{m.settingsReloadErr}, problems...)
+	}
+	problems = append(problems, m.keyProblems...)
+	if len(problems) > 0 {
+		problemStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+		if m.darkMode {
+			problemStyle = problemStyle.Foreground(darkModeAccentColor)
+		}
+		content = append(content, problemStyle.Bold(true).Render("Settings file problems:"))
+		for _, problem := range problems {
+			content = append(content, problemStyle.Render("• "+problem.Error()))
+		}
+		content = append(content, "")
+	}
+	content = append(content, "Server Endpoints", "")
+	selectedLine := len(content) + min(m.settingsRow, m.endpointRows()-1)
+	content = append(content, m.endpointLines()...)
+	content = append(content,
 		"",
-		"Current URL: " + currentURL,
-		"Connection: " + lipgloss.NewStyle().Foreground(connectionColor).Bold(true).Render(connectionStatus),
+		"Current URL: "+currentURL,
+		"Backend: "+bot.ProviderLabel(m.settings.Provider),
+		"Model list: "+modelListText,
+		"Connection: "+lipgloss.NewStyle().Foreground(connectionColor).Bold(true).Render(connectionStatus),
 		"",
 		statusMessage,
-		"Press Enter to test the connection.",
 		"",
-		"Example: http://localhost:11434",
+		"Example: http://localhost:11434 (Ollama) or http://localhost:8080/v1 (OpenAI-compatible)",
 		"",
-		"Controls:",
-		"Enter - Edit/Test URL",
-		"Tab - Switch tabs",
-	}
+	)
 
-	m.settingsViewport.SetContent(strings.Join(content, "\n"))
+	formLines, formSelected := m.settingsFormLines()
+	if _, ok := m.selectedField(); ok {
+		selectedLine = len(content) + formSelected
+	}
+	content = append(content, formLines...)
+	content = append(content,
+		"",
+		"Controls:",
+		controlLine("Select endpoint or setting", m.keys.Up, m.keys.Down),
+		controlLine("Toggle, cycle or edit the selected setting", m.keys.Enter),
+		controlLine("Save changed settings", m.keys.SettingsSave),
+		controlLine("Revert changed settings", m.keys.SettingsRevert),
+		controlLine("Cancel editing", m.keys.Back),
+		"",
+		"On an endpoint:",
+		controlLine("Use selected endpoint (edit URL if already active)", m.keys.Enter),
+		controlLine("Edit selected endpoint URL", m.keys.EndpointEdit),
+		controlLine("Add endpoint", m.keys.EndpointAdd),
+		controlLine("Remove selected endpoint", m.keys.EndpointRemove),
+		controlLine("Switch backend of selected endpoint", m.keys.EndpointBackend),
+		controlLine("Merge models from all endpoints", m.keys.AggregateModels),
+		controlLine("Recheck endpoints", m.keys.EndpointRecheck),
+		controlLine("Switch tabs", m.keys.NextTab),
+	)
+
+	m.settingsViewport.SetContent(strings.Join(content, "\n"))
+
+	// Keep the highlighted row in view
+	if selectedLine < m.settingsViewport.YOffset {
+		m.settingsViewport.SetYOffset(selectedLine)
+	} else if height := m.settingsViewport.Height; height > 0 && selectedLine >= m.settingsViewport.YOffset+height {
+		m.settingsViewport.SetYOffset(selectedLine - height + 1)
+	}
+}
+
+// endpointLines renders the endpoint picker with the reachability of each endpoint
+func (m *model) endpointLines() []string {
+	if len(m.settings.Endpoints) == 0 {
+		return []string{"  (no endpoints configured - press " + formatKeys(m.keys.Enter.Keys()) + " to add one)"}
+	}
+
+	reachableColor, unreachableColor := lipgloss.Color("2"), lipgloss.Color("1")
+	if m.darkMode {
+		reachableColor, unreachableColor = darkModeAccentColor, darkModeTextColor
+	}
+
+	nameWidth := 0
+	for _, endpoint := range m.settings.Endpoints {
+		nameWidth = max(nameWidth, len(endpoint.Name))
+	}
+
+	var lines []string
+	for i, endpoint := range m.settings.Endpoints {
+		cursor := "  "
+		if i == m.settingsRow {
+			cursor = "▶ "
+		}
+		active := "  "
+		if endpoint.Name == m.settings.ActiveEndpoint {
+			active = "* "
+		}
+
+		status := "? unchecked"
+		if err, checked := m.endpointStatus[endpoint.Name]; m.endpointChecking {
+			status = "… checking"
+		} else if checked && err == nil {
+			status = lipgloss.NewStyle().Foreground(reachableColor).Render("● reachable")
+		} else if checked {
+			status = lipgloss.NewStyle().Foreground(unreachableColor).Render("✗ unreachable")
+		}
+
+		lines = append(lines, fmt.Sprintf("%s%s%-*s  %s  [%s]  %s",
+			cursor, active, nameWidth, endpoint.Name, endpoint.URL, bot.ProviderLabel(endpoint.Provider), status))
+	}
+	return lines
 }
 
 func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.MouseMsg); ok {
+		return m, m.updateMouse(msg)
+	}
+	// Keys typed in the search bar shouldn't also scroll the chat
+	if msg, ok := msg.(tea.KeyMsg); ok && m.focus == focusSearch {
+		return m.updateSearch(msg)
+	}
+	if msg, ok := msg.(tea.KeyMsg); ok && m.focus == focusSessions {
+		return m.updateSessions(msg)
+	}
+
 	var (
-		tiCmd     tea.Cmd
-		vpCmd     tea.Cmd
-		mvCmd     tea.Cmd
-		ragCmd    tea.Cmd
-		chromaCmd tea.Cmd
+		tiCmd         tea.Cmd
+		vpCmd         tea.Cmd
+		mvCmd         tea.Cmd
+		ragCmd        tea.Cmd
+		chromaCmd     tea.Cmd
+		ragOptCmd     tea.Cmd
+		ragInspectCmd tea.Cmd
 	)
 
 	if m.focus == focusTextarea {
 		m.textarea, tiCmd = m.textarea.Update(msg)
 	}
+	// Fit the prompt input to whatever text this message leaves in it
+	defer m.resizeInput()
 	if m.focus == focusChromaDBInput {
 		m.chromaDBTextInput, chromaCmd = m.chromaDBTextInput.Update(msg)
 	}
+	if m.focus == focusRAGOptionInput {
+		m.ragOptionInput, ragOptCmd = m.ragOptionInput.Update(msg)
+	}
+	if m.focus == focusRAGInspectInput {
+		m.ragInspectInput, ragInspectCmd = m.ragInspectInput.Update(msg)
+	}
 	m.viewport, vpCmd = m.viewport.Update(msg)
 	m.modelsViewport, mvCmd = m.modelsViewport.Update(msg)
 	m.ragViewport, ragCmd = m.ragViewport.Update(msg)
 
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
-		// Calculate full width for chat tab
-		chatViewportWidth := msg.Width - 4 // 4 for padding and border
-
-		// Models viewport width for models tab
-		modelsViewportWidth := 30 // Default width
-		if m.bot.ModelManager != nil {
-			modelsViewportWidth = min(msg.Width-4, m.bot.ModelManager.MaxModelNameLength()+10)
-		} else {
-			// When no models are available, use wider viewport for the help message
-			modelsViewportWidth = min(msg.Width-4, 60) // Wider width for help text
+		m.resize(msg.Width, msg.Height)
+	case tea.KeyMsg:
+		if m.focus == focusPalette {
+			return m.updatePalette(msg)
 		}
 
-		// RAG viewport width - make it wider to accommodate status messages
-		ragViewportWidth := min(msg.Width-4, 70) // Wider width for RAG status messages
-
-		// Settings viewport width - make it wider to accommodate URLs
-		settingsViewportWidth := min(msg.Width-4, 60) // Larger width for settings
-
-		m.viewport.Width = chatViewportWidth
-		m.modelsViewport.Width = modelsViewportWidth
-		m.ragViewport.Width = ragViewportWidth
-		m.settingsViewport.Width = settingsViewportWidth
-		m.textarea.SetWidth(chatViewportWidth)
-
-		// Height calculations (accounting for tab header)
-		tabHeaderHeight := 3 // Height of tab header
-		availableHeight := msg.Height - m.textarea.Height() - lipgloss.Height(gap) - tabHeaderHeight
-
-		m.viewport.Height = availableHeight
-		m.modelsViewport.Height = availableHeight
-		m.ragViewport.Height = availableHeight
-		m.settingsViewport.Height = availableHeight
+		switch {
+		case key.Matches(msg, m.keys.Palette):
+			m.openPalette()
+		case key.Matches(msg, m.keys.Editor) && m.focus == focusTextarea:
+			return m, m.openEditor()
+		case key.Matches(msg, m.keys.CopyMessage) && m.selectionHint() != "":
+			m.copyMessage()
+		case key.Matches(msg, m.keys.EditMessage) && m.selectionHint() != "":
+			m.editMessage()
+		case key.Matches(msg, m.keys.Back) && m.selectionHint() != "":
+			m.selectMessage(-1)
+		case key.Matches(msg, m.keys.Find) && m.activeTab == chatTab:
+			m.openSearch("")
+		case key.Matches(msg, m.keys.Sessions):
+			m.openSessions("")
+		case key.Matches(msg, m.keys.NextTab):
+			// Complete a slash command being typed rather than switching tabs
+			if m.completeCommand() {
+				return m, nil
+			}
 
-		if m.bot.MessageLen() > 0 {
-			// Wrap content before setting it.
-			m.viewport.SetContent(lipgloss.NewStyle().Width(m.viewport.Width).Render(strings.Join(m.bot.MessageManager.StyledMessages(), "\n")))
-		}
-		m.viewport.GotoBottom()
-	case tea.KeyMsg:
-		switch msg.String() {
-		case "tab":
 			// Check if we have models available for chat functionality
 			hasModels := m.connectionValid && m.bot.ModelManager != nil
 
@@ -819,7 +1756,7 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.updateRAGViewportContent()
 			m.updateSettingsViewportContent()
 			m.updateInputPlaceholder()
-		case "ctrl+t":
+		case key.Matches(msg, m.keys.FocusInput):
 			// Switch focus to textarea for command input (works from any tab)
 			if m.focus != focusTextarea {
 				m.focus = focusTextarea
@@ -842,49 +1779,206 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			m.updateSettingsViewportContent()
 			m.updateInputPlaceholder()
-		case "c":
+		case key.Matches(msg, m.keys.RAGChromaURL) && m.ragViewportFocused():
 			// Handle ChromaDB URL configuration on RAG tab
-			if m.activeTab == ragTab && m.focus == focusRAGViewport {
-				m.focus = focusChromaDBInput
-				m.chromaDBTextInput.Focus()
-				// Pre-fill with current ChromaDB URL if any for editing
-				if m.settings.ChromaDBURL != "" {
-					m.chromaDBTextInput.SetValue(m.settings.ChromaDBURL)
+			m.focus = focusChromaDBInput
+			m.chromaDBTextInput.Focus()
+			// Pre-fill with current ChromaDB URL if any for editing
+			if m.settings.ChromaDBURL != "" {
+				m.chromaDBTextInput.SetValue(m.settings.ChromaDBURL)
+			}
+			m.updateInputPlaceholder()
+		case key.Matches(msg, m.keys.RAGTopK, m.keys.RAGMaxDistance, m.keys.RAGWhere, m.keys.RAGPromptTemplate, m.keys.RAGWatchFolders) && m.ragViewportFocused():
+			// Handle RAG retrieval option editing on RAG tab
+			fields := []struct {
+				binding key.Binding
+				field   ragField
+			}{
+				{m.keys.RAGTopK, ragFieldTopK},
+				{m.keys.RAGMaxDistance, ragFieldMaxDistance},
+				{m.keys.RAGWhere, ragFieldWhere},
+				{m.keys.RAGPromptTemplate, ragFieldPromptTemplate},
+				{m.keys.RAGWatchFolders, ragFieldWatchFolders},
+			}
+			for _, f := range fields {
+				if key.Matches(msg, f.binding) {
+					m.startRAGOptionEdit(f.field)
+					break
 				}
-				m.updateInputPlaceholder()
 			}
-		case "up":
+			m.updateInputPlaceholder()
+		case key.Matches(msg, m.keys.EndpointBackend) && m.settingsViewportFocused():
+			// Cycle the LLM backend type of the selected endpoint, reconnecting if it is active
+			m.inputError = ""
+			endpoint, ok := m.settings.Endpoint(m.selectedEndpointName())
+			if !ok && len(m.settings.Endpoints) > 0 {
+				// A form field is selected
+				return m, nil
+			}
+			if !ok {
+				// No endpoint yet, the backend applies to the first URL entered
+				m.settings.SetProvider(bot.NextProviderName(m.settings.Provider))
+				m.updateSettingsViewportContent()
+				return m, nil
+			}
+
+			endpoint.Provider = bot.NextProviderName(endpoint.Provider)
+			m.settings.SetEndpoint(endpoint)
+			if endpoint.Name == m.settings.ActiveEndpoint || bot.Aggregating(m.settings) {
+				m.reconnect()
+			}
+			cmd := m.checkEndpoints()
+			m.updateTabNames()
+			m.updateModelsViewportContent()
+			m.updateSettingsViewportContent()
+			return m, cmd
+		case key.Matches(msg, m.keys.EndpointEdit) && m.settingsViewportFocused():
+			// Edit the selected form field or endpoint's URL
+			if field, ok := m.selectedField(); ok {
+				m.inputError = ""
+				m.activateField(field)
+				m.updateSettingsViewportContent()
+			} else if len(m.settings.Endpoints) == 0 {
+				m.startEndpointEdit("", false)
+			} else {
+				m.startEndpointEdit(m.selectedEndpointName(), false)
+			}
+			return m, nil
+		case key.Matches(msg, m.keys.EndpointAdd) && m.settingsViewportFocused():
+			// Add a new endpoint
+			m.startEndpointEdit("", len(m.settings.Endpoints) > 0)
+			return m, nil
+		case key.Matches(msg, m.keys.EndpointRemove) && m.settingsViewportFocused():
+			// Remove the selected endpoint
+			if m.selectedEndpointName() == "" {
+				return m, nil
+			}
+			if err := m.settings.RemoveEndpoint(m.selectedEndpointName()); err != nil {
+				m.inputError = "Failed to remove endpoint: " + err.Error()
+			} else {
+				m.inputError = ""
+				m.settingsRow = min(m.settingsRow, m.endpointRows()-1)
+				if m.settings.AggregateModels {
+					m.reconnect()
+				}
+				m.updateTabNames()
+				m.updateModelsViewportContent()
+			}
+			m.updateSettingsViewportContent()
+		case key.Matches(msg, m.keys.RAGMode) && m.ragViewportFocused():
+			// Cycle the retrieval mode used for the next queries
+			m.settings.SetRAGMode(string(rag.NextMode(rag.ParseMode(m.settings.RAGMode))))
+			m.updateRAGViewportContent()
+		case key.Matches(msg, m.keys.RAGRerank) && m.ragViewportFocused():
+			// Toggle re-ranking of retrieved chunks by the current model
+			m.settings.SetRAGRerank(!m.settings.RAGRerank)
+			m.updateRAGViewportContent()
+		case key.Matches(msg, m.keys.EndpointRecheck) && m.settingsViewportFocused():
+			// Recheck which endpoints are reachable
+			if !m.endpointChecking {
+				cmd := m.checkEndpoints()
+				m.updateSettingsViewportContent()
+				return m, cmd
+			}
+		case key.Matches(msg, m.keys.SettingsRevert) && m.settingsViewportFocused():
+			// Discard unsaved Settings tab form values
+			m.revertSettingsForm()
+			m.updateSettingsViewportContent()
+		case key.Matches(msg, m.keys.SettingsSave) && m.settingsViewportFocused():
+			// Save the Settings tab form values together
+			cmd := m.saveSettingsForm()
+			m.updateSettingsViewportContent()
+			return m, cmd
+		case key.Matches(msg, m.keys.RAGSync) && m.ragViewportFocused():
+			// Sync the watch folders into the knowledge base now
+			if !m.ragSyncReady() {
+				m.inputError = "Configure a ChromaDB URL and watch folders before syncing"
+				return m, nil
+			}
+			if !m.ragSyncing {
+				cmd := m.syncKnowledgeBase()
+				m.updateRAGViewportContent()
+				return m, cmd
+			}
+		case key.Matches(msg, m.keys.RAGWatch) && m.ragViewportFocused():
+			// Toggle keeping the watch folders in sync while the TUI is running
+			enabled := !m.settings.RAGWatchEnabled
+			m.settings.SetRAGWatchEnabled(enabled)
+			var cmd tea.Cmd
+			if enabled && m.ragSyncReady() && !m.ragSyncing {
+				cmd = m.syncKnowledgeBase()
+			}
+			m.updateRAGViewportContent()
+			return m, cmd
+		case key.Matches(msg, m.keys.AggregateModels) && m.settingsViewportFocused():
+			// Toggle merging the models of all endpoints into the Models tab
+			m.settings.SetAggregateModels(!m.settings.AggregateModels)
+			m.inputError = ""
+			if m.settings.AggregateModels && len(m.settings.Endpoints) < 2 {
+				m.inputError = "Add another endpoint to merge model lists"
+			}
+			m.reconnect()
+			m.updateTabNames()
+			m.updateModelsViewportContent()
+			m.updateSettingsViewportContent()
+		case key.Matches(msg, m.keys.RAGInspect) && m.ragViewportFocused():
+			// Open the query inspector on RAG tab
+			if m.settings.ChromaDBURL == "" {
+				m.inputError = "Configure a ChromaDB URL before inspecting queries"
+				return m, nil
+			}
+			m.ragInspecting = true
+			m.focus = focusRAGInspectInput
+			m.ragInspectInput.Reset()
+			m.ragInspectInput.Focus()
+			m.updateRAGViewportContent()
+			m.updateInputPlaceholder()
+		case key.Matches(msg, m.keys.RAGKeepContext) && m.ragViewportFocused():
+			// Toggle whether past retrieval context is re-sent on later turns
+			keep := !m.settings.RAGKeepContext
+			m.settings.SetRAGKeepContext(keep)
+			if keep {
+				m.bot.MessageManager.SetContextPolicy(messages.ContextPolicyKeep)
+			} else {
+				m.bot.MessageManager.SetContextPolicy(messages.ContextPolicyDrop)
+			}
+			m.updateTabNames()
+			m.updateRAGViewportContent()
+		case key.Matches(msg, m.keys.Up):
 			if m.activeTab == modelsTab && m.focus == focusModelsViewport && m.selectedModel > 0 {
 				m.selectedModel--
 				m.updateModelsViewportContent()
-			} else if m.activeTab == chatTab && m.focus == focusTextarea {
+			} else if m.activeTab == settingsTab && m.focus == focusSettingsViewport && m.settingsRow > 0 {
+				m.inputError = ""
+				m.settingsRow--
+				m.updateSettingsViewportContent()
+			} else if m.activeTab == chatTab && m.focus == focusTextarea && m.textarea.LineCount() == 1 {
+				// Arrows move between lines of a multiline prompt instead of scrolling
 				m.viewport.ScrollUp(1)
 			}
-		case "down":
+		case key.Matches(msg, m.keys.Down):
 			if m.activeTab == modelsTab && m.focus == focusModelsViewport && m.selectedModel < len(m.models)-1 {
 				m.selectedModel++
 				m.updateModelsViewportContent()
-			} else if m.activeTab == chatTab && m.focus == focusTextarea {
+			} else if m.activeTab == settingsTab && m.focus == focusSettingsViewport && m.settingsRow < m.settingsRows()-1 {
+				m.inputError = ""
+				m.settingsRow++
+				m.updateSettingsViewportContent()
+			} else if m.activeTab == chatTab && m.focus == focusTextarea && m.textarea.LineCount() == 1 {
+				// Arrows move between lines of a multiline prompt instead of scrolling
 				m.viewport.ScrollDown(1)
 			}
-		case "enter":
+		case key.Matches(msg, m.keys.Enter):
 			input := m.textarea.Value()
 
 			// Handle tab-specific viewport interactions first (when not focused on textarea)
-			if m.focus != focusTextarea && m.focus != focusChromaDBInput {
+			if m.focus != focusTextarea && !m.ragInputFocused() {
 				if m.activeTab == modelsTab && m.focus == focusModelsViewport {
 					if m.bot.ModelManager != nil && len(m.models) > 0 {
-						selectedModel := m.models[m.selectedModel]
-						err := m.bot.ModelManager.UseModel(selectedModel)
-						if err != nil {
+						if err := m.useModel(m.models[m.selectedModel]); err != nil {
 							msg := llm.Message{Role: "error", Content: err.Error()}
 							m.bot.MessageManager.AddMessage(msg)
-						} else {
-							// Save the selected model to settings
-							m.settings.SetLastModel(selectedModel)
 						}
-						m.updateTabNames()
-						m.updateModelsViewportContent()
 					}
 				} else if m.activeTab == ragTab && m.focus == focusRAGViewport {
 					// Toggle RAG enabled/disabled
@@ -894,14 +1988,24 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.updateTabNames()
 					m.updateRAGViewportContent()
 				} else if m.activeTab == settingsTab && m.focus == focusSettingsViewport {
-					// On settings tab with viewport focus, switch to textarea focus for URL editing
-					m.focus = focusTextarea
-					m.textarea.Focus()
-					// Pre-fill with current URL if any for editing
-					if m.settings.OllamaURL != "" {
-						m.textarea.SetValue(m.settings.OllamaURL)
+					if field, ok := m.selectedField(); ok {
+						m.inputError = ""
+						m.activateField(field)
+						m.updateSettingsViewportContent()
+						return m, nil
+					}
+					name := m.selectedEndpointName()
+					if name == "" || name == m.settings.ActiveEndpoint {
+						// Switch to textarea focus to edit the active endpoint's URL
+						m.startEndpointEdit(name, false)
+					} else {
+						// Switch to the selected endpoint
+						m.inputError = ""
+						m.switchEndpoint(name)
+						m.updateTabNames()
+						m.updateModelsViewportContent()
+						m.updateSettingsViewportContent()
 					}
-					m.updateInputPlaceholder()
 				}
 				return m, nil
 			}
@@ -924,6 +2028,54 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.chromaDBTextInput.Blur()
 				m.updateRAGViewportContent() // Update to show new URL
 				m.updateInputPlaceholder()
+				return m, m.loadRAGStatus()
+			}
+
+			// Handle RAG option input
+			if m.focus == focusRAGOptionInput {
+				if err := m.submitRAGOptionInput(); err != nil {
+					m.inputError = err.Error()
+					return m, nil
+				}
+				m.inputError = ""
+				// Return to RAG viewport
+				m.focus = focusRAGViewport
+				m.ragOptionInput.Blur()
+				// Start syncing newly configured watch folders right away when watching
+				var cmd tea.Cmd
+				if m.ragOptionField == ragFieldWatchFolders && m.settings.RAGWatchEnabled && m.ragSyncReady() && !m.ragSyncing {
+					cmd = m.syncKnowledgeBase()
+				}
+				m.updateRAGViewportContent()
+				m.updateInputPlaceholder()
+				return m, cmd
+			}
+
+			// Handle RAG inspector input
+			if m.focus == focusRAGInspectInput {
+				query := strings.TrimSpace(m.ragInspectInput.Value())
+				if query == "" {
+					m.inputError = "Inspector query cannot be empty"
+					return m, nil
+				}
+				m.inputError = ""
+				m.ragInspection = nil
+				// Return to RAG viewport so the results can be scrolled
+				m.focus = focusRAGViewport
+				m.ragInspectInput.Blur()
+				m.updateRAGViewportContent()
+				m.updateInputPlaceholder()
+				return m, m.inspectRAGQuery(query)
+			}
+
+			// Handle a Settings tab form value typed in the textarea; it may be empty or start with /
+			if m.activeTab == settingsTab && m.editingField != "" {
+				if err := m.submitFieldEdit(input); err != nil {
+					m.inputError = err.Error()
+					return m, nil
+				}
+				m.inputError = ""
+				m.updateSettingsViewportContent()
 				return m, nil
 			}
 
@@ -932,110 +2084,68 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				isCommand := strings.HasPrefix(input, "/")
 
 				if isCommand {
-					// Clear any existing error when processing a command
-					m.inputError = ""
-
-					// Handle valid commands
-					switch input {
-					case "/exit", "/quit":
-						return m, tea.Quit
-					case "/chat":
-						if !m.connectionValid {
-							m.inputError = "Please configure Ollama URL in Settings tab first"
-							m.textarea.Reset()
-							return m, nil
-						}
-						if m.bot.ModelManager == nil {
-							m.inputError = "No models available. Please pull models using 'ollama pull <model-name>'"
-							m.textarea.Reset()
-							return m, nil
-						}
-						m.activeTab = chatTab
-						m.textarea.Reset()
-						return m, nil
-					case "/models":
-						if !m.connectionValid {
-							m.inputError = "Please configure Ollama URL in Settings tab first"
-							m.textarea.Reset()
-							return m, nil
+					m.textarea.Reset()
+					return m, m.runCommand(input)
+				} else {
+					// Non-command input
+					if m.activeTab == settingsTab {
+						// Settings tab: treat input as the URL of the endpoint being edited
+						m.inputError = "" // Clear any existing errors
+
+						name, url := m.editingEndpoint, input
+						if m.addingEndpoint {
+							fields := strings.Fields(input)
+							if len(fields) != 2 {
+								m.inputError = "Enter an endpoint name and URL, e.g. workstation http://gpu-box:11434"
+								return m, nil
+							}
+							name, url = fields[0], fields[1]
+							if _, exists := m.settings.Endpoint(name); exists {
+								m.inputError = "Endpoint " + name + " already exists"
+								return m, nil
+							}
 						}
-						m.activeTab = modelsTab
-						// Fetch models when switching via command
-						if m.bot.ModelManager != nil {
-							m.models = m.bot.ModelManager.ModelNames()
+						if name == "" {
+							name = settings.DefaultEndpointName
 						}
-						m.updateTabNames()
-						m.updateModelsViewportContent()
-						m.textarea.Reset()
-						return m, nil
-					case "/rag":
-						if !m.connectionValid {
-							m.inputError = "Please configure Ollama URL in Settings tab first"
-							m.textarea.Reset()
-							return m, nil
+
+						endpoint, exists := m.settings.Endpoint(name)
+						if !exists {
+							endpoint = settings.Endpoint{Name: name, Provider: m.settings.Provider}
+							if len(m.settings.Endpoints) == 0 {
+								endpoint.LastModel = m.settings.LastModel
+							}
 						}
-						m.activeTab = ragTab
-						m.textarea.Reset()
-						return m, nil
-					case "/settings":
-						m.activeTab = settingsTab
-						m.textarea.Reset()
-						return m, nil
-					case "/dark":
-						// Toggle dark mode
-						m.darkMode = !m.darkMode
-						m.settings.SetDarkMode(m.darkMode)
-						m.applyTheme() // Apply the new theme
-						m.textarea.Reset()
-						return m, nil
-					case "/clear":
-						// /clear only works on chat tab
-						if m.activeTab == chatTab {
-							m.bot.ClearMessages()
-							m.viewport.SetContent(`Welcome to Gollama-Chat!
-Type a message and press Enter to send.` + ascii + `
-
-Use the Tab key to switch between Chat, Models, RAG, and Settings tabs.
-Use Ctrl+T to focus the input field for commands from any tab.
-
-Special commands:
-  • /clear - clear chat history
-  • /chat - switch to chat tab
-  • /models - switch to models tab
-  • /rag - switch to RAG tab
-  • /settings - switch to settings tab
-  • /dark - toggle dark mode
-  • /exit or /quit - quit application
-
-Key bindings:
-  • Ctrl+U - clear input
-  • Ctrl+A - go to start
-  • Ctrl+E - go to end
-  • Ctrl+C or Esc - quit`)
-							// Update tab names to reflect cleared tokens (should be 0 now)
-							m.updateTabNames()
-							m.textarea.Reset()
-							return m, nil
-						} else {
-							// Invalid command on non-chat tab
-							m.inputError = "Command '/clear' is only available on the chat tab"
+						endpoint.URL = url
+
+						if len(m.settings.Endpoints) > 0 && name != m.settings.ActiveEndpoint {
+							// Inactive endpoints are saved as entered; the status column shows whether they're reachable
+							if err := m.settings.SetEndpoint(endpoint); err != nil {
+								m.inputError = "Failed to save endpoint: " + err.Error()
+								return m, nil
+							}
+							if m.addingEndpoint {
+								m.settingsRow = len(m.settings.Endpoints) - 1
+							}
+							m.addingEndpoint = false
+							m.editingEndpoint = ""
+							m.focus = focusSettingsViewport
+							m.textarea.Blur()
 							m.textarea.Reset()
-							return m, nil
+							if bot.Aggregating(m.settings) {
+								m.reconnect()
+								m.updateTabNames()
+								m.updateModelsViewportContent()
+							}
+							cmd := m.checkEndpoints()
+							m.updateSettingsViewportContent()
+							m.updateInputPlaceholder()
+							return m, cmd
 						}
-					default:
-						// Invalid command
-						m.inputError = "Invalid command: " + input
-						m.textarea.Reset()
-						return m, nil
-					}
-				} else {
-					// Non-command input
-					if m.activeTab == settingsTab {
-						// Settings tab: treat input as URL to test
-						m.inputError = "" // Clear any existing errors
 
 						// Test the connection to the entered URL
-						err := bot.TestConnection(input)
+						provider := bot.EndpointProvider(m.settings, endpoint)
+						err := bot.TestConnection(provider)
 						if err != nil {
 							// Connection failed - don't save the invalid URL
 							// Keep the existing connectionValid state and URL
@@ -1052,16 +2162,19 @@ Key bindings:
 							// Don't change connectionValid or save the bad URL
 						} else {
 							// Connection successful - save URL and initialize bot
-							m.settings.SetOllamaURL(input)
+							m.settings.SetEndpoint(endpoint)
+							m.settings.UseEndpoint(name)
+							m.addingEndpoint = false
+							m.editingEndpoint = ""
 							m.connectionValid = true
 
 							// Initialize model manager with the new URL
 							defaultModel := "tinyllama:latest"
-							if m.settings.LastModel != "" {
-								defaultModel = m.settings.LastModel
+							if last := lastModel(m.settings); last != "" {
+								defaultModel = last
 							}
 
-							err := m.bot.InitializeModelManager(input, defaultModel)
+							err := m.bot.InitializeModelManager(bot.SettingsProvider(m.settings), defaultModel)
 							if err != nil {
 								m.inputError = "Failed to initialize models: " + err.Error()
 								// Don't change connectionValid if we had a working URL before
@@ -1083,7 +2196,7 @@ Key bindings:
 						m.updateTabNames() // Update tab names to reflect current connection status
 						m.updateSettingsViewportContent()
 						m.updateInputPlaceholder()
-						return m, nil
+						return m, m.checkEndpoints()
 					} else if m.activeTab == ragTab && m.focus == focusChromaDBInput {
 						// RAG tab: handle ChromaDB URL input
 						chromaDBURL := m.chromaDBTextInput.Value()
@@ -1110,7 +2223,7 @@ Key bindings:
 
 						m.chromaDBTextInput.Reset()
 						m.updateInputPlaceholder()
-						return m, nil
+						return m, m.loadRAGStatus()
 					} else if m.activeTab != chatTab {
 						// Non-settings, non-chat tab with non-command input
 						m.inputError = "Chat input detected. Please switch to the chat tab and press enter"
@@ -1130,11 +2243,29 @@ Key bindings:
 							m.textarea.Reset()
 							return m, nil
 						}
+						// One reply at a time, as replies share the buffer they are streamed into
+						if m.isThinking {
+							m.inputError = "Wait for the reply, or press " + formatKeys(m.keys.Back.Keys()) + " to stop it, before sending another message"
+							return m, nil // Keep the message to send once the reply is done
+						}
+
+						// Files named with @path join those attached with /attach
+						files, images, err := mentionedFiles(input)
+						if err == nil {
+							err = m.attach(files, images)
+						}
+						if err != nil {
+							m.inputError = err.Error()
+							return m, nil // Keep the message so the attachment can be fixed
+						}
+						m.inputError = m.visionWarning()
+						content := attachments.Inline(input, m.attachments)
 
 						// Add user message to viewport immediately
-						userMsg := llm.Message{Role: "user", Content: input}
-						m.bot.MessageManager.AddMessage(userMsg)
-						m.viewport.SetContent(lipgloss.NewStyle().Width(m.viewport.Width).Render(strings.Join(m.bot.MessageManager.StyledMessages(), "\n")))
+						userMsg := llm.Message{Role: "user", Content: content}
+						m.bot.MessageManager.AddImageMessage(userMsg, m.images)
+						m.clearAttachments()
+						m.refreshChat()
 						m.viewport.GotoBottom()
 
 						// Update tab names to reflect new token count after adding user message
@@ -1148,26 +2279,26 @@ Key bindings:
 						m.thinkingFrame = 0
 
 						// Send message asynchronously
-						return m, m.sendChatMessage(input)
+						return m, m.sendChatMessage(content)
 					}
 				}
 			}
-		case "ctrl+u":
+		case key.Matches(msg, m.keys.ClearInput):
 			// Clear text before cursor (like bash)
 			if m.focus == focusTextarea {
 				m.textarea.SetValue("")
 			}
-		case "ctrl+a":
+		case key.Matches(msg, m.keys.LineStart):
 			// Go to beginning of text (like bash)
 			if m.focus == focusTextarea {
 				m.textarea.SetCursor(0)
 			}
-		case "ctrl+e":
+		case key.Matches(msg, m.keys.LineEnd):
 			// Go to end of text (like bash)
 			if m.focus == focusTextarea {
 				m.textarea.SetCursor(len(m.textarea.Value()))
 			}
-		case "ctrl+c", "esc":
+		case key.Matches(msg, m.keys.Quit, m.keys.Back):
 			// If we're in ChromaDB input mode, escape back to RAG viewport
 			if m.focus == focusChromaDBInput {
 				m.focus = focusRAGViewport
@@ -1175,7 +2306,47 @@ Key bindings:
 				m.updateInputPlaceholder()
 				return m, nil
 			}
-			return m, tea.Quit
+			// If we're typing an inspector query, discard it and return to RAG viewport
+			if m.focus == focusRAGInspectInput {
+				m.focus = focusRAGViewport
+				m.ragInspectInput.Blur()
+				m.inputError = ""
+				m.updateRAGViewportContent()
+				m.updateInputPlaceholder()
+				return m, nil
+			}
+			// If the RAG tab shows the inspector, Esc returns to the RAG settings
+			if key.Matches(msg, m.keys.Back) && m.activeTab == ragTab && m.ragInspecting {
+				m.ragInspecting = false
+				m.ragInspection = nil
+				m.updateRAGViewportContent()
+				return m, nil
+			}
+			// If we're editing an endpoint or form field, discard the edit and return to the settings viewport
+			if key.Matches(msg, m.keys.Back) && m.activeTab == settingsTab && m.focus == focusTextarea {
+				m.cancelFieldEdit()
+				m.addingEndpoint = false
+				m.inputError = ""
+				m.updateSettingsViewportContent()
+				return m, nil
+			}
+			// If we're editing a RAG option, discard the edit and return to RAG viewport
+			if m.focus == focusRAGOptionInput {
+				m.focus = focusRAGViewport
+				m.ragOptionInput.Blur()
+				m.inputError = ""
+				m.updateInputPlaceholder()
+				return m, nil
+			}
+			// Otherwise Esc stops the reply being received
+			if key.Matches(msg, m.keys.Back) && m.cancelChat != nil {
+				m.cancelChat()
+				return m, nil
+			}
+			// Esc only cancels, so it can be pressed freely by habit
+			if key.Matches(msg, m.keys.Quit) {
+				return m, m.quit()
+			}
 		}
 
 	// Handle tick messages for thinking indicator animation
@@ -1183,100 +2354,133 @@ Key bindings:
 		if m.isThinking {
 			m.thinkingFrame++
 			// Update the viewport with the new thinking indicator
-			messages := m.bot.MessageManager.StyledMessages()
-			if len(messages) > 0 {
-				// Add thinking indicator to the last message
-				content := strings.Join(messages, "\n") + "\n\n" + m.getThinkingIndicator()
-				m.viewport.SetContent(lipgloss.NewStyle().Width(m.viewport.Width).Render(content))
+			if m.bot.MessageLen() > 0 {
+				m.refreshChat()
 				m.viewport.GotoBottom()
 			}
 		}
 		return m, tickEvery(100 * time.Millisecond)
 
+	// Show streamed replies as they arrive
+	case chatChunkMsg:
+		m.responseBuffer += msg.content
+		m.refreshChat()
+		m.viewport.GotoBottom()
+		return m, waitForChat(msg.stream)
+
 	// Handle async chat responses
 	case chatResponseMsg:
 		m.isThinking = false // Stop thinking indicator
+		m.cancelChat = nil
+		partial := m.responseBuffer
+		m.responseBuffer = "" // The response carries the whole reply
 
-		if msg.err != nil {
+		// Record exactly what was sent so later turns can re-send or drop it
+		if msg.context != "" {
+			m.bot.MessageManager.AddContextMessage(msg.context)
+		}
+
+		switch {
+		case errors.Is(msg.err, context.Canceled):
+			// Keep what arrived before the reply was stopped
+			if partial != "" {
+				m.bot.MessageManager.AddMessage(llm.Message{Role: "assistant", Content: partial})
+			}
+			m.bot.MessageManager.AddMessage(llm.Message{Role: "error", Content: "reply cancelled"})
+		case msg.err != nil:
 			errorMsg := llm.Message{Role: "error", Content: msg.err.Error()}
 			m.bot.MessageManager.AddMessage(errorMsg)
-		} else if msg.response != nil {
+		case msg.response != nil:
 			m.handleChatResponse(*msg.response)
 		}
 
 		// Update viewport to show the final state
-		m.viewport.SetContent(lipgloss.NewStyle().Width(m.viewport.Width).Render(strings.Join(m.bot.MessageManager.StyledMessages(), "\n")))
+		m.refreshChat()
 		m.viewport.GotoBottom()
 		// Update tab names to reflect final token count
 		m.updateTabNames()
+		m.saveSession()
 
-	// We handle errors just like any other message
-	case errMsg:
-		m.err = msg
+	// Read the prompt back once the external editor exits
+	case editorFinishedMsg:
+		m.finishEditing(msg)
 		return m, nil
-	}
 
-	return m, tea.Batch(tiCmd, vpCmd, mvCmd, ragCmd, chromaCmd)
-}
+	// Handle finished knowledge base syncs, scheduling the next check when watching
+	case ragSyncMsg:
+		m.ragSyncing = false
+		m.ragSyncResult = &msg
+		if msg.status != nil {
+			m.ragStatus = msg.status
+		}
+		m.updateRAGViewportContent()
+		if m.settings.RAGWatchEnabled {
+			return m, m.scheduleRAGWatch()
+		}
+		return m, nil
 
-func (m *model) View() string {
-	// Get theme-aware tab styles
-	inactiveStyle, activeStyle, inactiveModelsStyle, activeModelsStyle,
-		inactiveRAGStyle, activeRAGStyle, inactiveSettingsStyle, activeSettingsStyle := m.getTabStyles()
+	case ragStatusMsg:
+		// A status read for the same URL is older than any sync finished since
+		if m.ragStatus == nil || m.ragStatus.chromaDBURL != msg.chromaDBURL {
+			m.ragStatus = &msg
+			m.updateRAGViewportContent()
+		}
+		return m, nil
 
-	// Render tabs
-	var renderedTabs []string
+	case endpointStatusMsg:
+		m.endpointChecking = false
+		m.endpointStatus = msg.statuses
+		m.updateSettingsViewportContent()
+		return m, nil
 
-	for i, tabName := range m.tabs {
-		var style lipgloss.Style
-		isFirst, isLast, isActive := i == 0, i == len(m.tabs)-1, i == int(m.activeTab)
+	case ragWatchTickMsg:
+		if msg.seq == m.ragWatchSeq && m.settings.RAGWatchEnabled && m.ragSyncReady() && !m.ragSyncing {
+			cmd := m.syncKnowledgeBase()
+			m.updateRAGViewportContent()
+			return m, cmd
+		}
+		return m, nil
 
-		// Apply styles based on tab type and state
-		if i == 1 { // Models tab
-			if isActive {
-				style = activeModelsStyle
-			} else {
-				style = inactiveModelsStyle
-			}
-		} else if i == 2 { // RAG tab
-			if isActive {
-				style = activeRAGStyle
-			} else {
-				style = inactiveRAGStyle
-			}
-		} else if i == 3 { // Settings tab
-			if isActive {
-				style = activeSettingsStyle
-			} else {
-				style = inactiveSettingsStyle
-			}
-		} else { // Chat tab
-			if isActive {
-				style = activeStyle
-			} else {
-				style = inactiveStyle
-			}
+	// Handle RAG inspector results
+	case ragInspectMsg:
+		if m.ragInspecting {
+			m.ragInspection = &msg
+			m.updateRAGViewportContent()
+			m.ragViewport.GotoTop()
 		}
 
-		border, _, _, _, _ := style.GetBorder()
-		if isFirst && isActive {
-			border.BottomLeft = "│"
-		} else if isFirst && !isActive {
-			border.BottomLeft = "├"
-		} else if isLast && isActive {
-			border.BottomRight = "│"
-		} else if isLast && !isActive {
-			border.BottomRight = "┤"
+	// Apply edits made to the settings file outside the TUI
+	case settingsFileMsg:
+		var cmd tea.Cmd
+		// Our own saves and edits already rejected aren't reloaded
+		if msg.changed && msg.fresh.Stamp() != m.settings.Stamp() && msg.fresh.Stamp() != m.settingsRejected {
+			cmd = m.reloadSettings(msg.fresh)
 		}
-		style = style.Border(border)
-		renderedTabs = append(renderedTabs, style.Render(tabName))
+		return m, tea.Batch(cmd, m.watchSettings())
+
+	// We handle errors just like any other message
+	case errMsg:
+		m.err = msg
+		return m, nil
+	}
+
+	return m, tea.Batch(tiCmd, vpCmd, mvCmd, ragCmd, chromaCmd, ragOptCmd, ragInspectCmd)
+}
+
+func (m *model) View() string {
+	if m.tooSmall() {
+		return m.tooSmallView()
 	}
 
-	tabRow := lipgloss.JoinHorizontal(lipgloss.Top, renderedTabs...)
+	tabRow := m.renderTabs(m.tabNames())
 
 	// Render content based on active tab
 	var content string
-	if m.activeTab == chatTab {
+	if m.focus == focusPalette {
+		content = m.paletteView()
+	} else if m.focus == focusSessions {
+		content = m.sessionsView()
+	} else if m.activeTab == chatTab {
 		// Chat tab: show full-width chat viewport
 		content = m.viewport.View()
 	} else if m.activeTab == modelsTab {
@@ -1314,8 +2518,16 @@ func (m *model) View() string {
 			Foreground(errorColor).
 			Bold(true).
 			Padding(0, 1)
-		errorDisplay = errorStyle.Render("⚠ "+m.inputError) + "\n"
+		errorDisplay = errorStyle.Render(m.fitStatus("⚠ "+m.inputError)) + "\n"
 		adjustedGap = "\n" // Reduce gap since we're adding error message line
+	} else if hint := cmp.Or(m.completionHint(), m.selectionHint(), m.searchHint()); hint != "" {
+		// Show completions of the slash command being typed in the same place
+		hintStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8")).Padding(0, 1)
+		if m.darkMode {
+			hintStyle = hintStyle.Foreground(darkModeTextColor)
+		}
+		errorDisplay = hintStyle.Render(m.fitStatus(hint)) + "\n"
+		adjustedGap = "\n"
 	}
 
 	// Handle special input rendering
@@ -1323,10 +2535,87 @@ func (m *model) View() string {
 	if m.focus == focusChromaDBInput {
 		// Show ChromaDB input instead of textarea when focused
 		inputDisplay = m.chromaDBTextInput.View()
+	} else if m.focus == focusRAGOptionInput {
+		// Show RAG option input instead of textarea when focused
+		inputDisplay = m.ragOptionInput.View()
+	} else if m.focus == focusRAGInspectInput {
+		// Show RAG inspector input instead of textarea when focused
+		inputDisplay = m.ragInspectInput.View()
+	} else if m.focus == focusPalette {
+		// Show the palette query instead of textarea when the palette is open
+		inputDisplay = m.paletteInput.View()
+	} else if m.focus == focusSearch {
+		// Show the search query instead of textarea while searching the conversation
+		inputDisplay = m.searchInput.View()
+	} else if m.focus == focusSessions {
+		// Show the sessions query instead of textarea while searching saved sessions
+		inputDisplay = m.sessionsInput.View()
 	} else {
 		inputDisplay = m.textarea.View()
 	}
 
 	// Combine tabs, content, error message, and input
-	return tabRow + "\n" + content + adjustedGap + errorDisplay + inputDisplay
+	return tabRow + "\n" + content + adjustedGap + errorDisplay + m.attachmentChips() + inputDisplay
+}
+
+// renderTabs renders the tab row with the given tab names
+func (m *model) renderTabs(names []string) string {
+	return lipgloss.JoinHorizontal(lipgloss.Top, m.tabCells(names)...)
+}
+
+// tabCells renders each tab of the tab row
+func (m *model) tabCells(names []string) []string {
+	// Get theme-aware tab styles
+	inactiveStyle, activeStyle, inactiveModelsStyle, activeModelsStyle,
+		inactiveRAGStyle, activeRAGStyle, inactiveSettingsStyle, activeSettingsStyle := m.getTabStyles()
+
+	// Render tabs
+	var renderedTabs []string
+
+	for i, tabName := range names {
+		var style lipgloss.Style
+		isFirst, isLast, isActive := i == 0, i == len(names)-1, i == int(m.activeTab)
+
+		// Apply styles based on tab type and state
+		if i == 1 { // Models tab
+			if isActive {
+				style = activeModelsStyle
+			} else {
+				style = inactiveModelsStyle
+			}
+		} else if i == 2 { // RAG tab
+			if isActive {
+				style = activeRAGStyle
+			} else {
+				style = inactiveRAGStyle
+			}
+		} else if i == 3 { // Settings tab
+			if isActive {
+				style = activeSettingsStyle
+			} else {
+				style = inactiveSettingsStyle
+			}
+		} else { // Chat tab
+			if isActive {
+				style = activeStyle
+			} else {
+				style = inactiveStyle
+			}
+		}
+
+		border, _, _, _, _ := style.GetBorder()
+		if isFirst && isActive {
+			border.BottomLeft = "│"
+		} else if isFirst && !isActive {
+			border.BottomLeft = "├"
+		} else if isLast && isActive {
+			border.BottomRight = "│"
+		} else if isLast && !isActive {
+			border.BottomRight = "┤"
+		}
+		style = style.Border(border)
+		renderedTabs = append(renderedTabs, style.Render(tabName))
+	}
+
+	return renderedTabs
 }